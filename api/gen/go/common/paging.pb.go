@@ -0,0 +1,311 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: common/paging.proto
+
+package common
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// 通用列表查询请求
+//
+// 过滤、排序规则见 pkg/utils/entgo/query/README.md
+type PagingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Page          *int32                 `protobuf:"varint,1,opt,name=page,proto3,oneof" json:"page,omitempty"`                           // 当前页码，默认为1
+	PageSize      *int32                 `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3,oneof" json:"page_size,omitempty"`   // 每页的行数，默认为10
+	NoPaging      *bool                  `protobuf:"varint,3,opt,name=no_paging,json=noPaging,proto3,oneof" json:"no_paging,omitempty"`   // 是否不分页，为true时page、pageSize无效
+	Query         *string                `protobuf:"bytes,4,opt,name=query,proto3,oneof" json:"query,omitempty"`                          // AND过滤条件，json object 或 json object array
+	OrQuery       *string                `protobuf:"bytes,5,opt,name=or_query,json=or,proto3,oneof" json:"or_query,omitempty"`            // OR过滤条件，格式同query
+	OrderBy       []string               `protobuf:"bytes,6,rep,name=order_by,json=orderBy,proto3" json:"order_by,omitempty"`             // 排序条件，字段名前加'-'为降序，如 ["-create_time", "type"]
+	FieldMask     *fieldmaskpb.FieldMask `protobuf:"bytes,7,opt,name=field_mask,json=fieldMask,proto3,oneof" json:"field_mask,omitempty"` // 字段掩码，为空时返回所有字段
+	Cursor        *string                `protobuf:"bytes,8,opt,name=cursor,proto3,oneof" json:"cursor,omitempty"`                        // 游标，使用游标分页时传入上一次响应中的nextCursor或prevCursor
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PagingRequest) Reset() {
+	*x = PagingRequest{}
+	mi := &file_common_paging_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PagingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PagingRequest) ProtoMessage() {}
+
+func (x *PagingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_common_paging_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PagingRequest.ProtoReflect.Descriptor instead.
+func (*PagingRequest) Descriptor() ([]byte, []int) {
+	return file_common_paging_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *PagingRequest) GetPage() int32 {
+	if x != nil && x.Page != nil {
+		return *x.Page
+	}
+	return 0
+}
+
+func (x *PagingRequest) GetPageSize() int32 {
+	if x != nil && x.PageSize != nil {
+		return *x.PageSize
+	}
+	return 0
+}
+
+func (x *PagingRequest) GetNoPaging() bool {
+	if x != nil && x.NoPaging != nil {
+		return *x.NoPaging
+	}
+	return false
+}
+
+func (x *PagingRequest) GetQuery() string {
+	if x != nil && x.Query != nil {
+		return *x.Query
+	}
+	return ""
+}
+
+func (x *PagingRequest) GetOrQuery() string {
+	if x != nil && x.OrQuery != nil {
+		return *x.OrQuery
+	}
+	return ""
+}
+
+func (x *PagingRequest) GetOrderBy() []string {
+	if x != nil {
+		return x.OrderBy
+	}
+	return nil
+}
+
+func (x *PagingRequest) GetFieldMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.FieldMask
+	}
+	return nil
+}
+
+func (x *PagingRequest) GetCursor() string {
+	if x != nil && x.Cursor != nil {
+		return *x.Cursor
+	}
+	return ""
+}
+
+// 通用列表分页信息
+type PagingResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Total         uint64                 `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`                                  // 总行数，游标分页时为0
+	Page          int32                  `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`                                    // 当前页码，不分页或游标分页时为0
+	PageSize      int32                  `protobuf:"varint,3,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`            // 每页的行数，不分页时为0
+	HasNext       bool                   `protobuf:"varint,4,opt,name=has_next,json=hasNext,proto3" json:"has_next,omitempty"`               // 是否有下一页
+	HasPrev       bool                   `protobuf:"varint,5,opt,name=has_prev,json=hasPrev,proto3" json:"has_prev,omitempty"`               // 是否有上一页
+	NextCursor    *string                `protobuf:"bytes,6,opt,name=next_cursor,json=nextCursor,proto3,oneof" json:"next_cursor,omitempty"` // 下一页游标
+	PrevCursor    *string                `protobuf:"bytes,7,opt,name=prev_cursor,json=prevCursor,proto3,oneof" json:"prev_cursor,omitempty"` // 上一页游标
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PagingResponse) Reset() {
+	*x = PagingResponse{}
+	mi := &file_common_paging_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PagingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PagingResponse) ProtoMessage() {}
+
+func (x *PagingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_common_paging_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PagingResponse.ProtoReflect.Descriptor instead.
+func (*PagingResponse) Descriptor() ([]byte, []int) {
+	return file_common_paging_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PagingResponse) GetTotal() uint64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *PagingResponse) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *PagingResponse) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *PagingResponse) GetHasNext() bool {
+	if x != nil {
+		return x.HasNext
+	}
+	return false
+}
+
+func (x *PagingResponse) GetHasPrev() bool {
+	if x != nil {
+		return x.HasPrev
+	}
+	return false
+}
+
+func (x *PagingResponse) GetNextCursor() string {
+	if x != nil && x.NextCursor != nil {
+		return *x.NextCursor
+	}
+	return ""
+}
+
+func (x *PagingResponse) GetPrevCursor() string {
+	if x != nil && x.PrevCursor != nil {
+		return *x.PrevCursor
+	}
+	return ""
+}
+
+var File_common_paging_proto protoreflect.FileDescriptor
+
+const file_common_paging_proto_rawDesc = "" +
+	"\n" +
+	"\x13common/paging.proto\x12\x06common\x1a google/protobuf/field_mask.proto\"\xf0\x02\n" +
+	"\rPagingRequest\x12\x17\n" +
+	"\x04page\x18\x01 \x01(\x05H\x00R\x04page\x88\x01\x01\x12 \n" +
+	"\tpage_size\x18\x02 \x01(\x05H\x01R\bpageSize\x88\x01\x01\x12 \n" +
+	"\tno_paging\x18\x03 \x01(\bH\x02R\bnoPaging\x88\x01\x01\x12\x19\n" +
+	"\x05query\x18\x04 \x01(\tH\x03R\x05query\x88\x01\x01\x12\x19\n" +
+	"\bor_query\x18\x05 \x01(\tH\x04R\x02or\x88\x01\x01\x12\x19\n" +
+	"\border_by\x18\x06 \x03(\tR\aorderBy\x12>\n" +
+	"\n" +
+	"field_mask\x18\a \x01(\v2\x1a.google.protobuf.FieldMaskH\x05R\tfieldMask\x88\x01\x01\x12\x1b\n" +
+	"\x06cursor\x18\b \x01(\tH\x06R\x06cursor\x88\x01\x01B\a\n" +
+	"\x05_pageB\f\n" +
+	"\n" +
+	"_page_sizeB\f\n" +
+	"\n" +
+	"_no_pagingB\b\n" +
+	"\x06_queryB\v\n" +
+	"\t_or_queryB\r\n" +
+	"\v_field_maskB\t\n" +
+	"\a_cursor\"\xf9\x01\n" +
+	"\x0ePagingResponse\x12\x14\n" +
+	"\x05total\x18\x01 \x01(\x04R\x05total\x12\x12\n" +
+	"\x04page\x18\x02 \x01(\x05R\x04page\x12\x1b\n" +
+	"\tpage_size\x18\x03 \x01(\x05R\bpageSize\x12\x19\n" +
+	"\bhas_next\x18\x04 \x01(\bR\ahasNext\x12\x19\n" +
+	"\bhas_prev\x18\x05 \x01(\bR\ahasPrev\x12$\n" +
+	"\vnext_cursor\x18\x06 \x01(\tH\x00R\n" +
+	"nextCursor\x88\x01\x01\x12$\n" +
+	"\vprev_cursor\x18\a \x01(\tH\x01R\n" +
+	"prevCursor\x88\x01\x01B\x0e\n" +
+	"\f_next_cursorB\x0e\n" +
+	"\f_prev_cursorB\x7f\n" +
+	"\n" +
+	"com.commonB\vPagingProtoP\x01Z,github.com/heyinLab/common/api/gen/go/common\xa2\x02\x03CXX\xaa\x02\x06Common\xca\x02\x06Common\xe2\x02\x12Common\\GPBMetadata\xea\x02\x06Commonb\x06proto3"
+
+var (
+	file_common_paging_proto_rawDescOnce sync.Once
+	file_common_paging_proto_rawDescData []byte
+)
+
+func file_common_paging_proto_rawDescGZIP() []byte {
+	file_common_paging_proto_rawDescOnce.Do(func() {
+		file_common_paging_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_common_paging_proto_rawDesc), len(file_common_paging_proto_rawDesc)))
+	})
+	return file_common_paging_proto_rawDescData
+}
+
+var file_common_paging_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_common_paging_proto_goTypes = []any{
+	(*PagingRequest)(nil),         // 0: common.PagingRequest
+	(*PagingResponse)(nil),        // 1: common.PagingResponse
+	(*fieldmaskpb.FieldMask)(nil), // 2: google.protobuf.FieldMask
+}
+var file_common_paging_proto_depIdxs = []int32{
+	2, // 0: common.PagingRequest.field_mask:type_name -> google.protobuf.FieldMask
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_common_paging_proto_init() }
+func file_common_paging_proto_init() {
+	if File_common_paging_proto != nil {
+		return
+	}
+	file_common_paging_proto_msgTypes[0].OneofWrappers = []any{}
+	file_common_paging_proto_msgTypes[1].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_common_paging_proto_rawDesc), len(file_common_paging_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_common_paging_proto_goTypes,
+		DependencyIndexes: file_common_paging_proto_depIdxs,
+		MessageInfos:      file_common_paging_proto_msgTypes,
+	}.Build()
+	File_common_paging_proto = out.File
+	file_common_paging_proto_goTypes = nil
+	file_common_paging_proto_depIdxs = nil
+}
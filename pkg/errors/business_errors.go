@@ -1,8 +1,10 @@
 package errors
 
 import (
-	commonV1 "github.com/heyinLab/common/api/gen/go/common"
+	"errors"
 	"strings"
+
+	commonV1 "github.com/heyinLab/common/api/gen/go/common"
 )
 
 // 业务错误类型
@@ -80,8 +82,9 @@ func ClassifyError(err error) *BusinessError {
 		return nil
 	}
 
-	// 检查是否已经是业务错误
-	if businessErr, ok := err.(*BusinessError); ok {
+	// 检查是否已经是业务错误（包括包装了业务错误的错误）
+	var businessErr *BusinessError
+	if errors.As(err, &businessErr) {
 		return businessErr
 	}
 
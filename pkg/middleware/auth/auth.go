@@ -2,13 +2,17 @@ package auth
 
 import (
 	"context"
+	stdErrors "errors"
 	"strconv"
 
 	"github.com/go-kratos/kratos/v2/errors"
 	"github.com/go-kratos/kratos/v2/middleware"
 	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/golang-jwt/jwt/v5"
 	businessErrors "github.com/heyinLab/common/pkg/errors"
+	"github.com/heyinLab/common/pkg/middleware/auth/openapi"
 	"github.com/heyinLab/common/pkg/middleware/common"
+	"github.com/heyinLab/common/pkg/utils/jwtutil"
 )
 
 // GetAuthType 获取认证类型
@@ -59,106 +63,239 @@ func GetOperator(ctx context.Context) Operator {
 }
 
 // Server 统一认证中间件，支持 JWT Token 和 OpenAPI 两种认证方式
-func Server(needTenant bool) middleware.Middleware {
+//
+// 默认工作在 ModeHeader 模式，信任网关注入的 X-User-ID/X-Tenant-ID/X-Region-Name 头；
+// 通过 WithSigningKey/WithKeyFunc 切换到 ModeToken 模式后，将自行校验 Authorization: Bearer 中的 JWT。
+// 设置 WithOpenAPIVerifier 后，X-Auth-Type: openapi 的请求改为校验 HMAC 请求签名。
+// 设置 WithPolicies 后，按 Operation 决定是否需要认证、允许的认证方式以及是否需要租户。
+//
+// 使用示例:
+//
+//	// 部署在网关之后的服务
+//	auth.Server(true)
+//
+//	// 可被直接访问的服务，自行验证 Token
+//	auth.Server(true,
+//	    auth.WithSigningKey([]byte(secret)),
+//	    auth.WithIssuer("heyin"),
+//	    auth.WithAudience("merchant"),
+//	)
+func Server(needTenant bool, opts ...Option) middleware.Middleware {
+	o := newOptions(opts...)
+	if o.mode == ModeToken && o.keyFunc == nil {
+		panic("auth: ModeToken requires WithSigningKey or WithKeyFunc")
+	}
+	if o.mode == ModeToken && len(o.validMethods) == 0 {
+		panic("auth: WithKeyFunc requires WithValidMethods")
+	}
+
 	return func(handler middleware.Handler) middleware.Handler {
 		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
 			// 从 context 中获取 transport 信息 (HTTP/gRPC)
 			tr, ok := transport.FromServerContext(ctx)
 			if !ok {
-				return nil, errors.New(
-					int(businessErrors.ErrSystemError.HttpCode),
-					businessErrors.ErrSystemError.Type,
-					businessErrors.ErrSystemError.Message,
-				)
+				return nil, newError(businessErrors.ErrSystemError, businessErrors.ErrSystemError.Message)
 			}
 
 			header := tr.RequestHeader()
+			// ModeToken 且未配置 OpenAPI 校验时，X-Auth-Type 不可信，按普通 Token 请求处理
+			isOpenAPI := header.Get(openapi.HeaderAuthType) == openapi.AuthTypeValue &&
+				(o.openapiVerifier != nil || o.mode == ModeHeader)
 
-			// 1. 先检查认证类型
-			authType := header.Get("X-Auth-Type")
-			isOpenAPI := authType == "openapi"
-
-			// 2. 读取公共 headers
-			userId := header.Get(common.USERID)
-			regionName := header.Get(common.REGIONNAME)
-
-			var userIdUint uint64 = 0
-
-			if isOpenAPI {
-				// OpenAPI 认证：X-User-ID 可以为空或为 "0"
-				if userId != "" {
-					userIdUint, _ = strconv.ParseUint(userId, 10, 32)
-				}
-				// OpenAPI 请求的 UserID 固定为 0，不报错
-			} else {
-				// JWT Token 认证：X-User-ID 必须存在且有效
-				if userId == "" {
-					return nil, errors.New(
-						int(businessErrors.ErrAuthHeaderMissing.HttpCode),
-						businessErrors.ErrAuthHeaderMissing.Type,
-						"X-User-ID header is missing",
-					)
+			policy := o.policies.Match(tr.Operation())
+			switch policy.Access {
+			case AccessAnonymous:
+				return handler(ctx, req)
+			case AccessToken:
+				if isOpenAPI {
+					return nil, newError(businessErrors.ErrAccessForbidden, "OpenAPI access is not allowed")
 				}
-
-				userIdUint, err = strconv.ParseUint(userId, 10, 32)
-				if err != nil {
-					return nil, errors.New(
-						int(businessErrors.ErrAuthHeaderInvalid.HttpCode),
-						businessErrors.ErrAuthHeaderInvalid.Type,
-						"Invalid X-User-ID format",
-					)
+			case AccessOpenAPI:
+				if !isOpenAPI {
+					return nil, newError(businessErrors.ErrAccessForbidden, "Only OpenAPI access is allowed")
 				}
 			}
+			requireTenant := policy.needTenant(needTenant)
 
-			// 3. 处理租户ID
-			var tenantIdUint uint64 = 0
-
-			if needTenant {
-				tenantId := header.Get(common.TENANTID)
-				if tenantId == "" {
-					return nil, errors.New(
-						int(businessErrors.ErrTenantMissing.HttpCode),
-						businessErrors.ErrTenantMissing.Type,
-						businessErrors.ErrTenantMissing.Message,
-					)
-				}
-				t, err := strconv.ParseUint(tenantId, 10, 32)
-				if err != nil {
-					return nil, errors.New(
-						int(businessErrors.ErrTenantInvalid.HttpCode),
-						businessErrors.ErrTenantInvalid.Type,
-						businessErrors.ErrTenantInvalid.Message,
-					)
-				}
-				tenantIdUint = t
+			var newCtx context.Context
+			switch {
+			case o.openapiVerifier != nil && isOpenAPI:
+				newCtx, err = authenticateOpenAPI(ctx, tr, req, requireTenant, o.openapiVerifier)
+			case o.mode == ModeToken:
+				newCtx, err = authenticateToken(ctx, header, requireTenant, o)
+			default:
+				newCtx, err = authenticateHeader(ctx, header, requireTenant)
 			}
-
-			// 4. 创建 Claims 并注入 context
-			claims := &Claims{
-				UserID:     uint32(userIdUint),
-				TenantID:   uint32(tenantIdUint),
-				RegionName: regionName,
+			if err != nil {
+				return nil, err
 			}
-			newCtx := NewContext(ctx, claims)
 
-			// 5. 如果是 OpenAPI 请求，设置额外的 context 值
-			if isOpenAPI {
-				newCtx = context.WithValue(newCtx, common.KeyAuthType, common.AuthTypeOpenAPI)
+			return handler(newCtx, req)
+		}
+	}
+}
 
-				// 读取 API Key ID
-				if apiKeyIDStr := header.Get("X-API-Key-ID"); apiKeyIDStr != "" {
-					if id, err := strconv.ParseUint(apiKeyIDStr, 10, 64); err == nil {
-						newCtx = context.WithValue(newCtx, common.KeyAPIKeyID, id)
-					}
-				}
+// authenticateHeader 基于网关注入的请求头构建 Claims
+func authenticateHeader(ctx context.Context, header transport.Header, needTenant bool) (context.Context, error) {
+	// 1. 先检查认证类型
+	authType := header.Get("X-Auth-Type")
+	isOpenAPI := authType == "openapi"
 
-				// 读取 Product Code
-				if productCode := header.Get("X-Product-Code"); productCode != "" {
-					newCtx = context.WithValue(newCtx, common.KeyProductCode, productCode)
-				}
+	// 2. 读取公共 headers
+	userId := header.Get(common.USERID)
+	regionName := header.Get(common.REGIONNAME)
+
+	var userIdUint uint64 = 0
+	var err error
+
+	if isOpenAPI {
+		// OpenAPI 认证：X-User-ID 可以为空或为 "0"
+		if userId != "" {
+			userIdUint, _ = strconv.ParseUint(userId, 10, 32)
+		}
+		// OpenAPI 请求的 UserID 固定为 0，不报错
+	} else {
+		// JWT Token 认证：X-User-ID 必须存在且有效
+		if userId == "" {
+			return nil, newError(businessErrors.ErrAuthHeaderMissing, "X-User-ID header is missing")
+		}
+
+		userIdUint, err = strconv.ParseUint(userId, 10, 32)
+		if err != nil {
+			return nil, newError(businessErrors.ErrAuthHeaderInvalid, "Invalid X-User-ID format")
+		}
+	}
+
+	// 3. 处理租户ID
+	var tenantIdUint uint64 = 0
+
+	if needTenant {
+		tenantId := header.Get(common.TENANTID)
+		if tenantId == "" {
+			return nil, newError(businessErrors.ErrTenantMissing, businessErrors.ErrTenantMissing.Message)
+		}
+		t, err := strconv.ParseUint(tenantId, 10, 32)
+		if err != nil {
+			return nil, newError(businessErrors.ErrTenantInvalid, businessErrors.ErrTenantInvalid.Message)
+		}
+		tenantIdUint = t
+	}
+
+	// 4. 创建 Claims 并注入 context
+	claims := &Claims{
+		UserID:     uint32(userIdUint),
+		TenantID:   uint32(tenantIdUint),
+		RegionName: regionName,
+	}
+	newCtx := NewContext(ctx, claims)
+
+	// 5. 如果是 OpenAPI 请求，设置额外的 context 值
+	if isOpenAPI {
+		newCtx = context.WithValue(newCtx, common.KeyAuthType, common.AuthTypeOpenAPI)
+
+		// 读取 API Key ID
+		if apiKeyIDStr := header.Get("X-API-Key-ID"); apiKeyIDStr != "" {
+			if id, err := strconv.ParseUint(apiKeyIDStr, 10, 64); err == nil {
+				newCtx = context.WithValue(newCtx, common.KeyAPIKeyID, id)
 			}
+		}
 
-			return handler(newCtx, req)
+		// 读取 Product Code
+		if productCode := header.Get("X-Product-Code"); productCode != "" {
+			newCtx = context.WithValue(newCtx, common.KeyProductCode, productCode)
+		}
+	}
+
+	return newCtx, nil
+}
+
+// authenticateToken 校验 Authorization: Bearer 中的 JWT 并构建 Claims
+//
+// 该模式下不读取 X-User-ID 等请求头，所有身份信息均来自已验签的 Token。
+func authenticateToken(ctx context.Context, header transport.Header, needTenant bool, o *options) (context.Context, error) {
+	authHeader := header.Get(common.AUTHORIZATION)
+	if authHeader == "" {
+		return nil, newError(businessErrors.ErrAuthHeaderMissing, "Authorization header is missing")
+	}
+
+	tokenString, err := jwtutil.ExtractBearerToken(authHeader)
+	if err != nil {
+		return nil, newError(businessErrors.ErrAuthHeaderInvalid, "Invalid Authorization header format")
+	}
+
+	mapClaims, err := jwtutil.VerifyJWTWithOptions(tokenString, o.keyFunc, o.parserOptions()...)
+	if err != nil {
+		if stdErrors.Is(err, jwt.ErrTokenExpired) {
+			return nil, newError(businessErrors.ErrTokenExpired, businessErrors.ErrTokenExpired.Message)
+		}
+		return nil, newError(businessErrors.ErrTokenInvalid, businessErrors.ErrTokenInvalid.Message)
+	}
+
+	claims, err := o.claimsMapper(mapClaims)
+	if err != nil || claims == nil {
+		return nil, newError(businessErrors.ErrTokenInvalid, businessErrors.ErrTokenInvalid.Message)
+	}
+	if claims.UserID == 0 {
+		return nil, newError(businessErrors.ErrTokenInvalid, businessErrors.ErrTokenInvalid.Message)
+	}
+
+	if needTenant && claims.TenantID == 0 {
+		return nil, newError(businessErrors.ErrTenantMissing, businessErrors.ErrTenantMissing.Message)
+	}
+
+	newCtx := NewContext(ctx, claims)
+	newCtx = context.WithValue(newCtx, common.KeyAuthType, common.AuthTypeToken)
+
+	return newCtx, nil
+}
+
+// authenticateOpenAPI 校验 OpenAPI 请求的 HMAC 签名并构建 Claims
+//
+// 租户、API Key ID 和产品编码均取自 KeyStore 中登记的密钥，而不是请求头。
+func authenticateOpenAPI(ctx context.Context, tr transport.Transporter, req interface{}, needTenant bool, verifier *openapi.Verifier) (context.Context, error) {
+	signedReq, err := openapi.RequestFromTransport(ctx, tr, req)
+	if err != nil {
+		return nil, newError(businessErrors.ErrAuthServiceError, err.Error())
+	}
+
+	key, err := verifier.Verify(ctx, signedReq)
+	if err != nil {
+		switch {
+		case stdErrors.Is(err, openapi.ErrMissingSignature):
+			return nil, newError(businessErrors.ErrAuthHeaderMissing, "OpenAPI signature headers are missing")
+		case stdErrors.Is(err, openapi.ErrInvalidTimestamp), stdErrors.Is(err, openapi.ErrTimestampSkewed):
+			return nil, newError(businessErrors.ErrAuthHeaderInvalid, "Invalid or expired X-Timestamp")
+		case stdErrors.Is(err, openapi.ErrNonceReused):
+			return nil, newError(businessErrors.ErrAccessForbidden, "Request has already been used")
+		case stdErrors.Is(err, openapi.ErrKeyDisabled):
+			return nil, newError(businessErrors.ErrAccessForbidden, "API key is disabled")
+		case stdErrors.Is(err, openapi.ErrUnknownKey), stdErrors.Is(err, openapi.ErrSignatureMismatch):
+			return nil, newError(businessErrors.ErrInvalidCredentials, "Invalid API key or signature")
+		default:
+			return nil, newError(businessErrors.ErrAuthServiceError, businessErrors.ErrAuthServiceError.Message)
 		}
 	}
+
+	if needTenant && key.TenantID == 0 {
+		return nil, newError(businessErrors.ErrTenantMissing, businessErrors.ErrTenantMissing.Message)
+	}
+
+	// OpenAPI 请求的 UserID 固定为 0
+	claims := &Claims{
+		TenantID:   key.TenantID,
+		RegionName: tr.RequestHeader().Get(common.REGIONNAME),
+	}
+	newCtx := NewContext(ctx, claims)
+	newCtx = context.WithValue(newCtx, common.KeyAuthType, common.AuthTypeOpenAPI)
+	newCtx = context.WithValue(newCtx, common.KeyAPIKeyID, key.ID)
+	if key.ProductCode != "" {
+		newCtx = context.WithValue(newCtx, common.KeyProductCode, key.ProductCode)
+	}
+
+	return newCtx, nil
+}
+
+// newError 将业务错误转换为 kratos 错误
+func newError(e *businessErrors.BusinessError, message string) *errors.Error {
+	return errors.New(int(e.HttpCode), e.Type, message)
 }
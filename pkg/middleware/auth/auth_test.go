@@ -0,0 +1,319 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/heyinLab/common/pkg/middleware/auth/openapi"
+	"github.com/heyinLab/common/pkg/middleware/common"
+	"github.com/heyinLab/common/pkg/utils/jwtutil"
+
+	_ "github.com/go-kratos/kratos/v2/encoding/json"
+)
+
+type headerCarrier http.Header
+
+func (hc headerCarrier) Get(key string) string { return http.Header(hc).Get(key) }
+
+func (hc headerCarrier) Set(key string, value string) { http.Header(hc).Set(key, value) }
+
+func (hc headerCarrier) Add(key string, value string) { http.Header(hc).Add(key, value) }
+
+func (hc headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(hc))
+	for k := range http.Header(hc) {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (hc headerCarrier) Values(key string) []string { return http.Header(hc).Values(key) }
+
+type testTransport struct {
+	operation string
+	header    headerCarrier
+}
+
+func (tr *testTransport) Kind() transport.Kind            { return transport.KindHTTP }
+func (tr *testTransport) Endpoint() string                { return "" }
+func (tr *testTransport) Operation() string               { return tr.operation }
+func (tr *testTransport) RequestHeader() transport.Header { return tr.header }
+func (tr *testTransport) ReplyHeader() transport.Header   { return headerCarrier{} }
+
+func newTestContext(operation string, headers map[string]string) context.Context {
+	h := headerCarrier{}
+	for k, v := range headers {
+		h.Set(k, v)
+	}
+	return transport.NewServerContext(context.Background(), &testTransport{operation: operation, header: h})
+}
+
+func runServer(ctx context.Context, needTenant bool, opts ...Option) (*Claims, context.Context, error) {
+	var claims *Claims
+	var handlerCtx context.Context
+	_, err := Server(needTenant, opts...)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		claims, _ = FromContext(ctx)
+		handlerCtx = ctx
+		return nil, nil
+	})(ctx, nil)
+	return claims, handlerCtx, err
+}
+
+func TestServerHeaderMode(t *testing.T) {
+	claims, _, err := runServer(newTestContext("", map[string]string{
+		common.USERID:     "12",
+		common.TENANTID:   "34",
+		common.REGIONNAME: "cn",
+	}), true)
+	assert.NoError(t, err)
+	assert.Equal(t, &Claims{UserID: 12, TenantID: 34, RegionName: "cn"}, claims)
+
+	_, _, err = runServer(newTestContext("", map[string]string{}), false)
+	assert.Equal(t, "AUTH_HEADER_MISSING", errors.Reason(err))
+
+	_, _, err = runServer(newTestContext("", map[string]string{common.USERID: "12"}), true)
+	assert.Equal(t, "TENANT_MISSING", errors.Reason(err))
+}
+
+func TestServerTokenMode(t *testing.T) {
+	secretKey := []byte("secret")
+	opts := []Option{
+		WithSigningKey(secretKey),
+		WithIssuer("heyin"),
+		WithAudience("api"),
+	}
+
+	sign := func(claims jwt.MapClaims) string {
+		token, err := jwtutil.GenerateJWT(claims, secretKey, jwt.SigningMethodHS256)
+		assert.NoError(t, err)
+		return "Bearer " + token
+	}
+
+	valid := sign(jwt.MapClaims{
+		"sub":         "12",
+		"tenant_id":   34,
+		"region_name": "cn",
+		"iss":         "heyin",
+		"aud":         "api",
+		"exp":         time.Now().Add(time.Hour).Unix(),
+	})
+
+	// 有效 Token，且忽略伪造的 X-User-ID
+	claims, ctx, err := runServer(newTestContext("", map[string]string{
+		common.AUTHORIZATION: valid,
+		common.USERID:        "999",
+	}), true, opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, &Claims{UserID: 12, TenantID: 34, RegionName: "cn"}, claims)
+	assert.Equal(t, common.AuthTypeToken, GetAuthType(ctx))
+
+	// 缺少 Authorization 头
+	_, _, err = runServer(newTestContext("", map[string]string{common.USERID: "12"}), false, opts...)
+	assert.Equal(t, "AUTH_HEADER_MISSING", errors.Reason(err))
+
+	// Authorization 头格式错误
+	_, _, err = runServer(newTestContext("", map[string]string{common.AUTHORIZATION: "Basic abc"}), false, opts...)
+	assert.Equal(t, "AUTH_HEADER_INVALID", errors.Reason(err))
+
+	// 过期 Token
+	expired := sign(jwt.MapClaims{
+		"sub": "12",
+		"iss": "heyin",
+		"aud": "api",
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	})
+	_, _, err = runServer(newTestContext("", map[string]string{common.AUTHORIZATION: expired}), false, opts...)
+	assert.Equal(t, "TOKEN_EXPIRED", errors.Reason(err))
+
+	// 受众不匹配
+	wrongAudience := sign(jwt.MapClaims{
+		"sub": "12",
+		"iss": "heyin",
+		"aud": "other",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	_, _, err = runServer(newTestContext("", map[string]string{common.AUTHORIZATION: wrongAudience}), false, opts...)
+	assert.Equal(t, "TOKEN_INVALID", errors.Reason(err))
+
+	// 签名错误
+	forged, _ := jwtutil.GenerateJWT(jwt.MapClaims{
+		"sub": "12",
+		"iss": "heyin",
+		"aud": "api",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}, []byte("forged"), jwt.SigningMethodHS256)
+	_, _, err = runServer(newTestContext("", map[string]string{common.AUTHORIZATION: "Bearer " + forged}), false, opts...)
+	assert.Equal(t, "TOKEN_INVALID", errors.Reason(err))
+
+	// 需要租户但 Token 中没有租户
+	noTenant := sign(jwt.MapClaims{
+		"sub": "12",
+		"iss": "heyin",
+		"aud": "api",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	_, _, err = runServer(newTestContext("", map[string]string{common.AUTHORIZATION: noTenant}), true, opts...)
+	assert.Equal(t, "TENANT_MISSING", errors.Reason(err))
+}
+
+func TestServerKeyFunc(t *testing.T) {
+	secretKey := []byte("secret")
+	keyFunc := func(*jwt.Token) (interface{}, error) {
+		return secretKey, nil
+	}
+
+	// 未限定签名算法时拒绝启动
+	assert.PanicsWithValue(t, "auth: WithKeyFunc requires WithValidMethods", func() {
+		Server(false, WithKeyFunc(keyFunc))
+	})
+
+	claims := jwt.MapClaims{
+		"sub": "12",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	hs256, err := jwtutil.GenerateJWT(claims, secretKey, jwt.SigningMethodHS256)
+	assert.NoError(t, err)
+	hs512, err := jwtutil.GenerateJWT(claims, secretKey, jwt.SigningMethodHS512)
+	assert.NoError(t, err)
+
+	opts := []Option{WithKeyFunc(keyFunc), WithValidMethods(jwt.SigningMethodHS256.Alg())}
+
+	got, _, err := runServer(newTestContext("", map[string]string{common.AUTHORIZATION: "Bearer " + hs256}), false, opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(12), got.UserID)
+
+	// 签名有效但 alg 不在允许范围内
+	_, _, err = runServer(newTestContext("", map[string]string{common.AUTHORIZATION: "Bearer " + hs512}), false, opts...)
+	assert.Equal(t, "TOKEN_INVALID", errors.Reason(err))
+}
+
+func TestServerOpenAPI(t *testing.T) {
+	keys := openapi.KeyStoreFunc(func(_ context.Context, keyID string) (*openapi.APIKey, error) {
+		if keyID == "10001" {
+			return &openapi.APIKey{ID: 10001, Secret: []byte("secret"), TenantID: 7, ProductCode: "crm"}, nil
+		}
+		return nil, nil
+	})
+	opts := []Option{WithOpenAPIVerifier(openapi.NewVerifier(keys))}
+
+	const operation = "/order.v1.OrderService/ListOrders"
+	signer := openapi.NewSigner("10001", []byte("secret"))
+	headers := signer.SignHeaders(http.MethodPost, operation, nil, openapi.HashBody(nil))
+	headers[common.USERID] = "999"
+	headers[common.TENANTID] = "999"
+
+	claims, ctx, err := runServer(newTestContext(operation, headers), true, opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, &Claims{UserID: 0, TenantID: 7}, claims)
+	assert.True(t, IsOpenAPIRequest(ctx))
+	assert.Equal(t, uint64(10001), GetAPIKeyID(ctx))
+	assert.Equal(t, "crm", GetProductCode(ctx))
+
+	// 重放同一请求
+	_, _, err = runServer(newTestContext(operation, headers), true, opts...)
+	assert.Equal(t, "ACCESS_FORBIDDEN", errors.Reason(err))
+
+	// 使用错误密钥签名
+	forged := openapi.NewSigner("10001", []byte("forged")).SignHeaders(http.MethodPost, operation, nil, openapi.HashBody(nil))
+	_, _, err = runServer(newTestContext(operation, forged), true, opts...)
+	assert.Equal(t, "INVALID_CREDENTIALS", errors.Reason(err))
+
+	// 仅声明 openapi 而不签名
+	_, _, err = runServer(newTestContext(operation, map[string]string{
+		openapi.HeaderAuthType: openapi.AuthTypeValue,
+		openapi.HeaderKeyID:    "10001",
+	}), true, opts...)
+	assert.Equal(t, "AUTH_HEADER_MISSING", errors.Reason(err))
+}
+
+func TestServerPolicies(t *testing.T) {
+	policies := NewPolicyTable().
+		Add("/grpc.health.v1.Health/*", PolicyAnonymous).
+		Add("/user.v1.UserService/GetProfile", PolicyToken.WithoutTenant()).
+		Add("/order.v1.OpenOrderService/*", PolicyOpenAPI).
+		Add("/order.v1.**", PolicyToken)
+	opts := []Option{WithPolicies(policies)}
+
+	// 公开接口无需任何认证头
+	claims, _, err := runServer(newTestContext("/grpc.health.v1.Health/Check", nil), true, opts...)
+	assert.NoError(t, err)
+	assert.Nil(t, claims)
+
+	// 租户可选
+	claims, _, err = runServer(newTestContext("/user.v1.UserService/GetProfile", map[string]string{
+		common.USERID: "12",
+	}), true, opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(12), claims.UserID)
+
+	// 仅允许用户身份
+	_, _, err = runServer(newTestContext("/order.v1.OrderService/ListOrders", map[string]string{
+		common.USERID:          "12",
+		common.TENANTID:        "34",
+		openapi.HeaderAuthType: openapi.AuthTypeValue,
+	}), true, opts...)
+	assert.Equal(t, "ACCESS_FORBIDDEN", errors.Reason(err))
+
+	// 仅允许 OpenAPI
+	_, _, err = runServer(newTestContext("/order.v1.OpenOrderService/ListOrders", map[string]string{
+		common.USERID:   "12",
+		common.TENANTID: "34",
+	}), true, opts...)
+	assert.Equal(t, "ACCESS_FORBIDDEN", errors.Reason(err))
+
+	// 未命中规则时沿用默认行为
+	_, _, err = runServer(newTestContext("/user.v1.UserService/ListUsers", map[string]string{
+		common.USERID: "12",
+	}), true, opts...)
+	assert.Equal(t, "TENANT_MISSING", errors.Reason(err))
+
+	// ModeToken 未配置 OpenAPI 校验时，不信任 X-Auth-Type
+	_, _, err = runServer(newTestContext("/order.v1.OpenOrderService/ListOrders", map[string]string{
+		openapi.HeaderAuthType: openapi.AuthTypeValue,
+	}), true, append(opts, WithSigningKey([]byte("secret")))...)
+	assert.Equal(t, "ACCESS_FORBIDDEN", errors.Reason(err))
+}
+
+func TestPolicyTableMatch(t *testing.T) {
+	policies := NewPolicyTable().
+		Add("/order.v1.OrderService/*", PolicyToken).
+		Add("/order.v1.*/*", PolicyOpenAPI).
+		Default(PolicyAnonymous)
+
+	assert.Equal(t, PolicyToken, policies.Match("/order.v1.OrderService/ListOrders"))
+	assert.Equal(t, PolicyOpenAPI, policies.Match("/order.v1.OpenOrderService/ListOrders"))
+	assert.Equal(t, PolicyAnonymous, policies.Match("/user.v1.UserService/ListUsers"))
+
+	var empty *PolicyTable
+	assert.Equal(t, PolicyEither, empty.Match("/user.v1.UserService/ListUsers"))
+
+	assert.Panics(t, func() { NewPolicyTable().Add("[", PolicyToken) })
+}
+
+func TestDefaultClaimsMapper(t *testing.T) {
+	claims, err := DefaultClaimsMapper(jwt.MapClaims{
+		"user_id":   float64(7),
+		"sub":       "8",
+		"tenant_id": "9",
+		"roles":     []interface{}{"admin", "auditor"},
+		"scope":     "order:read order:write",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(7), claims.UserID)
+	assert.Equal(t, uint32(9), claims.TenantID)
+	assert.Equal(t, []string{"admin", "auditor"}, claims.Roles)
+	assert.Equal(t, []string{"order:read", "order:write"}, claims.Scopes)
+
+	_, err = DefaultClaimsMapper(jwt.MapClaims{"tenant_id": "9"})
+	assert.Error(t, err)
+
+	_, err = DefaultClaimsMapper(jwt.MapClaims{"user_id": float64(-1)})
+	assert.Error(t, err)
+}
@@ -1,13 +1,32 @@
 package auth
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
 
 type Claims struct {
 	UserID     uint32
 	TenantID   uint32
 	RegionName string
+	Roles      []string
+	Scopes     []string
 }
 
+// JWT 中默认使用的声明名称
+const (
+	ClaimUserID     = "user_id"
+	ClaimTenantID   = "tenant_id"
+	ClaimRegionName = "region_name"
+	ClaimRoles      = "roles"
+	ClaimScope      = "scope"
+)
+
 // 定义用于在 context 中传递 Claims 的 key
 type claimsKey struct{}
 
@@ -21,3 +40,92 @@ func FromContext(ctx context.Context) (*Claims, bool) {
 	claims, ok := ctx.Value(claimsKey{}).(*Claims)
 	return claims, ok
 }
+
+// DefaultClaimsMapper 默认的 JWT 声明映射：
+// user_id（缺省时使用 sub）-> UserID，tenant_id -> TenantID，region_name -> RegionName，
+// roles -> Roles，scope（空格分隔的字符串或数组）-> Scopes
+func DefaultClaimsMapper(mapClaims jwt.MapClaims) (*Claims, error) {
+	claims := &Claims{}
+
+	raw, ok := mapClaims[ClaimUserID]
+	if !ok {
+		raw, ok = mapClaims["sub"]
+	}
+	if !ok {
+		return nil, fmt.Errorf("user id claim is missing")
+	}
+	userID, err := claimToUint32(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id claim: %w", err)
+	}
+	claims.UserID = userID
+
+	if raw, ok = mapClaims[ClaimTenantID]; ok {
+		tenantID, err := claimToUint32(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tenant id claim: %w", err)
+		}
+		claims.TenantID = tenantID
+	}
+
+	if raw, ok = mapClaims[ClaimRegionName]; ok {
+		if regionName, isString := raw.(string); isString {
+			claims.RegionName = regionName
+		}
+	}
+
+	claims.Roles = claimToStrings(mapClaims[ClaimRoles])
+	claims.Scopes = claimToStrings(mapClaims[ClaimScope])
+
+	return claims, nil
+}
+
+// claimToStrings 将字符串数组或空格分隔的字符串声明转换为 []string
+func claimToStrings(v interface{}) []string {
+	switch val := v.(type) {
+	case string:
+		return strings.Fields(val)
+	case []string:
+		return val
+	case []interface{}:
+		values := make([]string, 0, len(val))
+		for _, item := range val {
+			if s, ok := item.(string); ok && s != "" {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// claimToUint32 将 JSON 解码后的声明值（float64/json.Number/string）转换为 uint32
+func claimToUint32(v interface{}) (uint32, error) {
+	switch val := v.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		if val < 0 || val > float64(^uint32(0)) || val != float64(uint32(val)) {
+			return 0, fmt.Errorf("value %v out of range", val)
+		}
+		return uint32(val), nil
+	case json.Number:
+		n, err := strconv.ParseUint(val.String(), 10, 32)
+		if err != nil {
+			return 0, err
+		}
+		return uint32(n), nil
+	case string:
+		if val == "" {
+			return 0, nil
+		}
+		n, err := strconv.ParseUint(val, 10, 32)
+		if err != nil {
+			return 0, err
+		}
+		return uint32(n), nil
+	default:
+		return 0, fmt.Errorf("unsupported type %T", v)
+	}
+}
@@ -0,0 +1,151 @@
+package openapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testKeyStore() KeyStore {
+	return KeyStoreFunc(func(_ context.Context, keyID string) (*APIKey, error) {
+		switch keyID {
+		case "10001":
+			return &APIKey{ID: 10001, Secret: []byte("secret"), TenantID: 7, ProductCode: "crm"}, nil
+		case "10002":
+			return &APIKey{ID: 10002, Secret: []byte("secret"), Disabled: true}, nil
+		}
+		return nil, nil
+	})
+}
+
+func TestCanonicalRequest(t *testing.T) {
+	query := url.Values{"b": {"2", "1"}, "a": {"x y"}}
+	assert.Equal(t, "a=x+y&b=1&b=2", CanonicalQuery(query))
+
+	canonical := CanonicalRequest("get", "", nil, "", 1700000000, "abc")
+	assert.Equal(t, "GET\n/\n\n"+HashBody(nil)+"\n1700000000\nabc", canonical)
+}
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+
+	signer := NewSigner("10001", []byte("secret"), WithSignerClock(clock))
+	verifier := NewVerifier(testKeyStore(), WithVerifierClock(clock))
+
+	body := `{"name":"tom"}`
+	query := url.Values{"page": {"1"}}
+	headers := signer.SignHeaders(http.MethodPost, "/v1/orders", query, HashBody([]byte(body)))
+
+	req := &Request{
+		Method:    http.MethodPost,
+		Path:      "/v1/orders",
+		Query:     query,
+		BodyHash:  HashBody([]byte(body)),
+		KeyID:     headers[HeaderKeyID],
+		Timestamp: headers[HeaderTimestamp],
+		Nonce:     headers[HeaderNonce],
+		Signature: headers[HeaderSignature],
+	}
+
+	key, err := verifier.Verify(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10001), key.ID)
+
+	// 重放
+	_, err = verifier.Verify(context.Background(), req)
+	assert.ErrorIs(t, err, ErrNonceReused)
+
+	// 篡改请求体
+	tampered := *req
+	tampered.Nonce = "other"
+	tampered.BodyHash = HashBody([]byte(`{"name":"jerry"}`))
+	_, err = verifier.Verify(context.Background(), &tampered)
+	assert.ErrorIs(t, err, ErrSignatureMismatch)
+
+	// 时间超出窗口
+	skewed := NewVerifier(testKeyStore(), WithVerifierClock(func() time.Time { return now.Add(10 * time.Minute) }))
+	_, err = skewed.Verify(context.Background(), req)
+	assert.ErrorIs(t, err, ErrTimestampSkewed)
+
+	// 未知密钥
+	unknown := *req
+	unknown.KeyID = "404"
+	_, err = verifier.Verify(context.Background(), &unknown)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	// 已禁用密钥
+	disabled := *req
+	disabled.KeyID = "10002"
+	_, err = verifier.Verify(context.Background(), &disabled)
+	assert.ErrorIs(t, err, ErrKeyDisabled)
+
+	// 缺少签名
+	_, err = verifier.Verify(context.Background(), &Request{KeyID: "10001"})
+	assert.ErrorIs(t, err, ErrMissingSignature)
+}
+
+func TestSignRequestWithBodyHashFilter(t *testing.T) {
+	signer := NewSigner("10001", []byte("secret"))
+	verifier := NewVerifier(testKeyStore())
+
+	var verifyErr error
+	var received string
+	handler := BodyHashFilter()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		received = string(data)
+
+		bodyHash, _ := BodyHashFromContext(r.Context())
+		_, verifyErr = verifier.Verify(r.Context(), &Request{
+			Method:    r.Method,
+			Path:      r.URL.EscapedPath(),
+			Query:     r.URL.Query(),
+			BodyHash:  bodyHash,
+			KeyID:     r.Header.Get(HeaderKeyID),
+			Timestamp: r.Header.Get(HeaderTimestamp),
+			Nonce:     r.Header.Get(HeaderNonce),
+			Signature: r.Header.Get(HeaderSignature),
+		})
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/orders?b=2&a=1", strings.NewReader(`{"name":"tom"}`))
+	assert.NoError(t, signer.SignRequest(req))
+	assert.Equal(t, AuthTypeValue, req.Header.Get(HeaderAuthType))
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.NoError(t, verifyErr)
+	assert.Equal(t, `{"name":"tom"}`, received)
+
+	// 请求体超出大小限制时返回 413，不进入后续处理
+	received = ""
+	req = httptest.NewRequest(http.MethodPost, "/v1/orders", strings.NewReader(strings.Repeat("a", maxHashedBodySize+1)))
+	assert.NoError(t, signer.SignRequest(req))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Empty(t, received)
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryNonceStore()
+	store.now = func() time.Time { return now }
+
+	ok, err := store.CheckAndStore(context.Background(), "n1", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, _ = store.CheckAndStore(context.Background(), "n1", time.Minute)
+	assert.False(t, ok)
+
+	now = now.Add(2 * time.Minute)
+	ok, _ = store.CheckAndStore(context.Background(), "n1", time.Minute)
+	assert.True(t, ok)
+}
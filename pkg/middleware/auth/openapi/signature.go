@@ -0,0 +1,99 @@
+package openapi
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OpenAPI 签名相关的 Header
+const (
+	HeaderAuthType  = "X-Auth-Type"
+	HeaderKeyID     = "X-API-Key-ID"
+	HeaderTimestamp = "X-Timestamp"
+	HeaderNonce     = "X-Nonce"
+	HeaderSignature = "X-Signature"
+
+	AuthTypeValue = "openapi"
+)
+
+// Algorithm 签名算法
+const Algorithm = "HMAC-SHA256"
+
+// emptyBodyHash 空请求体的 SHA256
+var emptyBodyHash = HashBody(nil)
+
+// CanonicalRequest 构建待签名的规范请求字符串
+//
+// 格式（以换行符分隔）：
+//
+//	HTTP方法（大写）
+//	URL路径（已转义）
+//	按 key、value 排序并转义后的查询字符串
+//	请求体的 SHA256（小写十六进制）
+//	时间戳（Unix 秒）
+//	随机数
+func CanonicalRequest(method, path string, query url.Values, bodyHash string, timestamp int64, nonce string) string {
+	if path == "" {
+		path = "/"
+	}
+	if bodyHash == "" {
+		bodyHash = emptyBodyHash
+	}
+
+	return strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		CanonicalQuery(query),
+		bodyHash,
+		strconv.FormatInt(timestamp, 10),
+		nonce,
+	}, "\n")
+}
+
+// CanonicalQuery 将查询参数按 key、value 排序后编码
+func CanonicalQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// HashBody 计算请求体的 SHA256，返回小写十六进制
+func HashBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Sign 使用 HMAC-SHA256 对规范请求字符串签名，返回小写十六进制
+func Sign(secret []byte, canonicalRequest string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(canonicalRequest))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature 以常量时间比较签名
+func VerifySignature(secret []byte, canonicalRequest, signature string) bool {
+	expected := Sign(secret, canonicalRequest)
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
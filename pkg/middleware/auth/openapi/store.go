@@ -0,0 +1,78 @@
+package openapi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// APIKey OpenAPI 密钥信息
+type APIKey struct {
+	ID          uint64 // API Key ID
+	Secret      []byte // 签名密钥
+	TenantID    uint32 // 所属租户
+	ProductCode string // 所属产品编码
+	Disabled    bool   // 是否已禁用
+}
+
+// KeyStore 根据 X-API-Key-ID 查询密钥
+//
+// 未找到时应返回 (nil, nil)，仅在存储异常时返回 error。
+type KeyStore interface {
+	GetAPIKey(ctx context.Context, keyID string) (*APIKey, error)
+}
+
+// KeyStoreFunc 函数形式的 KeyStore
+type KeyStoreFunc func(ctx context.Context, keyID string) (*APIKey, error)
+
+func (f KeyStoreFunc) GetAPIKey(ctx context.Context, keyID string) (*APIKey, error) {
+	return f(ctx, keyID)
+}
+
+// NonceStore 随机数存储，用于防重放
+//
+// 在 ttl 内第一次出现的 nonce 返回 true，重复出现返回 false。
+// 多实例部署时应使用 Redis 等共享存储实现（如 SET NX EX）。
+type NonceStore interface {
+	CheckAndStore(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+}
+
+// MemoryNonceStore 基于内存的 NonceStore，仅适用于单实例或测试
+type MemoryNonceStore struct {
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryNonceStore 创建基于内存的 NonceStore
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{
+		nonces: make(map[string]time.Time),
+		now:    time.Now,
+	}
+}
+
+func (s *MemoryNonceStore) CheckAndStore(_ context.Context, nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	// 定期清理过期的 nonce，避免内存无限增长
+	if now.Sub(s.lastSweep) > ttl {
+		for k, expireAt := range s.nonces {
+			if now.After(expireAt) {
+				delete(s.nonces, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if expireAt, ok := s.nonces[nonce]; ok && now.Before(expireAt) {
+		return false, nil
+	}
+
+	s.nonces[nonce] = now.Add(ttl)
+	return true, nil
+}
@@ -0,0 +1,134 @@
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	kratosHttp "github.com/go-kratos/kratos/v2/transport/http"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrBodyHashUnavailable HTTP 请求携带了请求体，但未安装 BodyHashFilter
+var ErrBodyHashUnavailable = errors.New("openapi: request body hash is unavailable, install BodyHashFilter")
+
+// maxHashedBodySize BodyHashFilter 最多读取的请求体大小
+const maxHashedBodySize = 8 << 20
+
+type bodyHashKey struct{}
+
+// BodyHashFromContext 获取 BodyHashFilter 计算的请求体哈希
+func BodyHashFromContext(ctx context.Context) (string, bool) {
+	hash, ok := ctx.Value(bodyHashKey{}).(string)
+	return hash, ok
+}
+
+// BodyHashFilter HTTP 过滤器，为 OpenAPI 请求计算请求体的 SHA256 并还原请求体，请求体超过 8 MiB 时返回 413
+//
+// Kratos 在中间件执行前就已经解码了请求体，因此需要在过滤器阶段提前计算哈希：
+//
+//	http.NewServer(
+//	    http.Filter(openapi.BodyHashFilter()),
+//	    http.Middleware(auth.Server(true, auth.WithOpenAPIVerifier(verifier))),
+//	)
+func BodyHashFilter() kratosHttp.FilterFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(HeaderAuthType) != AuthTypeValue || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxHashedBodySize+1))
+			if err != nil {
+				http.Error(w, "failed to read request body", http.StatusBadRequest)
+				return
+			}
+
+			if len(body) > maxHashedBodySize {
+				// 超出大小限制的请求无法计算哈希，直接拒绝
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			_ = r.Body.Close()
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			ctx := context.WithValue(r.Context(), bodyHashKey{}, HashBody(body))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequestFromTransport 从 Kratos 服务端上下文中构建待验证的请求
+//
+// HTTP 请求使用真实的方法、路径、查询参数和 BodyHashFilter 计算的请求体哈希；
+// gRPC 请求固定使用 POST 方法，路径为 Operation，请求体为确定性序列化的 protobuf 消息。
+func RequestFromTransport(ctx context.Context, tr transport.Transporter, req interface{}) (*Request, error) {
+	header := tr.RequestHeader()
+	r := &Request{
+		KeyID:     header.Get(HeaderKeyID),
+		Timestamp: header.Get(HeaderTimestamp),
+		Nonce:     header.Get(HeaderNonce),
+		Signature: header.Get(HeaderSignature),
+	}
+
+	if ht, ok := tr.(kratosHttp.Transporter); ok && ht.Request() != nil {
+		hr := ht.Request()
+		r.Method = hr.Method
+		r.Path = hr.URL.EscapedPath()
+		r.Query = hr.URL.Query()
+
+		if hash, ok := BodyHashFromContext(ctx); ok {
+			r.BodyHash = hash
+		} else if hr.ContentLength != 0 {
+			return nil, ErrBodyHashUnavailable
+		}
+		return r, nil
+	}
+
+	bodyHash, err := protoBodyHash(req)
+	if err != nil {
+		return nil, err
+	}
+	r.Method = http.MethodPost
+	r.Path = tr.Operation()
+	r.BodyHash = bodyHash
+
+	return r, nil
+}
+
+// Client Kratos gRPC 客户端中间件，为每个请求附加 OpenAPI 签名
+func Client(signer *Signer) middleware.Middleware {
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if tr, ok := transport.FromClientContext(ctx); ok {
+				bodyHash, err := protoBodyHash(req)
+				if err != nil {
+					return nil, err
+				}
+				headers := signer.SignHeaders(http.MethodPost, tr.Operation(), nil, bodyHash)
+				for k, v := range headers {
+					tr.RequestHeader().Set(k, v)
+				}
+			}
+			return handler(ctx, req)
+		}
+	}
+}
+
+// protoBodyHash 计算 protobuf 消息确定性序列化后的 SHA256
+func protoBodyHash(req interface{}) (string, error) {
+	msg, ok := req.(proto.Message)
+	if !ok || msg == nil {
+		return HashBody(nil), nil
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	return HashBody(data), nil
+}
@@ -0,0 +1,140 @@
+package openapi
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrMissingSignature  = errors.New("openapi: signature headers are missing")
+	ErrInvalidTimestamp  = errors.New("openapi: invalid timestamp")
+	ErrTimestampSkewed   = errors.New("openapi: timestamp is outside the allowed window")
+	ErrUnknownKey        = errors.New("openapi: unknown api key")
+	ErrKeyDisabled       = errors.New("openapi: api key is disabled")
+	ErrNonceReused       = errors.New("openapi: nonce has already been used")
+	ErrSignatureMismatch = errors.New("openapi: signature mismatch")
+)
+
+const (
+	// DefaultClockSkew 默认允许的客户端与服务端时钟偏差
+	DefaultClockSkew = 5 * time.Minute
+
+	// maxNonceLength nonce 的最大长度
+	maxNonceLength = 128
+)
+
+// Request 待验证的请求
+type Request struct {
+	Method    string
+	Path      string
+	Query     url.Values
+	BodyHash  string
+	KeyID     string
+	Timestamp string
+	Nonce     string
+	Signature string
+}
+
+type VerifierOption func(*Verifier)
+
+// WithClockSkew 设置允许的时钟偏差，默认 5 分钟
+func WithClockSkew(skew time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		if skew > 0 {
+			v.skew = skew
+		}
+	}
+}
+
+// WithNonceStore 设置防重放的 nonce 存储，默认使用内存存储
+func WithNonceStore(store NonceStore) VerifierOption {
+	return func(v *Verifier) {
+		v.nonces = store
+	}
+}
+
+// WithVerifierClock 设置时钟，便于测试
+func WithVerifierClock(now func() time.Time) VerifierOption {
+	return func(v *Verifier) {
+		if now != nil {
+			v.now = now
+		}
+	}
+}
+
+// Verifier 服务端签名验证器
+type Verifier struct {
+	keys   KeyStore
+	nonces NonceStore
+	skew   time.Duration
+	now    func() time.Time
+}
+
+// NewVerifier 创建签名验证器
+func NewVerifier(keys KeyStore, opts ...VerifierOption) *Verifier {
+	v := &Verifier{
+		keys:   keys,
+		nonces: NewMemoryNonceStore(),
+		skew:   DefaultClockSkew,
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Verify 验证请求签名，成功时返回对应的 APIKey
+//
+// 校验顺序：必填头 -> 时间窗口 -> 密钥 -> 签名 -> nonce，
+// nonce 只在签名通过后记录，避免未授权请求耗尽 nonce 空间。
+func (v *Verifier) Verify(ctx context.Context, req *Request) (*APIKey, error) {
+	if req.KeyID == "" || req.Timestamp == "" || req.Nonce == "" || req.Signature == "" {
+		return nil, ErrMissingSignature
+	}
+	if len(req.Nonce) > maxNonceLength {
+		return nil, ErrMissingSignature
+	}
+
+	timestamp, err := strconv.ParseInt(req.Timestamp, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidTimestamp
+	}
+
+	diff := v.now().Sub(time.Unix(timestamp, 0))
+	if diff < -v.skew || diff > v.skew {
+		return nil, ErrTimestampSkewed
+	}
+
+	key, err := v.keys.GetAPIKey(ctx, req.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrUnknownKey
+	}
+	if key.Disabled {
+		return nil, ErrKeyDisabled
+	}
+
+	canonical := CanonicalRequest(req.Method, req.Path, req.Query, req.BodyHash, timestamp, req.Nonce)
+	if !VerifySignature(key.Secret, canonical, req.Signature) {
+		return nil, ErrSignatureMismatch
+	}
+
+	if v.nonces != nil {
+		// nonce 需要覆盖整个可接受的时间窗口
+		fresh, err := v.nonces.CheckAndStore(ctx, req.KeyID+":"+req.Nonce, 2*v.skew)
+		if err != nil {
+			return nil, err
+		}
+		if !fresh {
+			return nil, ErrNonceReused
+		}
+	}
+
+	return key, nil
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/heyinLab/common/pkg/middleware/auth/openapi"
+)
+
+// Mode 认证模式
+type Mode int
+
+const (
+	// ModeHeader 信任网关注入的 X-User-ID/X-Tenant-ID/X-Region-Name 头，
+	// 仅适用于部署在网关之后、无法被直接访问的服务
+	ModeHeader Mode = iota
+	// ModeToken 自行校验 Authorization: Bearer 中的 JWT，并从中解析 Claims
+	ModeToken
+)
+
+// ClaimsMapper 将已验证的 JWT 声明映射为 Claims
+type ClaimsMapper func(claims jwt.MapClaims) (*Claims, error)
+
+type Option func(*options)
+
+type options struct {
+	mode Mode
+
+	keyFunc       jwt.Keyfunc
+	validMethods  []string
+	issuer        string
+	audience      string
+	leeway        time.Duration
+	requireExpiry bool
+	claimsMapper  ClaimsMapper
+
+	openapiVerifier *openapi.Verifier
+
+	policies *PolicyTable
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		mode:          ModeHeader,
+		requireExpiry: true,
+		claimsMapper:  DefaultClaimsMapper,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithMode 设置认证模式，默认为 ModeHeader
+func WithMode(mode Mode) Option {
+	return func(o *options) {
+		o.mode = mode
+	}
+}
+
+// WithSigningKey 使用 HMAC 密钥校验 JWT，并切换到 ModeToken
+func WithSigningKey(secretKey []byte) Option {
+	return func(o *options) {
+		o.mode = ModeToken
+		o.keyFunc = func(*jwt.Token) (interface{}, error) {
+			return secretKey, nil
+		}
+		if len(o.validMethods) == 0 {
+			o.validMethods = []string{
+				jwt.SigningMethodHS256.Alg(),
+				jwt.SigningMethodHS384.Alg(),
+				jwt.SigningMethodHS512.Alg(),
+			}
+		}
+	}
+}
+
+// WithKeyFunc 使用自定义的 keyFunc 获取验签密钥（如 RSA/ECDSA 公钥），并切换到 ModeToken，
+// 必须同时通过 WithValidMethods 限定签名算法，防止 keyFunc 返回的密钥被用于非预期的算法
+func WithKeyFunc(keyFunc jwt.Keyfunc) Option {
+	return func(o *options) {
+		o.mode = ModeToken
+		o.keyFunc = keyFunc
+	}
+}
+
+// WithValidMethods 限定允许的签名算法，例如 "HS256"、"RS256"
+func WithValidMethods(methods ...string) Option {
+	return func(o *options) {
+		o.validMethods = methods
+	}
+}
+
+// WithIssuer 校验 JWT 的 iss 声明
+func WithIssuer(issuer string) Option {
+	return func(o *options) {
+		o.issuer = issuer
+	}
+}
+
+// WithAudience 校验 JWT 的 aud 声明
+func WithAudience(audience string) Option {
+	return func(o *options) {
+		o.audience = audience
+	}
+}
+
+// WithLeeway 设置校验 exp/nbf/iat 时允许的时钟偏差
+func WithLeeway(leeway time.Duration) Option {
+	return func(o *options) {
+		o.leeway = leeway
+	}
+}
+
+// WithExpirationRequired 设置是否要求 JWT 必须携带 exp 声明，默认要求
+func WithExpirationRequired(required bool) Option {
+	return func(o *options) {
+		o.requireExpiry = required
+	}
+}
+
+// WithClaimsMapper 自定义 JWT 声明到 Claims 的映射
+func WithClaimsMapper(mapper ClaimsMapper) Option {
+	return func(o *options) {
+		if mapper != nil {
+			o.claimsMapper = mapper
+		}
+	}
+}
+
+// WithOpenAPIVerifier 对 X-Auth-Type: openapi 的请求校验 HMAC 签名
+//
+// 未设置时，ModeHeader 模式下沿用网关注入的 X-API-Key-ID/X-Product-Code，
+// ModeToken 模式下忽略 X-Auth-Type 并要求 Bearer Token。
+func WithOpenAPIVerifier(verifier *openapi.Verifier) Option {
+	return func(o *options) {
+		o.openapiVerifier = verifier
+	}
+}
+
+// WithPolicies 按 Operation 配置不同的认证策略，例如放行健康检查、登录等公开接口
+func WithPolicies(policies *PolicyTable) Option {
+	return func(o *options) {
+		o.policies = policies
+	}
+}
+
+// parserOptions 根据配置生成 jwt.Parser 的校验选项
+func (o *options) parserOptions() []jwt.ParserOption {
+	var opts []jwt.ParserOption
+	if len(o.validMethods) > 0 {
+		opts = append(opts, jwt.WithValidMethods(o.validMethods))
+	}
+	if o.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(o.issuer))
+	}
+	if o.audience != "" {
+		opts = append(opts, jwt.WithAudience(o.audience))
+	}
+	if o.leeway > 0 {
+		opts = append(opts, jwt.WithLeeway(o.leeway))
+	}
+	if o.requireExpiry {
+		opts = append(opts, jwt.WithExpirationRequired())
+	}
+	return opts
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/gobwas/glob"
+)
+
+// Access 接口允许的认证方式
+type Access int
+
+const (
+	// AccessEither 允许用户身份或 OpenAPI 签名，与未配置策略时的行为一致
+	AccessEither Access = iota
+	// AccessAnonymous 无需认证，不读取任何认证信息
+	AccessAnonymous
+	// AccessToken 仅允许用户身份（ModeHeader 的网关头或 ModeToken 的 JWT）
+	AccessToken
+	// AccessOpenAPI 仅允许 OpenAPI 请求
+	AccessOpenAPI
+)
+
+func (a Access) String() string {
+	switch a {
+	case AccessEither:
+		return "either"
+	case AccessAnonymous:
+		return "anonymous"
+	case AccessToken:
+		return "token"
+	case AccessOpenAPI:
+		return "openapi"
+	default:
+		return fmt.Sprintf("Access(%d)", int(a))
+	}
+}
+
+// TenantRule 接口对租户的要求
+type TenantRule int
+
+const (
+	// TenantInherit 沿用 Server 的 needTenant 参数
+	TenantInherit TenantRule = iota
+	// TenantRequired 必须携带租户
+	TenantRequired
+	// TenantOptional 租户可选
+	TenantOptional
+)
+
+// Policy 接口的认证策略
+type Policy struct {
+	Access Access
+	Tenant TenantRule
+}
+
+// 常用策略
+var (
+	PolicyAnonymous = Policy{Access: AccessAnonymous}
+	PolicyToken     = Policy{Access: AccessToken}
+	PolicyOpenAPI   = Policy{Access: AccessOpenAPI}
+	PolicyEither    = Policy{Access: AccessEither}
+)
+
+// WithoutTenant 返回租户可选的策略副本
+func (p Policy) WithoutTenant() Policy {
+	p.Tenant = TenantOptional
+	return p
+}
+
+// WithTenant 返回必须携带租户的策略副本
+func (p Policy) WithTenant() Policy {
+	p.Tenant = TenantRequired
+	return p
+}
+
+// needTenant 根据策略和默认值判断是否需要租户
+func (p Policy) needTenant(def bool) bool {
+	switch p.Tenant {
+	case TenantRequired:
+		return true
+	case TenantOptional:
+		return false
+	default:
+		return def
+	}
+}
+
+type policyRule struct {
+	pattern string
+	glob    glob.Glob
+	policy  Policy
+}
+
+// PolicyTable 按 Operation 匹配认证策略的规则表
+//
+// 规则按添加顺序匹配，命中第一条即返回；均未命中时使用默认策略（PolicyEither）。
+// 模式使用 glob 语法并以 "/" 为分隔符，"*" 不跨越 "/"，"**" 可跨越 "/"。
+//
+// 使用示例:
+//
+//	policies := auth.NewPolicyTable().
+//	    Add("/grpc.health.v1.Health/*", auth.PolicyAnonymous).
+//	    Add("/user.v1.AuthService/Login", auth.PolicyAnonymous).
+//	    Add("/user.v1.UserService/GetProfile", auth.PolicyToken.WithoutTenant()).
+//	    Add("/order.v1.OpenOrderService/*", auth.PolicyOpenAPI).
+//	    Add("/order.v1.OrderService/*", auth.PolicyToken)
+//
+//	auth.Server(true, auth.WithPolicies(policies))
+type PolicyTable struct {
+	rules         []policyRule
+	defaultPolicy Policy
+}
+
+// NewPolicyTable 创建空的策略表
+func NewPolicyTable() *PolicyTable {
+	return &PolicyTable{defaultPolicy: PolicyEither}
+}
+
+// Add 添加一条规则，模式非法时 panic
+func (t *PolicyTable) Add(pattern string, policy Policy) *PolicyTable {
+	g, err := glob.Compile(pattern, '/')
+	if err != nil {
+		panic(fmt.Sprintf("auth: invalid policy pattern %q: %v", pattern, err))
+	}
+	t.rules = append(t.rules, policyRule{pattern: pattern, glob: g, policy: policy})
+	return t
+}
+
+// Default 设置未命中任何规则时使用的策略
+func (t *PolicyTable) Default(policy Policy) *PolicyTable {
+	t.defaultPolicy = policy
+	return t
+}
+
+// Match 返回 Operation 对应的策略
+func (t *PolicyTable) Match(operation string) Policy {
+	if t == nil {
+		return PolicyEither
+	}
+	for _, r := range t.rules {
+		if r.glob.Match(operation) {
+			return r.policy
+		}
+	}
+	return t.defaultPolicy
+}
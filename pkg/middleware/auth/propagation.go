@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/heyinLab/common/pkg/middleware/common"
+)
+
+// AuthContextVersion 当前认证上下文的编码版本
+//
+// 仅新增字段时无需升级版本；字段含义发生不兼容变化时需升级版本，
+// 旧版本的服务会拒绝解码更高版本的上下文，并回退到 X-User-ID 等单独的 Header。
+const AuthContextVersion = 1
+
+// AuthContext 服务间传递的完整认证上下文
+type AuthContext struct {
+	Version     int             `json:"v"`
+	UserID      uint32          `json:"uid,omitempty"`
+	TenantID    uint32          `json:"tid,omitempty"`
+	RegionName  string          `json:"region,omitempty"`
+	AuthType    common.AuthType `json:"type,omitempty"`
+	APIKeyID    uint64          `json:"key,omitempty"`
+	ProductCode string          `json:"product,omitempty"`
+	Roles       []string        `json:"roles,omitempty"`
+	Scopes      []string        `json:"scopes,omitempty"`
+}
+
+// AuthContextFromContext 从 context 中收集认证上下文，未经过认证时返回 false
+func AuthContextFromContext(ctx context.Context) (*AuthContext, bool) {
+	claims, ok := FromContext(ctx)
+	if !ok || claims == nil {
+		return nil, false
+	}
+
+	ac := &AuthContext{
+		Version:    AuthContextVersion,
+		UserID:     claims.UserID,
+		TenantID:   claims.TenantID,
+		RegionName: claims.RegionName,
+		Roles:      claims.Roles,
+		Scopes:     claims.Scopes,
+	}
+	if v, ok := ctx.Value(common.KeyAuthType).(common.AuthType); ok {
+		ac.AuthType = v
+	}
+	ac.APIKeyID = GetAPIKeyID(ctx)
+	ac.ProductCode = GetProductCode(ctx)
+
+	return ac, true
+}
+
+// NewContext 将认证上下文写入 context，效果与 auth.Server 认证通过后一致
+func (ac *AuthContext) NewContext(ctx context.Context) context.Context {
+	ctx = NewContext(ctx, &Claims{
+		UserID:     ac.UserID,
+		TenantID:   ac.TenantID,
+		RegionName: ac.RegionName,
+		Roles:      ac.Roles,
+		Scopes:     ac.Scopes,
+	})
+	if ac.AuthType != "" {
+		ctx = context.WithValue(ctx, common.KeyAuthType, ac.AuthType)
+	}
+	if ac.APIKeyID != 0 {
+		ctx = context.WithValue(ctx, common.KeyAPIKeyID, ac.APIKeyID)
+	}
+	if ac.ProductCode != "" {
+		ctx = context.WithValue(ctx, common.KeyProductCode, ac.ProductCode)
+	}
+	return ctx
+}
+
+// Encode 将认证上下文编码为可放入 Header/Metadata 的字符串
+func (ac *AuthContext) Encode() (string, error) {
+	data, err := json.Marshal(ac)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeAuthContext 解码 Encode 生成的字符串
+func DecodeAuthContext(value string) (*AuthContext, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth context encoding: %w", err)
+	}
+
+	ac := &AuthContext{}
+	if err = json.Unmarshal(data, ac); err != nil {
+		return nil, fmt.Errorf("invalid auth context: %w", err)
+	}
+	if ac.Version < 1 || ac.Version > AuthContextVersion {
+		return nil, fmt.Errorf("unsupported auth context version %d", ac.Version)
+	}
+
+	return ac, nil
+}
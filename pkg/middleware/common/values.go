@@ -5,6 +5,8 @@ const (
 	USERID     string = "X-User-ID"
 	TENANTID   string = "X-Tenant-ID"
 	REGIONNAME string = "X-Region-Name"
+
+	AUTHORIZATION string = "Authorization"
 )
 
 // OpenAPI 认证相关的 context key
@@ -23,3 +25,11 @@ const (
 	AuthTypeToken   AuthType = "token"   // JWT Token 认证
 	AuthTypeOpenAPI AuthType = "openapi" // OpenAPI 签名认证
 )
+
+// 服务间传递完整认证上下文的 Header
+const (
+	AUTHCONTEXT string = "X-Auth-Context"
+	AUTHTYPE    string = "X-Auth-Type"
+	APIKEYID    string = "X-API-Key-ID"
+	PRODUCTCODE string = "X-Product-Code"
+)
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+
+	authWare "github.com/heyinLab/common/pkg/middleware/auth"
+	"github.com/heyinLab/common/pkg/middleware/common"
+)
+
+// forwardAndExtract 模拟一次 ForwardClaims -> ExtractClaims 的服务间调用
+func forwardAndExtract(t *testing.T, ctx context.Context) context.Context {
+	var outgoing metadata.MD
+	_, err := ForwardClaims()(func(ctx context.Context, req interface{}) (interface{}, error) {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil, nil
+	})(ctx, nil)
+	assert.NoError(t, err)
+
+	return extract(t, outgoing)
+}
+
+func extract(t *testing.T, md metadata.MD) context.Context {
+	var downstream context.Context
+	_, err := ExtractClaims()(func(ctx context.Context, req interface{}) (interface{}, error) {
+		downstream = ctx
+		return nil, nil
+	})(metadata.NewIncomingContext(context.Background(), md), nil)
+	assert.NoError(t, err)
+	return downstream
+}
+
+func TestClaimsPropagation(t *testing.T) {
+	// 用户请求
+	ctx := authWare.NewContext(context.Background(), &authWare.Claims{
+		UserID:     12,
+		TenantID:   34,
+		RegionName: "cn",
+		Roles:      []string{"admin"},
+		Scopes:     []string{"order:read"},
+	})
+	ctx = context.WithValue(ctx, common.KeyAuthType, common.AuthTypeToken)
+
+	downstream := forwardAndExtract(t, ctx)
+	claims, ok := authWare.FromContext(downstream)
+	assert.True(t, ok)
+	assert.Equal(t, &authWare.Claims{
+		UserID:     12,
+		TenantID:   34,
+		RegionName: "cn",
+		Roles:      []string{"admin"},
+		Scopes:     []string{"order:read"},
+	}, claims)
+	assert.Equal(t, authWare.GetOperator(ctx), authWare.GetOperator(downstream))
+
+	// OpenAPI 请求，UserID 为 0
+	ctx = authWare.NewContext(context.Background(), &authWare.Claims{TenantID: 7})
+	ctx = context.WithValue(ctx, common.KeyAuthType, common.AuthTypeOpenAPI)
+	ctx = context.WithValue(ctx, common.KeyAPIKeyID, uint64(10001))
+	ctx = context.WithValue(ctx, common.KeyProductCode, "crm")
+
+	downstream = forwardAndExtract(t, ctx)
+	claims, _ = authWare.FromContext(downstream)
+	assert.Equal(t, uint32(7), claims.TenantID)
+	assert.True(t, authWare.IsOpenAPIRequest(downstream))
+	assert.Equal(t, "crm", authWare.GetProductCode(downstream))
+	assert.Equal(t, authWare.Operator{Type: "api_key", ID: 10001}, authWare.GetOperator(downstream))
+}
+
+func TestExtractClaimsFallback(t *testing.T) {
+	// 未升级的上游服务只发送单独的字段
+	downstream := extract(t, metadata.Pairs(
+		common.USERID, "12",
+		common.TENANTID, "34",
+	))
+	claims, _ := authWare.FromContext(downstream)
+	assert.Equal(t, &authWare.Claims{UserID: 12, TenantID: 34}, claims)
+
+	// 更高版本的认证上下文无法解码时回退到单独的字段
+	future, _ := (&authWare.AuthContext{Version: authWare.AuthContextVersion + 1, UserID: 99}).Encode()
+	downstream = extract(t, metadata.Pairs(
+		common.AUTHCONTEXT, future,
+		common.USERID, "12",
+	))
+	claims, _ = authWare.FromContext(downstream)
+	assert.Equal(t, uint32(12), claims.UserID)
+
+	// 没有认证信息
+	_, ok := authWare.FromContext(extract(t, metadata.MD{}))
+	assert.False(t, ok)
+}
@@ -15,35 +15,10 @@ func ExtractClaims() middleware.Middleware {
 		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
 			// 1. 获取 gRPC 传入的 metadata
 			if md, ok := metadata.FromIncomingContext(ctx); ok {
-				// 准备一个空的 claims 对象
-				claims := &authWare.Claims{}
-				hasData := false
-
-				// 2. 安全地解析 UserID
-				// 注意：md.Get 返回的是切片，必须检查长度防止 panic
-				if vals := md.Get(common.USERID); len(vals) > 0 {
-					if uid, err := strconv.ParseUint(vals[0], 10, 32); err == nil {
-						claims.UserID = uint32(uid)
-						hasData = true
-					}
-				}
-
-				// 3. 解析 TenantID
-				if vals := md.Get(common.TENANTID); len(vals) > 0 {
-					if tid, err := strconv.ParseUint(vals[0], 10, 32); err == nil {
-						claims.TenantID = uint32(tid)
-					}
-				}
-
-				// 4. 解析 RegionName
-				if vals := md.Get(common.REGIONNAME); len(vals) > 0 {
-					claims.RegionName = vals[0]
-				}
-
-				// 5. 如果成功提取到了数据，将其注入到 Context 中
+				// 2. 优先使用完整的认证上下文，版本不支持或解码失败时回退到单独的字段
 				// 这样后续的业务逻辑（Service层）就可以通过 authWare.FromContext(ctx) 拿到了
-				if hasData {
-					ctx = authWare.NewContext(ctx, claims)
+				if ac := authContextFromMetadata(md); ac != nil {
+					ctx = ac.NewContext(ctx)
 				}
 			}
 
@@ -51,3 +26,50 @@ func ExtractClaims() middleware.Middleware {
 		}
 	}
 }
+
+// authContextFromMetadata 从 metadata 中解析认证上下文，没有认证信息时返回 nil
+func authContextFromMetadata(md metadata.MD) *authWare.AuthContext {
+	if vals := md.Get(common.AUTHCONTEXT); len(vals) > 0 {
+		if ac, err := authWare.DecodeAuthContext(vals[0]); err == nil {
+			return ac
+		}
+	}
+
+	// 准备一个空的认证上下文
+	ac := &authWare.AuthContext{Version: authWare.AuthContextVersion}
+
+	// 注意：md.Get 返回的是切片，必须检查长度防止 panic
+	if vals := md.Get(common.USERID); len(vals) > 0 {
+		if uid, err := strconv.ParseUint(vals[0], 10, 32); err == nil {
+			ac.UserID = uint32(uid)
+		}
+	}
+
+	if vals := md.Get(common.TENANTID); len(vals) > 0 {
+		if tid, err := strconv.ParseUint(vals[0], 10, 32); err == nil {
+			ac.TenantID = uint32(tid)
+		}
+	}
+
+	if vals := md.Get(common.REGIONNAME); len(vals) > 0 {
+		ac.RegionName = vals[0]
+	}
+
+	// OpenAPI 请求的 UserID 为 0，依靠认证类型和 API Key ID 识别
+	if vals := md.Get(common.AUTHTYPE); len(vals) > 0 && vals[0] == string(common.AuthTypeOpenAPI) {
+		ac.AuthType = common.AuthTypeOpenAPI
+		if vals := md.Get(common.APIKEYID); len(vals) > 0 {
+			if id, err := strconv.ParseUint(vals[0], 10, 64); err == nil {
+				ac.APIKeyID = id
+			}
+		}
+		if vals := md.Get(common.PRODUCTCODE); len(vals) > 0 {
+			ac.ProductCode = vals[0]
+		}
+	}
+
+	if ac.UserID == 0 && ac.APIKeyID == 0 {
+		return nil
+	}
+	return ac
+}
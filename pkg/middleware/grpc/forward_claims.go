@@ -13,17 +13,33 @@ import (
 func ForwardClaims() middleware.Middleware {
 	return func(handler middleware.Handler) middleware.Handler {
 		return func(ctx context.Context, req interface{}) (reply interface{}, err error) {
-			// 1. 从当前上下文中获取认证信息 (通常是 HTTP 侧解析 token 后放进去的)
-			claims, ok := authWare.FromContext(ctx)
-			if ok && claims != nil && claims.UserID != 0 {
-				// 2. 将关键字段转换为字符串并放入 gRPC Metadata
-				// 使用 AppendToOutgoingContext 可以保留已有的 metadata (如 trace_id)
-				ctx = metadata.AppendToOutgoingContext(ctx,
-					common.USERID, strconv.FormatUint(uint64(claims.UserID), 10),
-					common.TENANTID, strconv.FormatUint(uint64(claims.TenantID), 10),
-					common.REGIONNAME, claims.RegionName,
+			// 1. 从当前上下文中获取完整的认证信息 (通常是 HTTP 侧认证通过后放进去的)
+			ac, ok := authWare.AuthContextFromContext(ctx)
+			if !ok || (ac.UserID == 0 && ac.TenantID == 0 && ac.APIKeyID == 0) {
+				return handler(ctx, req)
+			}
+
+			// 2. 将关键字段转换为字符串并放入 gRPC Metadata，供未升级的下游服务读取
+			// 使用 AppendToOutgoingContext 可以保留已有的 metadata (如 trace_id)
+			kv := []string{
+				common.USERID, strconv.FormatUint(uint64(ac.UserID), 10),
+				common.TENANTID, strconv.FormatUint(uint64(ac.TenantID), 10),
+				common.REGIONNAME, ac.RegionName,
+			}
+			if ac.AuthType == common.AuthTypeOpenAPI {
+				kv = append(kv,
+					common.AUTHTYPE, string(ac.AuthType),
+					common.APIKEYID, strconv.FormatUint(ac.APIKeyID, 10),
+					common.PRODUCTCODE, ac.ProductCode,
 				)
 			}
+
+			// 3. 完整的认证上下文（含 OpenAPI 信息、角色和权限范围）
+			if encoded, err := ac.Encode(); err == nil {
+				kv = append(kv, common.AUTHCONTEXT, encoded)
+			}
+
+			ctx = metadata.AppendToOutgoingContext(ctx, kv...)
 			return handler(ctx, req)
 		}
 	}
@@ -0,0 +1,317 @@
+package permission
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCacheTTL     = 5 * time.Minute
+	DefaultFetchTimeout = 5 * time.Second
+)
+
+// sweepConcurrency 后台刷新时同时调用 Provider 的最大数量
+const sweepConcurrency = 16
+
+type CacheOption func(*CodeCache)
+
+// WithTTL 设置权限码的有效期，默认 5 分钟
+func WithTTL(ttl time.Duration) CacheOption {
+	return func(c *CodeCache) {
+		if ttl > 0 {
+			c.ttl = ttl
+		}
+	}
+}
+
+// WithMaxStale 设置权限码的最长使用时间，默认为 TTL 的 3 倍
+//
+// 刷新一直失败时，超过该时间的权限码被淘汰，下次访问时同步拉取，拉取失败则拒绝请求，
+// 避免 IAM 服务不可用期间已撤销的权限一直有效。小于 TTL 时使用 TTL。
+func WithMaxStale(maxStale time.Duration) CacheOption {
+	return func(c *CodeCache) {
+		if maxStale > 0 {
+			c.maxStale = maxStale
+		}
+	}
+}
+
+// WithRefreshInterval 设置后台刷新的间隔，默认为 TTL 的一半
+func WithRefreshInterval(interval time.Duration) CacheOption {
+	return func(c *CodeCache) {
+		if interval > 0 {
+			c.refreshInterval = interval
+		}
+	}
+}
+
+// WithIdleTimeout 设置空闲淘汰时间，超过该时间未被访问的主体不再后台刷新，默认为 TTL 的 3 倍
+func WithIdleTimeout(idle time.Duration) CacheOption {
+	return func(c *CodeCache) {
+		if idle > 0 {
+			c.idleTimeout = idle
+		}
+	}
+}
+
+// WithFetchTimeout 设置后台刷新时调用 Provider 的超时时间，默认 5 秒
+func WithFetchTimeout(timeout time.Duration) CacheOption {
+	return func(c *CodeCache) {
+		if timeout > 0 {
+			c.fetchTimeout = timeout
+		}
+	}
+}
+
+type cacheEntry struct {
+	ctx        context.Context // 最近一次访问的请求 context，不会被取消，用于刷新
+	subject    Subject
+	codes      *CodeSet
+	fetchedAt  time.Time
+	lastAccess time.Time
+	refreshing bool
+}
+
+// CodeCache 带 TTL 和后台刷新的权限码缓存
+//
+// - 首次访问时同步拉取，同一主体的并发请求只会触发一次拉取；
+// - 过期后继续返回旧数据，并异步刷新；
+// - 后台定时刷新最近访问过的主体，淘汰长时间未访问的主体；
+// - 刷新失败时保留旧数据，超过最长使用时间（WithMaxStale）后淘汰；
+// - 刷新使用请求 context 中的值（如链路追踪），不受请求取消的影响；
+// - Invalidate 之前开始的拉取和刷新，结果不会写入缓存。
+//
+// 使用示例:
+//
+//	cache := permission.NewCodeCache(
+//	    permission.ProviderFunc(func(ctx context.Context, s permission.Subject) ([]string, error) {
+//	        return roleRepo.ListGrantedCodes(ctx, s.TenantID, s.UserID, s.ProductCode)
+//	    }),
+//	    permission.WithTTL(time.Minute),
+//	)
+//	defer cache.Close()
+type CodeCache struct {
+	provider Provider
+
+	ttl             time.Duration
+	maxStale        time.Duration
+	refreshInterval time.Duration
+	idleTimeout     time.Duration
+	fetchTimeout    time.Duration
+	now             func() time.Time
+
+	mu          sync.Mutex
+	entries     map[string]*cacheEntry
+	inflight    map[string]*call
+	loading     map[string]int    // 正在进行的拉取数量，包括 Invalidate 后不再共享的拉取
+	generations map[string]uint64 // Invalidate 的次数，拉取开始后发生变化时丢弃结果
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+type call struct {
+	wg    sync.WaitGroup
+	gen   uint64
+	codes *CodeSet
+	err   error
+}
+
+// NewCodeCache 创建权限码缓存，并启动后台刷新
+func NewCodeCache(provider Provider, opts ...CacheOption) *CodeCache {
+	c := &CodeCache{
+		provider:     provider,
+		ttl:          DefaultCacheTTL,
+		fetchTimeout: DefaultFetchTimeout,
+		now:          time.Now,
+		entries:      make(map[string]*cacheEntry),
+		inflight:     make(map[string]*call),
+		loading:      make(map[string]int),
+		generations:  make(map[string]uint64),
+		done:         make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.maxStale <= 0 {
+		c.maxStale = c.ttl * 3
+	}
+	if c.maxStale < c.ttl {
+		c.maxStale = c.ttl
+	}
+	if c.refreshInterval <= 0 {
+		c.refreshInterval = c.ttl / 2
+	}
+	if c.idleTimeout <= 0 {
+		c.idleTimeout = c.ttl * 3
+	}
+
+	go c.loop()
+
+	return c
+}
+
+// Close 停止后台刷新
+func (c *CodeCache) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+// Get 获取主体的权限码集合
+func (c *CodeCache) Get(ctx context.Context, subject Subject) (*CodeSet, error) {
+	key := subject.Key()
+	now := c.now()
+
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		if now.Sub(e.fetchedAt) < c.maxStale {
+			e.lastAccess = now
+			e.ctx = context.WithoutCancel(ctx)
+			codes := e.codes
+			if now.Sub(e.fetchedAt) >= c.ttl && !e.refreshing {
+				e.refreshing = true
+				go c.refresh(e.ctx, key, e)
+			}
+			c.mu.Unlock()
+			return codes, nil
+		}
+		// 超过最长使用时间，淘汰后同步拉取
+		delete(c.entries, key)
+	}
+	c.mu.Unlock()
+
+	return c.load(ctx, key, subject)
+}
+
+// Invalidate 使主体的缓存失效，下次访问时重新拉取，正在进行的拉取和刷新的结果将被丢弃
+func (c *CodeCache) Invalidate(subject Subject) {
+	key := subject.Key()
+
+	c.mu.Lock()
+	delete(c.entries, key)
+	delete(c.inflight, key)
+	c.generations[key]++
+	c.mu.Unlock()
+}
+
+// load 同步拉取权限码，同一主体的并发调用共享一次拉取结果
+func (c *CodeCache) load(ctx context.Context, key string, subject Subject) (*CodeSet, error) {
+	c.mu.Lock()
+	if cl, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		cl.wg.Wait()
+		return cl.codes, cl.err
+	}
+	cl := &call{gen: c.generations[key]}
+	cl.wg.Add(1)
+	c.inflight[key] = cl
+	c.loading[key]++
+	c.mu.Unlock()
+
+	codes, err := c.provider.GrantedCodes(ctx, subject)
+	if err == nil {
+		cl.codes = NewCodeSet(codes)
+	}
+	cl.err = err
+
+	c.mu.Lock()
+	if c.inflight[key] == cl {
+		delete(c.inflight, key)
+	}
+	if c.loading[key]--; c.loading[key] == 0 {
+		delete(c.loading, key)
+	}
+	if err == nil && c.generations[key] == cl.gen {
+		now := c.now()
+		c.entries[key] = &cacheEntry{ctx: context.WithoutCancel(ctx), subject: subject, codes: cl.codes, fetchedAt: now, lastAccess: now}
+	}
+	c.mu.Unlock()
+	cl.wg.Done()
+
+	return cl.codes, cl.err
+}
+
+// refresh 异步刷新 e 的权限码，失败时保留旧数据，ctx 为不会被取消的请求 context
+//
+// 刷新期间 e 被淘汰或 Invalidate 时丢弃结果，避免写回已撤销的权限。
+func (c *CodeCache) refresh(ctx context.Context, key string, e *cacheEntry) {
+	ctx, cancel := context.WithTimeout(ctx, c.fetchTimeout)
+	defer cancel()
+
+	codes, err := c.provider.GrantedCodes(ctx, e.subject)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e.refreshing = false
+	if c.entries[key] != e {
+		return
+	}
+	if err != nil {
+		return
+	}
+	e.codes = NewCodeSet(codes)
+	e.fetchedAt = c.now()
+}
+
+// loop 后台定时刷新
+func (c *CodeCache) loop() {
+	ticker := time.NewTicker(c.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.sweep()
+		}
+	}
+}
+
+// sweep 淘汰空闲和超过最长使用时间的主体，并并发刷新即将过期的主体
+func (c *CodeCache) sweep() {
+	now := c.now()
+
+	type pending struct {
+		ctx   context.Context
+		key   string
+		entry *cacheEntry
+	}
+	var refreshes []pending
+
+	c.mu.Lock()
+	for key, e := range c.entries {
+		if now.Sub(e.lastAccess) > c.idleTimeout || now.Sub(e.fetchedAt) >= c.maxStale {
+			delete(c.entries, key)
+			continue
+		}
+		if !e.refreshing && now.Sub(e.fetchedAt) >= c.ttl-c.refreshInterval {
+			e.refreshing = true
+			refreshes = append(refreshes, pending{ctx: e.ctx, key: key, entry: e})
+		}
+	}
+	// 没有缓存和拉取的主体不再需要 Invalidate 的次数
+	for key := range c.generations {
+		if _, ok := c.entries[key]; !ok && c.loading[key] == 0 {
+			delete(c.generations, key)
+		}
+	}
+	c.mu.Unlock()
+
+	// 单个主体刷新变慢不影响其他主体，每次刷新受 fetchTimeout 限制
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, sweepConcurrency)
+	for _, p := range refreshes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			c.refresh(p.ctx, p.key, p.entry)
+		}()
+	}
+	wg.Wait()
+}
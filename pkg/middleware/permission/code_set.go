@@ -0,0 +1,73 @@
+package permission
+
+import (
+	"strings"
+
+	"github.com/gobwas/glob"
+)
+
+// CodeSet 已编译的权限码集合，支持通配符
+//
+// 不含通配符的权限码使用精确匹配；含有 `*`、`?`、`[`、`{` 的权限码按 glob 规则匹配，
+// 例如 `order:*` 匹配 `order:create`、`order:item:delete`，`*` 匹配所有权限码。
+type CodeSet struct {
+	exact    map[string]struct{}
+	patterns []glob.Glob
+}
+
+// NewCodeSet 编译权限码集合，无法编译的通配符权限码将被忽略
+func NewCodeSet(codes []string) *CodeSet {
+	s := &CodeSet{
+		exact: make(map[string]struct{}, len(codes)),
+	}
+
+	for _, code := range codes {
+		code = strings.TrimSpace(code)
+		if code == "" {
+			continue
+		}
+
+		if !isPattern(code) {
+			s.exact[code] = struct{}{}
+			continue
+		}
+
+		if g, err := glob.Compile(code); err == nil {
+			s.patterns = append(s.patterns, g)
+		}
+	}
+
+	return s
+}
+
+// Allows 判断是否拥有指定权限码
+func (s *CodeSet) Allows(code string) bool {
+	if s == nil {
+		return false
+	}
+
+	if _, ok := s.exact[code]; ok {
+		return true
+	}
+
+	for _, g := range s.patterns {
+		if g.Match(code) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Len 返回权限码数量
+func (s *CodeSet) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.exact) + len(s.patterns)
+}
+
+// isPattern 是否为通配符权限码
+func isPattern(code string) bool {
+	return strings.ContainsAny(code, "*?[{")
+}
@@ -0,0 +1,105 @@
+package permission
+
+import (
+	"context"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/transport"
+	businessErrors "github.com/heyinLab/common/pkg/errors"
+	"github.com/heyinLab/common/pkg/middleware/auth"
+)
+
+type Option func(*options)
+
+type options struct {
+	denyUnmapped bool
+}
+
+// WithDenyUnmapped 拒绝未登记权限码的请求，默认放行
+func WithDenyUnmapped() Option {
+	return func(o *options) {
+		o.denyUnmapped = true
+	}
+}
+
+// SubjectFromContext 从认证中间件写入的上下文中获取权限主体
+func SubjectFromContext(ctx context.Context) (Subject, bool) {
+	if auth.IsOpenAPIRequest(ctx) {
+		claims, _ := auth.FromContext(ctx)
+		s := Subject{
+			APIKeyID:    auth.GetAPIKeyID(ctx),
+			ProductCode: auth.GetProductCode(ctx),
+		}
+		if claims != nil {
+			s.TenantID = claims.TenantID
+		}
+		return s, s.APIKeyID != 0
+	}
+
+	claims, ok := auth.FromContext(ctx)
+	if !ok || claims.UserID == 0 {
+		return Subject{}, false
+	}
+	return Subject{TenantID: claims.TenantID, UserID: claims.UserID}, true
+}
+
+// Server 权限码鉴权中间件，需放在 auth.Server 之后
+//
+// 通过 resolver 将当前请求映射为权限码，并校验是否在主体被授予的权限码中，
+// 权限码支持通配符，例如授予 order:* 即拥有 order:create、order:item:delete 等权限。
+//
+// 使用示例:
+//
+//	cache := permission.NewCodeCache(permission.ProviderFunc(func(ctx context.Context, s permission.Subject) ([]string, error) {
+//	    return roleRepo.ListGrantedCodes(ctx, s.TenantID, s.UserID, s.ProductCode)
+//	}))
+//	routes := permission.NewRouteTable().
+//	    AddOperation("/order.v1.OrderService/CreateOrder", "order:create")
+//
+//	http.Middleware(
+//	    auth.Server(true),
+//	    permission.Server(routes, cache),
+//	)
+func Server(resolver Resolver, cache *CodeCache, opts ...Option) middleware.Middleware {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromServerContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+
+			code, ok := resolver.Resolve(ctx, tr)
+			if !ok {
+				if o.denyUnmapped {
+					return nil, newError(businessErrors.ErrPermissionDenied, "接口未配置权限")
+				}
+				return handler(ctx, req)
+			}
+
+			subject, ok := SubjectFromContext(ctx)
+			if !ok {
+				return nil, newError(businessErrors.ErrPermissionDenied, "缺少认证信息")
+			}
+
+			codes, err := cache.Get(ctx, subject)
+			if err != nil {
+				return nil, newError(businessErrors.ErrAuthServiceError, "获取权限失败")
+			}
+			if !codes.Allows(code) {
+				return nil, newError(businessErrors.ErrPermissionDenied, "缺少权限: "+code)
+			}
+
+			return handler(ctx, req)
+		}
+	}
+}
+
+func newError(e *businessErrors.BusinessError, message string) *errors.Error {
+	return errors.New(int(e.HttpCode), e.Type, message)
+}
@@ -0,0 +1,320 @@
+package permission
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/transport"
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/heyinLab/common/api/gen/go/platform/v1"
+	"github.com/heyinLab/common/pkg/middleware/auth"
+	"github.com/heyinLab/common/pkg/middleware/common"
+)
+
+type headerCarrier http.Header
+
+func (hc headerCarrier) Get(key string) string { return http.Header(hc).Get(key) }
+
+func (hc headerCarrier) Set(key string, value string) { http.Header(hc).Set(key, value) }
+
+func (hc headerCarrier) Add(key string, value string) { http.Header(hc).Add(key, value) }
+
+func (hc headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(hc))
+	for k := range http.Header(hc) {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (hc headerCarrier) Values(key string) []string { return http.Header(hc).Values(key) }
+
+type testTransport struct {
+	operation    string
+	pathTemplate string
+	request      *http.Request
+}
+
+func (tr *testTransport) Kind() transport.Kind            { return transport.KindHTTP }
+func (tr *testTransport) Endpoint() string                { return "" }
+func (tr *testTransport) Operation() string               { return tr.operation }
+func (tr *testTransport) RequestHeader() transport.Header { return headerCarrier{} }
+func (tr *testTransport) ReplyHeader() transport.Header   { return headerCarrier{} }
+func (tr *testTransport) Request() *http.Request          { return tr.request }
+func (tr *testTransport) PathTemplate() string            { return tr.pathTemplate }
+
+// newTestContext 模拟 auth.Server 已认证用户 1 的请求
+func newTestContext(tr *testTransport) context.Context {
+	ctx := transport.NewServerContext(context.Background(), tr)
+	ctx = auth.NewContext(ctx, &auth.Claims{UserID: 1, TenantID: 2})
+	return context.WithValue(ctx, common.KeyAuthType, common.AuthTypeToken)
+}
+
+func TestCodeSet(t *testing.T) {
+	set := NewCodeSet([]string{"order:create", "order:item:*", "user:*", ""})
+
+	assert.Equal(t, 3, set.Len())
+	assert.True(t, set.Allows("order:create"))
+	assert.True(t, set.Allows("order:item:delete"))
+	assert.True(t, set.Allows("user:role:update"))
+	assert.False(t, set.Allows("order:delete"))
+	assert.False(t, set.Allows("users:list"))
+
+	var empty *CodeSet
+	assert.False(t, empty.Allows("order:create"))
+}
+
+func TestRouteTable(t *testing.T) {
+	code := "order:list"
+	path := "/v1/orders"
+	apiType := "api"
+	table := NewRouteTableFromTree([]*v1.TenantPermissionTreeNode{{
+		Children: []*v1.TenantPermissionTreeNode{{Code: &code, Path: &path, Type: &apiType}},
+	}}).
+		AddOperation("/order.v1.OrderService/CreateOrder", "order:create").
+		AddRoute(http.MethodDelete, "/v1/orders/{id}", "order:delete")
+
+	resolve := func(tr *testTransport) string {
+		c, _ := table.Resolve(context.Background(), tr)
+		return c
+	}
+
+	assert.Equal(t, "order:create", resolve(&testTransport{operation: "/order.v1.OrderService/CreateOrder"}))
+	assert.Equal(t, "order:delete", resolve(&testTransport{
+		pathTemplate: "/v1/orders/{id}",
+		request:      &http.Request{Method: http.MethodDelete},
+	}))
+	assert.Equal(t, "order:list", resolve(&testTransport{
+		pathTemplate: "/v1/orders",
+		request:      &http.Request{Method: http.MethodGet},
+	}))
+
+	_, ok := table.Resolve(context.Background(), &testTransport{operation: "/order.v1.OrderService/Unknown"})
+	assert.False(t, ok)
+}
+
+func TestCodeCache(t *testing.T) {
+	var calls atomic.Int32
+	var codes atomic.Pointer[[]string]
+	codes.Store(&[]string{"order:create"})
+	provider := ProviderFunc(func(_ context.Context, _ Subject) ([]string, error) {
+		calls.Add(1)
+		return *codes.Load(), nil
+	})
+
+	var now atomic.Int64
+	now.Store(1700000000)
+	cache := NewCodeCache(provider, WithTTL(time.Minute), WithRefreshInterval(time.Hour))
+	defer cache.Close()
+	cache.now = func() time.Time { return time.Unix(now.Load(), 0) }
+
+	subject := Subject{TenantID: 2, UserID: 1}
+	set, err := cache.Get(context.Background(), subject)
+	assert.NoError(t, err)
+	assert.True(t, set.Allows("order:create"))
+
+	// TTL 内命中缓存
+	_, _ = cache.Get(context.Background(), subject)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// 过期后返回旧数据并异步刷新
+	codes.Store(&[]string{"order:*"})
+	now.Add(120)
+	set, _ = cache.Get(context.Background(), subject)
+	assert.False(t, set.Allows("order:delete"))
+	assert.Eventually(t, func() bool {
+		set, _ := cache.Get(context.Background(), subject)
+		return set.Allows("order:delete")
+	}, time.Second, 10*time.Millisecond)
+
+	// 空闲淘汰
+	now.Add(3600)
+	cache.sweep()
+	cache.mu.Lock()
+	assert.Empty(t, cache.entries)
+	cache.mu.Unlock()
+}
+
+type testCtxKey struct{}
+
+func TestCodeCacheMaxStale(t *testing.T) {
+	var failing atomic.Bool
+	refreshed := make(chan any, 1)
+	provider := ProviderFunc(func(ctx context.Context, _ Subject) ([]string, error) {
+		if failing.Load() {
+			refreshed <- ctx.Value(testCtxKey{})
+			return nil, errors.ServiceUnavailable("IAM_UNAVAILABLE", "iam unavailable")
+		}
+		return []string{"order:create"}, nil
+	})
+
+	var now atomic.Int64
+	now.Store(1700000000)
+	cache := NewCodeCache(provider, WithTTL(time.Minute), WithMaxStale(3*time.Minute), WithRefreshInterval(time.Hour))
+	defer cache.Close()
+	cache.now = func() time.Time { return time.Unix(now.Load(), 0) }
+
+	subject := Subject{TenantID: 2, UserID: 1}
+	_, err := cache.Get(context.Background(), subject)
+	assert.NoError(t, err)
+
+	// 刷新失败时在最长使用时间内返回旧数据，刷新使用请求 context 中的值
+	failing.Store(true)
+	now.Add(120)
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), testCtxKey{}, "trace"))
+	cancel()
+	set, err := cache.Get(ctx, subject)
+	assert.NoError(t, err)
+	assert.True(t, set.Allows("order:create"))
+	assert.Equal(t, "trace", <-refreshed)
+
+	// 超过最长使用时间后淘汰，拉取失败时拒绝
+	now.Add(120)
+	_, err = cache.Get(context.Background(), subject)
+	assert.Error(t, err)
+	<-refreshed
+}
+
+func TestCodeCacheInvalidate(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var blocking atomic.Bool
+	var released atomic.Int32
+	var codes atomic.Pointer[[]string]
+	codes.Store(&[]string{"order:create"})
+	provider := ProviderFunc(func(_ context.Context, _ Subject) ([]string, error) {
+		result := *codes.Load()
+		if blocking.Load() {
+			started <- struct{}{}
+			<-release
+			released.Add(1)
+		}
+		return result, nil
+	})
+
+	var now atomic.Int64
+	now.Store(1700000000)
+	cache := NewCodeCache(provider, WithTTL(time.Minute), WithRefreshInterval(time.Hour))
+	defer cache.Close()
+	cache.now = func() time.Time { return time.Unix(now.Load(), 0) }
+	subject := Subject{TenantID: 2, UserID: 1}
+
+	// 拉取期间撤销权限，拉取结果不写入缓存
+	blocking.Store(true)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = cache.Get(context.Background(), subject)
+	}()
+	<-started
+	codes.Store(&[]string{})
+	cache.Invalidate(subject)
+	close(release)
+	<-done
+	cache.mu.Lock()
+	assert.Empty(t, cache.entries)
+	cache.mu.Unlock()
+
+	blocking.Store(false)
+	set, err := cache.Get(context.Background(), subject)
+	assert.NoError(t, err)
+	assert.False(t, set.Allows("order:create"))
+
+	// 刷新期间撤销权限，刷新结果不写入缓存
+	codes.Store(&[]string{"order:create"})
+	blocking.Store(true)
+	release = make(chan struct{})
+	now.Add(120)
+	_, _ = cache.Get(context.Background(), subject)
+	<-started
+	codes.Store(&[]string{})
+	cache.Invalidate(subject)
+	blocking.Store(false)
+	set, err = cache.Get(context.Background(), subject)
+	assert.NoError(t, err)
+	assert.False(t, set.Allows("order:create"))
+	close(release)
+	assert.Eventually(t, func() bool { return released.Load() == 2 }, time.Second, 10*time.Millisecond)
+	assert.Never(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return cache.entries[subject.Key()].codes.Allows("order:create")
+	}, 100*time.Millisecond, 10*time.Millisecond)
+
+	// 没有缓存后清理 Invalidate 的次数
+	cache.Invalidate(subject)
+	cache.sweep()
+	cache.mu.Lock()
+	assert.Empty(t, cache.generations)
+	cache.mu.Unlock()
+}
+
+func TestCodeCacheSweepConcurrent(t *testing.T) {
+	slow := Subject{TenantID: 2, UserID: 1}
+	fast := Subject{TenantID: 2, UserID: 2}
+	release := make(chan struct{})
+	var refreshing atomic.Bool
+	var fastCalls atomic.Int32
+	provider := ProviderFunc(func(_ context.Context, s Subject) ([]string, error) {
+		if s == fast {
+			fastCalls.Add(1)
+		} else if refreshing.Load() {
+			<-release
+		}
+		return []string{"order:create"}, nil
+	})
+
+	var now atomic.Int64
+	now.Store(1700000000)
+	cache := NewCodeCache(provider, WithTTL(time.Minute), WithRefreshInterval(time.Hour))
+	defer cache.Close()
+	cache.now = func() time.Time { return time.Unix(now.Load(), 0) }
+
+	_, _ = cache.Get(context.Background(), slow)
+	_, _ = cache.Get(context.Background(), fast)
+
+	// 一个主体的刷新阻塞时，其他主体照常刷新
+	refreshing.Store(true)
+	now.Add(90)
+	swept := make(chan struct{})
+	go func() {
+		defer close(swept)
+		cache.sweep()
+	}()
+	assert.Eventually(t, func() bool { return fastCalls.Load() == 2 }, time.Second, 10*time.Millisecond)
+	close(release)
+	<-swept
+}
+
+func TestServer(t *testing.T) {
+	cache := NewCodeCache(ProviderFunc(func(_ context.Context, s Subject) ([]string, error) {
+		if s.UserID == 1 {
+			return []string{"order:*"}, nil
+		}
+		return nil, nil
+	}))
+	defer cache.Close()
+
+	table := NewRouteTable().
+		AddOperation("/order.v1.OrderService/CreateOrder", "order:create").
+		AddOperation("/user.v1.UserService/DeleteUser", "user:delete")
+
+	run := func(operation string, opts ...Option) error {
+		_, err := Server(table, cache, opts...)(func(context.Context, interface{}) (interface{}, error) {
+			return nil, nil
+		})(newTestContext(&testTransport{operation: operation}), nil)
+		return err
+	}
+
+	assert.NoError(t, run("/order.v1.OrderService/CreateOrder"))
+	assert.Equal(t, "PERMISSION_DENIED", errors.Reason(run("/user.v1.UserService/DeleteUser")))
+
+	// 未登记的接口默认放行
+	assert.NoError(t, run("/health.v1.Health/Check"))
+	assert.Equal(t, "PERMISSION_DENIED", errors.Reason(run("/health.v1.Health/Check", WithDenyUnmapped())))
+}
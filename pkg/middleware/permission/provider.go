@@ -0,0 +1,39 @@
+package permission
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// Subject 权限校验的主体
+type Subject struct {
+	TenantID    uint32
+	UserID      uint32
+	APIKeyID    uint64
+	ProductCode string
+}
+
+// Key 返回主体的缓存键
+func (s Subject) Key() string {
+	if s.APIKeyID != 0 {
+		return "k:" + strconv.FormatUint(s.APIKeyID, 10) + ":" + s.ProductCode
+	}
+	return fmt.Sprintf("u:%d:%d:%s", s.TenantID, s.UserID, s.ProductCode)
+}
+
+// Provider 获取主体被授予的权限码（可包含通配符）
+//
+// 必须按主体（租户、用户或 API Key）返回实际授予的权限码，不能返回产品的全部权限码，
+// 否则所有请求都会通过校验。平台 IAM 服务目前只提供按产品查询全部权限码的接口，
+// 需要由业务服务根据自身的角色授权数据实现。
+type Provider interface {
+	GrantedCodes(ctx context.Context, subject Subject) ([]string, error)
+}
+
+// ProviderFunc 函数形式的 Provider
+type ProviderFunc func(ctx context.Context, subject Subject) ([]string, error)
+
+func (f ProviderFunc) GrantedCodes(ctx context.Context, subject Subject) ([]string, error) {
+	return f(ctx, subject)
+}
@@ -0,0 +1,104 @@
+package permission
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-kratos/kratos/v2/transport"
+	kratosHttp "github.com/go-kratos/kratos/v2/transport/http"
+
+	v1 "github.com/heyinLab/common/api/gen/go/platform/v1"
+)
+
+// Resolver 将当前请求映射为权限码
+type Resolver interface {
+	// Resolve 返回当前请求所需的权限码，ok 为 false 表示该请求未登记权限码
+	Resolve(ctx context.Context, tr transport.Transporter) (code string, ok bool)
+}
+
+// anyMethod 匹配任意 HTTP 方法
+const anyMethod = "*"
+
+// RouteTable 基于 Operation 和 HTTP 方法+路径模板的权限码映射表
+//
+// 优先匹配 Kratos Operation（如 /order.v1.OrderService/CreateOrder），
+// 其次匹配 HTTP 方法+路径模板（如 POST /v1/orders/{id}）。
+type RouteTable struct {
+	operations map[string]string
+	routes     map[string]string
+}
+
+// NewRouteTable 创建空的权限码映射表
+func NewRouteTable() *RouteTable {
+	return &RouteTable{
+		operations: make(map[string]string),
+		routes:     make(map[string]string),
+	}
+}
+
+// NewRouteTableFromTree 根据平台权限树中 type 为 api 的节点构建映射表
+//
+// 节点的 path 作为路径模板，适用于任意 HTTP 方法。
+func NewRouteTableFromTree(nodes []*v1.TenantPermissionTreeNode) *RouteTable {
+	t := NewRouteTable()
+	t.AddTree(nodes)
+	return t
+}
+
+// AddOperation 登记 Kratos Operation 对应的权限码
+func (t *RouteTable) AddOperation(operation, code string) *RouteTable {
+	t.operations[operation] = code
+	return t
+}
+
+// AddRoute 登记 HTTP 方法+路径模板对应的权限码，method 为空或 "*" 时匹配任意方法
+func (t *RouteTable) AddRoute(method, path, code string) *RouteTable {
+	t.routes[routeKey(method, path)] = code
+	return t
+}
+
+// AddTree 登记平台权限树中 type 为 api 的节点
+func (t *RouteTable) AddTree(nodes []*v1.TenantPermissionTreeNode) *RouteTable {
+	for _, node := range nodes {
+		if node == nil {
+			continue
+		}
+		if node.GetType() == "api" && node.GetCode() != "" && node.GetPath() != "" {
+			t.AddRoute(anyMethod, node.GetPath(), node.GetCode())
+		}
+		t.AddTree(node.GetChildren())
+	}
+	return t
+}
+
+func (t *RouteTable) Resolve(_ context.Context, tr transport.Transporter) (string, bool) {
+	if code, ok := t.operations[tr.Operation()]; ok {
+		return code, true
+	}
+
+	ht, ok := tr.(kratosHttp.Transporter)
+	if !ok || ht.Request() == nil {
+		return "", false
+	}
+
+	path := ht.PathTemplate()
+	if path == "" {
+		path = ht.Request().URL.Path
+	}
+
+	if code, ok := t.routes[routeKey(ht.Request().Method, path)]; ok {
+		return code, true
+	}
+	if code, ok := t.routes[routeKey(anyMethod, path)]; ok {
+		return code, true
+	}
+
+	return "", false
+}
+
+func routeKey(method, path string) string {
+	if method == "" {
+		method = anyMethod
+	}
+	return strings.ToUpper(method) + " " + path
+}
@@ -0,0 +1,48 @@
+package annotation
+
+// FieldType 过滤值的目标类型
+type FieldType int
+
+const (
+	FieldTypeString    FieldType = iota // 字符串，不做转换
+	FieldTypeInt                        // 有符号整数
+	FieldTypeUint                       // 无符号整数
+	FieldTypeFloat                      // 浮点数
+	FieldTypeBool                       // 布尔值
+	FieldTypeTime                       // 时间
+	FieldTypeUnixMilli                  // 毫秒时间戳，可传入日期字符串
+	FieldTypeEnum                       // 枚举，值必须在登记的枚举值之中
+	FieldTypeArray                      // PostgreSQL 原生数组，集合操作符按数组生成查询
+)
+
+var fieldTypeNames = [...]string{
+	FieldTypeString:    "string",
+	FieldTypeInt:       "int",
+	FieldTypeUint:      "uint",
+	FieldTypeFloat:     "float",
+	FieldTypeBool:      "bool",
+	FieldTypeTime:      "time",
+	FieldTypeUnixMilli: "unix_milli",
+	FieldTypeEnum:      "enum",
+	FieldTypeArray:     "array",
+}
+
+func (t FieldType) String() string {
+	if t >= 0 && int(t) < len(fieldTypeNames) {
+		return fieldTypeNames[t]
+	}
+	return "unknown"
+}
+
+// FieldTypeAnnotation 在 ent schema 中声明字段的过滤值类型，优先于根据字段类型推断的结果
+//
+// 本包不依赖查询包，schema 和 mixin 只需引入本包，例如毫秒时间戳字段:
+//
+//	field.Int64("create_time").Annotations(annotation.FieldTypeAnnotation{Type: annotation.FieldTypeUnixMilli})
+type FieldTypeAnnotation struct {
+	Type FieldType
+}
+
+func (FieldTypeAnnotation) Name() string {
+	return "QueryFieldType"
+}
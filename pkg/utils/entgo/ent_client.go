@@ -22,15 +22,34 @@ type EntClientInterface interface {
 }
 
 type EntClient[T EntClientInterface] struct {
-	db  T
-	drv *entSql.Driver
+	db        T
+	drv       *entSql.Driver
+	newClient func(drv dialect.Driver) T
 }
 
-func NewEntClient[T EntClientInterface](db T, drv *entSql.Driver) *EntClient[T] {
-	return &EntClient[T]{
+// EntClientOption EntClient 选项
+type EntClientOption[T EntClientInterface] func(*EntClient[T])
+
+// WithClientFactory 设置使用指定 Driver 创建 Client 的函数，ClientContext 用它创建加入事务的 Client
+//
+//	entgo.NewEntClient(client, drv, entgo.WithClientFactory(func(drv dialect.Driver) *ent.Client {
+//	    return ent.NewClient(ent.Driver(drv))
+//	}))
+func WithClientFactory[T EntClientInterface](newClient func(drv dialect.Driver) T) EntClientOption[T] {
+	return func(c *EntClient[T]) {
+		c.newClient = newClient
+	}
+}
+
+func NewEntClient[T EntClientInterface](db T, drv *entSql.Driver, opts ...EntClientOption[T]) *EntClient[T] {
+	c := &EntClient[T]{
 		db:  db,
 		drv: drv,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 func (c *EntClient[T]) Client() T {
@@ -50,13 +69,14 @@ func (c *EntClient[T]) Close() error {
 	return c.db.Close()
 }
 
-// Query 查询数据
+// Query 查询数据，ctx 中有事务时在事务中执行
 func (c *EntClient[T]) Query(ctx context.Context, query string, args, v any) error {
-	return c.Driver().Query(ctx, query, args, v)
+	return c.DriverContext(ctx).Query(ctx, query, args, v)
 }
 
+// Exec 执行语句，ctx 中有事务时在事务中执行
 func (c *EntClient[T]) Exec(ctx context.Context, query string, args, v any) error {
-	return c.Driver().Exec(ctx, query, args, v)
+	return c.DriverContext(ctx).Exec(ctx, query, args, v)
 }
 
 // SetConnectionOption 设置连接配置
@@ -135,69 +155,20 @@ func Rollback[T Rollbacker](tx T, err error) error {
 	return err
 }
 
-// QueryAllChildrenIds 使用CTE递归查询所有子节点ID
+// QueryAllChildrenIds 使用CTE递归查询 parent_id 为 parentID 的所有子孙节点ID
+//
+// 使用 id、parent_id 字段，不要求父节点存在（如根节点的父节点 ID 为 0），不过滤软删除的节点。
+// 其他字段名、软删除和层级限制见 Tree。
 func QueryAllChildrenIds[T EntClientInterface](ctx context.Context, entClient *EntClient[T], tableName string, parentID uint32) ([]uint32, error) {
-	var query string
-	switch entClient.Driver().Dialect() {
-	case dialect.MySQL:
-		query = fmt.Sprintf(`
-			WITH RECURSIVE all_descendants AS (
-				SELECT 
-					id,
-					parent_id,
-					name,
-					1 AS depth
-				FROM %s
-				WHERE parent_id = ?
-				
-				UNION ALL
-				
-				SELECT 
-					p.id,
-					p.parent_id,
-					p.name,
-					ad.depth + 1 AS depth
-				FROM %s p
-					INNER JOIN all_descendants ad
-				ON p.parent_id = ad.id
-			)
-			SELECT id FROM all_descendants;
-		`, tableName, tableName)
-
-	case dialect.Postgres:
-		query = fmt.Sprintf(`
-        WITH RECURSIVE all_descendants AS (
-            SELECT *
-			FROM %s
-			WHERE parent_id = $1
-            UNION ALL
-            SELECT p.*
-			FROM %s p
-            	INNER JOIN all_descendants ad
-			ON p.parent_id = ad.id
-        )
-        SELECT id FROM all_descendants;
-    `, tableName, tableName)
-	}
-
-	rows := &sql.Rows{}
-	if err := entClient.Query(ctx, query, []any{parentID}, rows); err != nil {
+	nodes, err := NewTree[uint32](entClient.DriverContext(ctx), tableName, WithTreeNameColumn("")).descendantsOfParent(ctx, parentID)
+	if err != nil {
 		log.Errorf("query child nodes failed: %s", err.Error())
-		return nil, errors.New("query child nodes failed: " + err.Error())
+		return nil, fmt.Errorf("query child nodes failed: %w", err)
 	}
-	defer rows.Close()
-
-	childIDs := make([]uint32, 0)
-	for rows.Next() {
-		var id uint32
 
-		if err := rows.Scan(&id); err != nil {
-			log.Errorf("scan child node failed: %s", err.Error())
-			return nil, errors.New("scan child node failed")
-		}
-
-		childIDs = append(childIDs, id)
+	childIDs := make([]uint32, 0, len(nodes))
+	for _, node := range nodes {
+		childIDs = append(childIDs, node.ID)
 	}
-
 	return childIDs, nil
 }
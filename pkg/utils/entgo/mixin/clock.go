@@ -0,0 +1,69 @@
+package mixin
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"entgo.io/ent"
+)
+
+// Clock 时间来源
+type Clock func() time.Time
+
+var clock atomic.Pointer[Clock]
+
+// SetClock 设置时间类 Mixin 使用的时间来源，返回恢复原时间来源的函数，主要用于测试
+//
+// 使用示例:
+//
+//	restore := mixin.SetClock(func() time.Time { return fixed })
+//	defer restore()
+func SetClock(c Clock) (restore func()) {
+	prev := clock.Load()
+	clock.Store(&c)
+	return func() {
+		clock.Store(prev)
+	}
+}
+
+// now 返回当前时间
+func now() time.Time {
+	if c := clock.Load(); c != nil {
+		return (*c)()
+	}
+	return time.Now()
+}
+
+// nowMilli 返回当前毫秒时间戳
+func nowMilli() int64 {
+	return now().UnixMilli()
+}
+
+func timeValue(t time.Time) ent.Value  { return t }
+func milliValue(t time.Time) ent.Value { return t.UnixMilli() }
+
+// createTimeHook 创建时填充 field，调用方已显式设置时保持不变
+func createTimeHook(field string, value func(time.Time) ent.Value) ent.Hook {
+	return timeHook(ent.OpCreate, field, value)
+}
+
+// updateTimeHook 更新（包括批量更新）时填充 field，调用方已显式设置时保持不变
+func updateTimeHook(field string, value func(time.Time) ent.Value) ent.Hook {
+	return timeHook(ent.OpUpdate|ent.OpUpdateOne, field, value)
+}
+
+func timeHook(op ent.Op, field string, value func(time.Time) ent.Value) ent.Hook {
+	return func(next ent.Mutator) ent.Mutator {
+		return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+			if m.Op().Is(op) {
+				if _, ok := m.Field(field); !ok {
+					if err := m.SetField(field, value(now())); err != nil {
+						return nil, err
+					}
+				}
+			}
+			return next.Mutate(ctx, m)
+		})
+	}
+}
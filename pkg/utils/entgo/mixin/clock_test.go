@@ -0,0 +1,45 @@
+package mixin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"entgo.io/ent"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimestampHooks(t *testing.T) {
+	fixed := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	restore := SetClock(func() time.Time { return fixed })
+	defer restore()
+
+	hooks := TimestampAt{}.Hooks()
+
+	m := newFakeMutation(ent.OpCreate)
+	assert.NoError(t, runHooks(context.Background(), hooks, m))
+	assert.Equal(t, map[string]ent.Value{"created_at": fixed.UnixMilli()}, m.fields)
+
+	// 批量更新同样填充
+	for _, op := range []ent.Op{ent.OpUpdate, ent.OpUpdateOne} {
+		m = newFakeMutation(op)
+		assert.NoError(t, runHooks(context.Background(), hooks, m))
+		assert.Equal(t, map[string]ent.Value{"updated_at": fixed.UnixMilli()}, m.fields)
+	}
+
+	// 显式设置的值保持不变
+	m = newFakeMutation(ent.OpCreate)
+	m.fields["created_at"] = int64(1)
+	assert.NoError(t, runHooks(context.Background(), hooks, m))
+	assert.Equal(t, int64(1), m.fields["created_at"])
+
+	// time.Time 字段
+	m = newFakeMutation(ent.OpUpdate)
+	assert.NoError(t, runHooks(context.Background(), Time{}.Hooks(), m))
+	assert.Equal(t, map[string]ent.Value{"update_time": fixed}, m.fields)
+
+	// 默认值在每次调用时求值
+	assert.Equal(t, fixed.UnixMilli(), nowMilli())
+	restore()
+	assert.NotEqual(t, fixed.UnixMilli(), nowMilli())
+}
@@ -0,0 +1,109 @@
+package mixin
+
+import (
+	"context"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/sql"
+)
+
+// fakeQuery 模拟 ent 生成的查询构建器
+type fakeQuery struct {
+	preds []func(*sql.Selector)
+}
+
+func (q *fakeQuery) WhereP(ps ...func(*sql.Selector)) {
+	q.preds = append(q.preds, ps...)
+}
+
+// fakeMutation 模拟 ent 生成的 Mutation，仅实现钩子用到的方法
+type fakeMutation struct {
+	ent.Mutation
+
+	op      ent.Op
+	typ     string
+	fields  map[string]ent.Value
+	cleared map[string]bool
+	added   map[string]ent.Value
+	preds   []func(*sql.Selector)
+	client  *fakeClient
+}
+
+// fakeClient 模拟 ent 生成的 Client，记录通过 Client().Mutate 重新提交的 Mutation，
+// 设置 hooks 时与生成代码一样再次经过钩子
+type fakeClient struct {
+	mutations []ent.Mutation
+	hooks     []ent.Hook
+}
+
+func (c *fakeClient) Mutate(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+	c.mutations = append(c.mutations, m)
+	return 1, runHooks(ctx, c.hooks, m)
+}
+
+func newFakeMutation(op ent.Op) *fakeMutation {
+	return &fakeMutation{
+		op:      op,
+		typ:     "Order",
+		fields:  make(map[string]ent.Value),
+		cleared: make(map[string]bool),
+		added:   make(map[string]ent.Value),
+		client:  &fakeClient{},
+	}
+}
+
+func (m *fakeMutation) SetOp(op ent.Op)     { m.op = op }
+func (m *fakeMutation) Client() *fakeClient { return m.client }
+
+func (m *fakeMutation) Op() ent.Op   { return m.op }
+func (m *fakeMutation) Type() string { return m.typ }
+
+func (m *fakeMutation) Field(name string) (ent.Value, bool) {
+	v, ok := m.fields[name]
+	return v, ok
+}
+
+func (m *fakeMutation) SetField(name string, value ent.Value) error {
+	m.fields[name] = value
+	return nil
+}
+
+func (m *fakeMutation) AddField(name string, value ent.Value) error {
+	m.added[name] = value
+	return nil
+}
+
+func (m *fakeMutation) ClearField(name string) error {
+	m.cleared[name] = true
+	return nil
+}
+
+func (m *fakeMutation) WhereP(ps ...func(*sql.Selector)) {
+	m.preds = append(m.preds, ps...)
+}
+
+// whereSQL 将条件渲染为 SQL，便于断言
+func whereSQL(preds []func(*sql.Selector)) (string, []any) {
+	s := sql.Select("*").From(sql.Table("orders"))
+	for _, p := range preds {
+		p(s)
+	}
+	return s.Query()
+}
+
+// runHook 执行钩子，返回下游是否被调用
+func runHook(ctx context.Context, hook ent.Hook, m ent.Mutation) (bool, error) {
+	called := false
+	_, err := runHookWith(ctx, hook, m, func() (ent.Value, error) {
+		called = true
+		return nil, nil
+	})
+	return called, err
+}
+
+// runHookWith 执行钩子，下游返回 result 的结果
+func runHookWith(ctx context.Context, hook ent.Hook, m ent.Mutation, result func() (ent.Value, error)) (ent.Value, error) {
+	return hook(ent.MutateFunc(func(context.Context, ent.Mutation) (ent.Value, error) {
+		return result()
+	})).Mutate(ctx, m)
+}
@@ -1,11 +1,23 @@
 package mixin
 
 import (
+	"context"
+	"fmt"
+	"math"
+
 	"entgo.io/ent"
 	"entgo.io/ent/schema/field"
 	"entgo.io/ent/schema/mixin"
+
+	"github.com/heyinLab/common/pkg/middleware/auth"
 )
 
+// 操作者字段会在写入时根据 auth.GetOperator 自动填充：
+// 创建时填充 create_by/created_by，更新时填充 update_by/updated_by，软删除时填充 delete_by/deleted_by，
+// 物理删除时不填充。
+// 操作者为用户时填充用户ID，为 API Key 时填充 API Key ID；需要区分两者时同时使用 OperatorType。
+// context 中没有操作者（如后台任务）时不填充。
+
 var _ ent.Mixin = (*CreateBy)(nil)
 
 type CreateBy struct{ mixin.Schema }
@@ -19,6 +31,10 @@ func (CreateBy) Fields() []ent.Field {
 	}
 }
 
+func (CreateBy) Hooks() []ent.Hook {
+	return []ent.Hook{operatorHook(ent.OpCreate, "create_by")}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*UpdateBy)(nil)
@@ -34,6 +50,10 @@ func (UpdateBy) Fields() []ent.Field {
 	}
 }
 
+func (UpdateBy) Hooks() []ent.Hook {
+	return []ent.Hook{operatorHook(ent.OpUpdate|ent.OpUpdateOne, "update_by")}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*DeleteBy)(nil)
@@ -49,6 +69,10 @@ func (DeleteBy) Fields() []ent.Field {
 	}
 }
 
+func (DeleteBy) Hooks() []ent.Hook {
+	return []ent.Hook{deleteOperatorHook("delete_by")}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*CreatedBy)(nil)
@@ -64,6 +88,10 @@ func (CreatedBy) Fields() []ent.Field {
 	}
 }
 
+func (CreatedBy) Hooks() []ent.Hook {
+	return []ent.Hook{operatorHook(ent.OpCreate, "created_by")}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*UpdatedBy)(nil)
@@ -79,6 +107,10 @@ func (UpdatedBy) Fields() []ent.Field {
 	}
 }
 
+func (UpdatedBy) Hooks() []ent.Hook {
+	return []ent.Hook{operatorHook(ent.OpUpdate|ent.OpUpdateOne, "updated_by")}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*DeletedBy)(nil)
@@ -94,6 +126,10 @@ func (DeletedBy) Fields() []ent.Field {
 	}
 }
 
+func (DeletedBy) Hooks() []ent.Hook {
+	return []ent.Hook{deleteOperatorHook("deleted_by")}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*OperatorID)(nil)
@@ -107,3 +143,101 @@ func (OperatorID) Fields() []ent.Field {
 	fields = append(fields, DeletedBy{}.Fields()...)
 	return fields
 }
+
+func (OperatorID) Hooks() []ent.Hook {
+	var hooks []ent.Hook
+	hooks = append(hooks, CreatedBy{}.Hooks()...)
+	hooks = append(hooks, UpdatedBy{}.Hooks()...)
+	hooks = append(hooks, DeletedBy{}.Hooks()...)
+	return hooks
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+const FieldOperatorType = "operator_type"
+
+var _ ent.Mixin = (*OperatorType)(nil)
+
+// OperatorType 最后一次写入的操作者类型（user/api_key），与操作者字段配合区分用户和 API Key
+type OperatorType struct{ mixin.Schema }
+
+func (OperatorType) Fields() []ent.Field {
+	return []ent.Field{
+		field.String(FieldOperatorType).
+			Comment("操作者类型").
+			MaxLen(16).
+			Optional().
+			Nillable(),
+	}
+}
+
+func (OperatorType) Hooks() []ent.Hook {
+	return []ent.Hook{
+		func(next ent.Mutator) ent.Mutator {
+			return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+				// 物理删除不写入，软删除改写为更新后写入
+				if m.Op().Is(ent.OpDelete | ent.OpDeleteOne) {
+					return next.Mutate(ctx, m)
+				}
+				if operator, ok := operatorFromContext(ctx); ok {
+					if err := m.SetField(FieldOperatorType, operator.Type); err != nil {
+						return nil, err
+					}
+				}
+				return next.Mutate(ctx, m)
+			})
+		},
+	}
+}
+
+// operatorHook 在 op 操作时将操作者ID写入 field
+func operatorHook(op ent.Op, field string) ent.Hook {
+	return func(next ent.Mutator) ent.Mutator {
+		return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+			if m.Op().Is(op) {
+				if err := setOperatorField(ctx, m, field); err != nil {
+					return nil, err
+				}
+			}
+			return next.Mutate(ctx, m)
+		})
+	}
+}
+
+// deleteOperatorHook 删除被软删除改写为更新时将操作者ID写入 field，物理删除时不写入
+func deleteOperatorHook(field string) ent.Hook {
+	return func(next ent.Mutator) ent.Mutator {
+		return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+			if isSoftDeleting(ctx, m) {
+				if err := setOperatorField(ctx, m, field); err != nil {
+					return nil, err
+				}
+			}
+			return next.Mutate(ctx, m)
+		})
+	}
+}
+
+// setOperatorField 将当前操作者ID写入 field，没有操作者或已显式设置时不写入
+func setOperatorField(ctx context.Context, m ent.Mutation, field string) error {
+	if _, ok := m.Field(field); ok {
+		return nil
+	}
+	operator, ok := operatorFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if operator.ID > math.MaxUint32 {
+		return fmt.Errorf("ent: operator %s:%d overflows field %s", operator.Type, operator.ID, field)
+	}
+	return m.SetField(field, uint32(operator.ID))
+}
+
+// operatorFromContext 获取当前操作者
+func operatorFromContext(ctx context.Context) (auth.Operator, bool) {
+	operator := auth.GetOperator(ctx)
+	if operator.ID == 0 {
+		return operator, false
+	}
+	return operator, true
+}
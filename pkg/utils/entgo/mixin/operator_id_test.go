@@ -0,0 +1,92 @@
+package mixin
+
+import (
+	"context"
+	"testing"
+
+	"entgo.io/ent"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/heyinLab/common/pkg/middleware/auth"
+	"github.com/heyinLab/common/pkg/middleware/common"
+)
+
+// runHooks 依次执行 schema 上的所有钩子
+func runHooks(ctx context.Context, hooks []ent.Hook, m ent.Mutation) error {
+	var mutator ent.Mutator = ent.MutateFunc(func(context.Context, ent.Mutation) (ent.Value, error) {
+		return nil, nil
+	})
+	for i := len(hooks) - 1; i >= 0; i-- {
+		mutator = hooks[i](mutator)
+	}
+	_, err := mutator.Mutate(ctx, m)
+	return err
+}
+
+func TestOperatorHooks(t *testing.T) {
+	hooks := append(OperatorID{}.Hooks(), OperatorType{}.Hooks()...)
+	userCtx := auth.NewContext(context.Background(), &auth.Claims{UserID: 12, TenantID: 7})
+
+	m := newFakeMutation(ent.OpCreate)
+	assert.NoError(t, runHooks(userCtx, hooks, m))
+	assert.Equal(t, map[string]ent.Value{"created_by": uint32(12), FieldOperatorType: "user"}, m.fields)
+
+	m = newFakeMutation(ent.OpUpdateOne)
+	assert.NoError(t, runHooks(userCtx, hooks, m))
+	assert.Equal(t, map[string]ent.Value{"updated_by": uint32(12), FieldOperatorType: "user"}, m.fields)
+
+	// API Key 操作者
+	keyCtx := auth.NewContext(context.Background(), &auth.Claims{TenantID: 7})
+	keyCtx = context.WithValue(keyCtx, common.KeyAuthType, common.AuthTypeOpenAPI)
+	keyCtx = context.WithValue(keyCtx, common.KeyAPIKeyID, uint64(10001))
+
+	m = newFakeMutation(ent.OpUpdate)
+	assert.NoError(t, runHooks(keyCtx, hooks, m))
+	assert.Equal(t, map[string]ent.Value{"updated_by": uint32(10001), FieldOperatorType: "api_key"}, m.fields)
+
+	// 物理删除不修改 Mutation
+	for _, op := range []ent.Op{ent.OpDelete, ent.OpDeleteOne} {
+		m = newFakeMutation(op)
+		assert.NoError(t, runHooks(keyCtx, append(hooks, DeleteBy{}.Hooks()...), m))
+		assert.Empty(t, m.fields)
+		assert.Equal(t, op, m.op)
+	}
+
+	// 没有操作者
+	m = newFakeMutation(ent.OpCreate)
+	assert.NoError(t, runHooks(context.Background(), hooks, m))
+	assert.Empty(t, m.fields)
+
+	// 显式设置的值保持不变
+	m = newFakeMutation(ent.OpUpdate)
+	m.fields["updated_by"] = uint32(3)
+	assert.NoError(t, runHooks(userCtx, hooks, m))
+	assert.Equal(t, uint32(3), m.fields["updated_by"])
+
+	// 超出字段范围
+	overflowCtx := context.WithValue(keyCtx, common.KeyAPIKeyID, uint64(1)<<40)
+	assert.Error(t, runHooks(overflowCtx, CreateBy{}.Hooks(), newFakeMutation(ent.OpCreate)))
+}
+
+func TestOperatorHooksSoftDelete(t *testing.T) {
+	userCtx := auth.NewContext(context.Background(), &auth.Claims{UserID: 12, TenantID: 7})
+
+	// 软删除改写为更新后同样记录删除者，与 mixin 的顺序无关
+	for _, hooks := range [][]ent.Hook{
+		append(append(TimeAt{SoftDelete: true}.Hooks(), OperatorID{}.Hooks()...), OperatorType{}.Hooks()...),
+		append(append(OperatorID{}.Hooks(), OperatorType{}.Hooks()...), TimeAt{SoftDelete: true}.Hooks()...),
+	} {
+		m := newFakeMutation(ent.OpDeleteOne)
+		m.client.hooks = hooks
+		assert.NoError(t, runHooks(userCtx, hooks, m))
+		assert.Equal(t, ent.OpUpdate, m.op)
+		assert.Equal(t, uint32(12), m.fields["deleted_by"])
+		assert.Equal(t, "user", m.fields[FieldOperatorType])
+	}
+
+	// 普通更新不记录删除者
+	m := newFakeMutation(ent.OpUpdate)
+	ctx := context.WithValue(userCtx, softDeletingKey{}, ent.Mutation(newFakeMutation(ent.OpUpdate)))
+	assert.NoError(t, runHooks(ctx, OperatorID{}.Hooks(), m))
+	assert.NotContains(t, m.fields, "deleted_by")
+}
@@ -1,10 +1,29 @@
 package mixin
 
 import (
+	"context"
+	"fmt"
+	"reflect"
+
 	"entgo.io/ent"
+	"entgo.io/ent/dialect/sql"
 	"entgo.io/ent/schema/mixin"
 )
 
+const (
+	FieldDeletedAt = "deleted_at"
+	FieldDeletedBy = "deleted_by"
+)
+
+// SoftDelete 软删除，包含 deleted_at（时间）和 deleted_by 字段：
+//
+// - 查询时自动过滤 deleted_at 不为空的记录；
+// - 删除时改写为更新 deleted_at 和 deleted_by（取自 auth.GetOperator）。
+//
+// 管理后台查看已删除数据、恢复和物理删除时，使用 SkipSoftDelete 跳过。
+//
+// SoftDelete 始终开启软删除。DeletedAt、DeleteTime、TimeAt、Time 及对应的毫秒时间戳 mixin
+// 默认只添加字段，需设置 SoftDelete: true 开启，如 mixin.TimeAt{SoftDelete: true}。
 type SoftDelete struct {
 	mixin.Schema
 }
@@ -15,3 +34,111 @@ func (SoftDelete) Fields() []ent.Field {
 	fields = append(fields, DeletedBy{}.Fields()...)
 	return fields
 }
+
+// Interceptors 过滤已删除的记录
+func (SoftDelete) Interceptors() []ent.Interceptor {
+	return []ent.Interceptor{softDeleteInterceptor(FieldDeletedAt)}
+}
+
+// Hooks 将删除改写为更新 deleted_at 和 deleted_by
+func (SoftDelete) Hooks() []ent.Hook {
+	return []ent.Hook{softDeleteHook(FieldDeletedAt, FieldDeletedBy, func() ent.Value { return now() })}
+}
+
+type skipSoftDeleteKey struct{}
+
+// SkipSoftDelete 返回跳过软删除的 context：查询包含已删除的记录，删除为物理删除
+func SkipSoftDelete(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipSoftDeleteKey{}, true)
+}
+
+// IsSkipSoftDelete 判断是否跳过软删除
+func IsSkipSoftDelete(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipSoftDeleteKey{}).(bool)
+	return skip
+}
+
+// softDeleteInterceptor 为查询追加 field IS NULL 条件
+func softDeleteInterceptor(field string) ent.Interceptor {
+	return ent.TraverseFunc(func(ctx context.Context, q ent.Query) error {
+		if IsSkipSoftDelete(ctx) {
+			return nil
+		}
+		w, ok := q.(interface{ WhereP(...func(*sql.Selector)) })
+		if !ok {
+			return fmt.Errorf("ent: unexpected query type %T", q)
+		}
+		w.WhereP(sql.FieldIsNull(field))
+		return nil
+	})
+}
+
+// softDeleteHook 将删除改写为更新删除时间和删除者，byField 为空时不记录删除者
+func softDeleteHook(field, byField string, value func() ent.Value) ent.Hook {
+	return func(next ent.Mutator) ent.Mutator {
+		return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+			if !m.Op().Is(ent.OpDelete|ent.OpDeleteOne) || IsSkipSoftDelete(ctx) {
+				return next.Mutate(ctx, m)
+			}
+
+			mx, ok := m.(softDeleteMutation)
+			if !ok {
+				return nil, fmt.Errorf("ent: unexpected mutation type %T", m)
+			}
+			client, err := mutationClient(m)
+			if err != nil {
+				return nil, err
+			}
+
+			// 删除和按 ID 删除都改写为批量更新，返回受影响的行数，与 Delete 的返回值一致
+			mx.SetOp(ent.OpUpdate)
+			mx.WhereP(sql.FieldIsNull(field))
+			if err = m.SetField(field, value()); err != nil {
+				return nil, err
+			}
+			if byField != "" {
+				if err = setOperatorField(ctx, m, byField); err != nil {
+					return nil, err
+				}
+			}
+
+			return client.Mutate(context.WithValue(ctx, softDeletingKey{}, m), m)
+		})
+	}
+}
+
+type softDeletingKey struct{}
+
+// isSoftDeleting 判断 m 是否为软删除改写后重新提交的更新
+//
+// 软删除改写后通过 Client 重新提交，其他钩子看到的是更新操作，
+// 记录删除者等需要按删除处理的钩子据此判断，与 mixin 的顺序无关。
+func isSoftDeleting(ctx context.Context, m ent.Mutation) bool {
+	v, ok := ctx.Value(softDeletingKey{}).(ent.Mutation)
+	return ok && m.Op().Is(ent.OpUpdate) && v == m
+}
+
+type softDeleteMutation interface {
+	SetOp(ent.Op)
+	WhereP(...func(*sql.Selector))
+}
+
+// entMutator 生成代码中的 Client 实现了该接口
+type entMutator interface {
+	Mutate(context.Context, ent.Mutation) (ent.Value, error)
+}
+
+// mutationClient 获取 Mutation 所属的 ent Client
+//
+// 生成代码中 Mutation 的 Client() 返回具体的 *ent.Client 类型，因此需要通过反射调用。
+func mutationClient(m ent.Mutation) (entMutator, error) {
+	method := reflect.ValueOf(m).MethodByName("Client")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return nil, fmt.Errorf("ent: mutation %T has no Client method", m)
+	}
+	client, ok := method.Call(nil)[0].Interface().(entMutator)
+	if !ok {
+		return nil, fmt.Errorf("ent: client of mutation %T cannot mutate", m)
+	}
+	return client, nil
+}
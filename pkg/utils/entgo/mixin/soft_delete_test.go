@@ -0,0 +1,109 @@
+package mixin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"entgo.io/ent"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/heyinLab/common/pkg/middleware/auth"
+)
+
+func TestSoftDeleteInterceptor(t *testing.T) {
+	traverse := SoftDelete{}.Interceptors()[0].(ent.TraverseFunc)
+
+	q := &fakeQuery{}
+	assert.NoError(t, traverse(context.Background(), q))
+	query, _ := whereSQL(q.preds)
+	assert.Equal(t, "SELECT * FROM `orders` WHERE `orders`.`deleted_at` IS NULL", query)
+
+	q = &fakeQuery{}
+	assert.NoError(t, traverse(SkipSoftDelete(context.Background()), q))
+	assert.Empty(t, q.preds)
+}
+
+func TestSoftDeleteHook(t *testing.T) {
+	ctx := auth.NewContext(context.Background(), &auth.Claims{UserID: 12, TenantID: 7})
+
+	for _, op := range []ent.Op{ent.OpDelete, ent.OpDeleteOne} {
+		m := newFakeMutation(op)
+		called, err := runHook(ctx, SoftDelete{}.Hooks()[0], m)
+		assert.NoError(t, err)
+		assert.False(t, called)
+
+		assert.Equal(t, ent.OpUpdate, m.op)
+		assert.Len(t, m.client.mutations, 1)
+		assert.IsType(t, time.Time{}, m.fields[FieldDeletedAt])
+		assert.Equal(t, uint32(12), m.fields[FieldDeletedBy])
+		query, _ := whereSQL(m.preds)
+		assert.Equal(t, "SELECT * FROM `orders` WHERE `orders`.`deleted_at` IS NULL", query)
+	}
+
+	// 毫秒时间戳，且不记录删除者
+	m := newFakeMutation(ent.OpDeleteOne)
+	_, err := runHook(ctx, DeletedAtTimestamp{SoftDelete: true}.Hooks()[0], m)
+	assert.NoError(t, err)
+	assert.IsType(t, int64(0), m.fields[FieldDeletedAt])
+	assert.NotContains(t, m.fields, FieldDeletedBy)
+
+	// 物理删除
+	m = newFakeMutation(ent.OpDelete)
+	called, err := runHook(SkipSoftDelete(ctx), SoftDelete{}.Hooks()[0], m)
+	assert.NoError(t, err)
+	assert.True(t, called)
+	assert.Equal(t, ent.OpDelete, m.op)
+
+	// 非删除操作不受影响
+	m = newFakeMutation(ent.OpUpdate)
+	called, _ = runHook(ctx, SoftDelete{}.Hooks()[0], m)
+	assert.True(t, called)
+	assert.Empty(t, m.fields)
+}
+
+func TestSoftDeleteComposite(t *testing.T) {
+	for name, tc := range map[string]struct {
+		mixin ent.Mixin
+		field string
+	}{
+		"DeletedAt":          {DeletedAt{SoftDelete: true}, FieldDeletedAt},
+		"TimeAt":             {TimeAt{SoftDelete: true}, FieldDeletedAt},
+		"DeletedAtTimestamp": {DeletedAtTimestamp{SoftDelete: true}, FieldDeletedAt},
+		"TimestampAt":        {TimestampAt{SoftDelete: true}, FieldDeletedAt},
+		"DeleteTime":         {DeleteTime{SoftDelete: true}, "delete_time"},
+		"Time":               {Time{SoftDelete: true}, "delete_time"},
+		"DeleteTimestamp":    {DeleteTimestamp{SoftDelete: true}, "delete_time"},
+		"Timestamp":          {Timestamp{SoftDelete: true}, "delete_time"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			interceptors := tc.mixin.Interceptors()
+			assert.Len(t, interceptors, 1)
+			q := &fakeQuery{}
+			assert.NoError(t, interceptors[0].(ent.TraverseFunc)(context.Background(), q))
+			query, _ := whereSQL(q.preds)
+			assert.Equal(t, "SELECT * FROM `orders` WHERE `orders`.`"+tc.field+"` IS NULL", query)
+
+			m := newFakeMutation(ent.OpDeleteOne)
+			assert.NoError(t, runHooks(context.Background(), tc.mixin.Hooks(), m))
+			assert.Equal(t, ent.OpUpdate, m.op)
+			assert.Len(t, m.client.mutations, 1)
+			assert.Contains(t, m.fields, tc.field)
+		})
+	}
+}
+
+func TestSoftDeleteOptIn(t *testing.T) {
+	// 未开启软删除时只添加字段，删除仍为物理删除
+	for _, mx := range []ent.Mixin{
+		DeletedAt{}, TimeAt{}, DeletedAtTimestamp{}, TimestampAt{},
+		DeleteTime{}, Time{}, DeleteTimestamp{}, Timestamp{},
+	} {
+		assert.Empty(t, mx.Interceptors(), "%T", mx)
+
+		m := newFakeMutation(ent.OpDeleteOne)
+		assert.NoError(t, runHooks(context.Background(), mx.Hooks(), m), "%T", mx)
+		assert.Equal(t, ent.OpDeleteOne, m.op, "%T", mx)
+		assert.Empty(t, m.client.mutations, "%T", mx)
+	}
+}
@@ -1,20 +1,55 @@
 package mixin
 
 import (
+	"context"
+	"errors"
+	"fmt"
+
 	"entgo.io/ent"
+	"entgo.io/ent/dialect/sql"
 	"entgo.io/ent/schema/field"
 	"entgo.io/ent/schema/index"
 	"entgo.io/ent/schema/mixin"
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/heyinLab/common/pkg/middleware/auth"
 )
 
 // 确保 TenantID 实现了 ent.Mixin 接口
 var _ ent.Mixin = (*TenantID)(nil)
 
-type TenantID struct{ mixin.Schema }
+const FieldTenantID = "tenant_id"
+
+var (
+	// ErrTenantMissing context 中没有租户，且未调用 SkipTenantIsolation
+	ErrTenantMissing = errors.New("ent: tenant id is missing in context")
+	// ErrCrossTenant 尝试写入其他租户的数据
+	ErrCrossTenant = errors.New("ent: cross-tenant mutation is not allowed")
+)
+
+// TenantID 租户ID字段，Isolate 为 true 时自动实现租户隔离：
+//
+// - 查询、更新、删除自动追加 tenant_id = 当前租户 的条件；
+// - 创建时自动填充当前租户，显式指定其他租户时返回 ErrCrossTenant；
+// - 当前租户取自 auth.Claims.TenantID，缺失时返回 ErrTenantMissing。
+//
+// 平台管理任务等需要跨租户访问时，使用 SkipTenantIsolation 显式跳过，并会触发审计回调。
+//
+// 租户隔离需要显式开启，已有的 schema 开启前需确认所有调用方的 context 中都有租户：
+//
+//	func (Order) Mixin() []ent.Mixin {
+//	    return []ent.Mixin{mixin.TenantID{Isolate: true}}
+//	}
+type TenantID struct {
+	mixin.Schema
+
+	// Isolate 是否开启租户隔离，默认只添加字段
+	Isolate bool
+}
 
 func (TenantID) Fields() []ent.Field {
 	return []ent.Field{
-		field.Uint32("tenant_id").
+		field.Uint32(FieldTenantID).
 			Comment("租户ID").
 			Immutable().
 			Nillable().
@@ -25,6 +60,128 @@ func (TenantID) Fields() []ent.Field {
 // Indexes of the TenantID.
 func (TenantID) Indexes() []ent.Index {
 	return []ent.Index{
-		index.Fields("tenant_id"),
+		index.Fields(FieldTenantID),
+	}
+}
+
+// Interceptors 为所有查询追加租户条件
+func (t TenantID) Interceptors() []ent.Interceptor {
+	if !t.Isolate {
+		return nil
+	}
+	return []ent.Interceptor{
+		ent.TraverseFunc(func(ctx context.Context, q ent.Query) error {
+			typ, op := fmt.Sprintf("%T", q), "Query"
+			if qc := ent.QueryFromContext(ctx); qc != nil {
+				typ, op = qc.Type, qc.Op
+			}
+			tenantID, skip, err := tenantFromContext(ctx, typ, op)
+			if err != nil || skip {
+				return err
+			}
+			w, ok := q.(interface{ WhereP(...func(*sql.Selector)) })
+			if !ok {
+				return fmt.Errorf("ent: unexpected query type %T", q)
+			}
+			w.WhereP(sql.FieldEQ(FieldTenantID, tenantID))
+			return nil
+		}),
+	}
+}
+
+// Hooks 为创建填充租户，为更新、删除追加租户条件
+func (t TenantID) Hooks() []ent.Hook {
+	if !t.Isolate {
+		return nil
+	}
+	return []ent.Hook{
+		func(next ent.Mutator) ent.Mutator {
+			return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+				tenantID, skip, err := tenantFromContext(ctx, m.Type(), m.Op().String())
+				if err != nil {
+					return nil, err
+				}
+				if skip {
+					return next.Mutate(ctx, m)
+				}
+
+				if m.Op().Is(ent.OpCreate) {
+					if v, ok := m.Field(FieldTenantID); ok {
+						if id, _ := v.(uint32); id != tenantID {
+							return nil, ErrCrossTenant
+						}
+					} else if err = m.SetField(FieldTenantID, tenantID); err != nil {
+						return nil, err
+					}
+					return next.Mutate(ctx, m)
+				}
+
+				w, ok := m.(interface{ WhereP(...func(*sql.Selector)) })
+				if !ok {
+					return nil, fmt.Errorf("ent: unexpected mutation type %T", m)
+				}
+				w.WhereP(sql.FieldEQ(FieldTenantID, tenantID))
+				return next.Mutate(ctx, m)
+			})
+		},
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// TenantBypass 跳过租户隔离的审计信息
+type TenantBypass struct {
+	Reason   string        // 调用 SkipTenantIsolation 时给出的原因
+	Operator auth.Operator // 当前操作者
+	Type     string        // 实体类型
+	Op       string        // 操作类型，如 All、OpUpdate
+}
+
+// TenantBypassAuditor 跳过租户隔离时的审计回调
+type TenantBypassAuditor func(ctx context.Context, bypass TenantBypass)
+
+var tenantBypassAuditor TenantBypassAuditor = func(ctx context.Context, bypass TenantBypass) {
+	log.Context(ctx).Warnf("ent: tenant isolation skipped: reason=%q operator=%s:%d type=%s op=%s",
+		bypass.Reason, bypass.Operator.Type, bypass.Operator.ID, bypass.Type, bypass.Op)
+}
+
+// SetTenantBypassAuditor 设置跳过租户隔离时的审计回调，默认输出告警日志
+func SetTenantBypassAuditor(auditor TenantBypassAuditor) {
+	if auditor != nil {
+		tenantBypassAuditor = auditor
+	}
+}
+
+type skipTenantKey struct{}
+
+// SkipTenantIsolation 返回跳过租户隔离的 context，reason 为必填的审计原因
+//
+// 使用示例:
+//
+//	ctx = mixin.SkipTenantIsolation(ctx, "nightly billing job")
+//	client.Order.Query().All(ctx)
+func SkipTenantIsolation(ctx context.Context, reason string) context.Context {
+	if reason == "" {
+		panic("mixin: SkipTenantIsolation requires a reason")
+	}
+	return context.WithValue(ctx, skipTenantKey{}, reason)
+}
+
+// tenantFromContext 获取当前租户，跳过租户隔离时 skip 为 true
+func tenantFromContext(ctx context.Context, typ, op string) (tenantID uint32, skip bool, err error) {
+	if reason, ok := ctx.Value(skipTenantKey{}).(string); ok {
+		tenantBypassAuditor(ctx, TenantBypass{
+			Reason:   reason,
+			Operator: auth.GetOperator(ctx),
+			Type:     typ,
+			Op:       op,
+		})
+		return 0, true, nil
+	}
+
+	claims, ok := auth.FromContext(ctx)
+	if !ok || claims == nil || claims.TenantID == 0 {
+		return 0, false, ErrTenantMissing
 	}
+	return claims.TenantID, false, nil
 }
@@ -0,0 +1,94 @@
+package mixin
+
+import (
+	"context"
+	"testing"
+
+	"entgo.io/ent"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/heyinLab/common/pkg/middleware/auth"
+)
+
+func tenantContext(tenantID uint32) context.Context {
+	return auth.NewContext(context.Background(), &auth.Claims{UserID: 1, TenantID: tenantID})
+}
+
+func TestTenantIDIsolate(t *testing.T) {
+	// 默认只添加字段，不开启租户隔离
+	assert.Len(t, TenantID{}.Fields(), 1)
+	assert.Empty(t, TenantID{}.Interceptors())
+	assert.Empty(t, TenantID{}.Hooks())
+}
+
+func TestTenantIDInterceptor(t *testing.T) {
+	traverse := TenantID{Isolate: true}.Interceptors()[0].(ent.TraverseFunc)
+
+	q := &fakeQuery{}
+	assert.NoError(t, traverse(tenantContext(7), q))
+	query, args := whereSQL(q.preds)
+	assert.Equal(t, "SELECT * FROM `orders` WHERE `orders`.`tenant_id` = ?", query)
+	assert.Equal(t, []any{uint32(7)}, args)
+
+	assert.ErrorIs(t, traverse(context.Background(), &fakeQuery{}), ErrTenantMissing)
+}
+
+func TestTenantIDHook(t *testing.T) {
+	hook := TenantID{Isolate: true}.Hooks()[0]
+
+	// 创建时自动填充租户
+	m := newFakeMutation(ent.OpCreate)
+	called, err := runHook(tenantContext(7), hook, m)
+	assert.NoError(t, err)
+	assert.True(t, called)
+	assert.Equal(t, uint32(7), m.fields[FieldTenantID])
+
+	// 创建其他租户的数据
+	m = newFakeMutation(ent.OpCreate)
+	m.fields[FieldTenantID] = uint32(8)
+	called, err = runHook(tenantContext(7), hook, m)
+	assert.ErrorIs(t, err, ErrCrossTenant)
+	assert.False(t, called)
+
+	// 更新、删除追加租户条件
+	for _, op := range []ent.Op{ent.OpUpdate, ent.OpUpdateOne, ent.OpDelete, ent.OpDeleteOne} {
+		m = newFakeMutation(op)
+		_, err = runHook(tenantContext(7), hook, m)
+		assert.NoError(t, err)
+		query, _ := whereSQL(m.preds)
+		assert.Equal(t, "SELECT * FROM `orders` WHERE `orders`.`tenant_id` = ?", query)
+	}
+
+	// 缺少租户
+	_, err = runHook(context.Background(), hook, newFakeMutation(ent.OpUpdate))
+	assert.ErrorIs(t, err, ErrTenantMissing)
+}
+
+func TestSkipTenantIsolation(t *testing.T) {
+	auditor := tenantBypassAuditor
+	t.Cleanup(func() { tenantBypassAuditor = auditor })
+
+	var audits []TenantBypass
+	SetTenantBypassAuditor(func(_ context.Context, bypass TenantBypass) {
+		audits = append(audits, bypass)
+	})
+
+	ctx := SkipTenantIsolation(tenantContext(7), "billing job")
+
+	m := newFakeMutation(ent.OpDelete)
+	called, err := runHook(ctx, TenantID{Isolate: true}.Hooks()[0], m)
+	assert.NoError(t, err)
+	assert.True(t, called)
+	assert.Empty(t, m.preds)
+
+	q := &fakeQuery{}
+	assert.NoError(t, TenantID{Isolate: true}.Interceptors()[0].(ent.TraverseFunc)(ctx, q))
+	assert.Empty(t, q.preds)
+
+	assert.Len(t, audits, 2)
+	assert.Equal(t, "billing job", audits[0].Reason)
+	assert.Equal(t, auth.Operator{Type: "user", ID: 1}, audits[0].Operator)
+	assert.Equal(t, "Order", audits[0].Type)
+
+	assert.Panics(t, func() { SkipTenantIsolation(context.Background(), "") })
+}
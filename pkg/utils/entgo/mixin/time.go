@@ -24,6 +24,10 @@ func (CreatedAt) Fields() []ent.Field {
 	}
 }
 
+func (CreatedAt) Hooks() []ent.Hook {
+	return []ent.Hook{createTimeHook("created_at", timeValue)}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*UpdatedAt)(nil)
@@ -40,11 +44,27 @@ func (UpdatedAt) Fields() []ent.Field {
 	}
 }
 
+func (UpdatedAt) Hooks() []ent.Hook {
+	return []ent.Hook{updateTimeHook("updated_at", timeValue)}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*DeletedAt)(nil)
 
-type DeletedAt struct{ mixin.Schema }
+// DeletedAt 删除时间，SoftDelete 为 true 时开启软删除，见 SoftDelete
+//
+// 软删除需要显式开启，开启后删除改写为更新，已有的 schema 开启前需确认调用方不依赖物理删除：
+//
+//	func (Order) Mixin() []ent.Mixin {
+//	    return []ent.Mixin{mixin.DeletedAt{SoftDelete: true}}
+//	}
+type DeletedAt struct {
+	mixin.Schema
+
+	// SoftDelete 是否开启软删除，默认只添加字段
+	SoftDelete bool
+}
 
 func (DeletedAt) Fields() []ent.Field {
 	return []ent.Field{
@@ -63,11 +83,32 @@ func (DeletedAt) Indexes() []ent.Index {
 	}
 }
 
+// Interceptors 开启软删除时过滤已删除的记录
+func (d DeletedAt) Interceptors() []ent.Interceptor {
+	if !d.SoftDelete {
+		return nil
+	}
+	return []ent.Interceptor{softDeleteInterceptor(FieldDeletedAt)}
+}
+
+// Hooks 开启软删除时将删除改写为更新 deleted_at
+func (d DeletedAt) Hooks() []ent.Hook {
+	if !d.SoftDelete {
+		return nil
+	}
+	return []ent.Hook{softDeleteHook(FieldDeletedAt, "", func() ent.Value { return now() })}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*TimeAt)(nil)
 
-type TimeAt struct{ mixin.Schema }
+type TimeAt struct {
+	mixin.Schema
+
+	// SoftDelete 是否开启软删除，见 DeletedAt
+	SoftDelete bool
+}
 
 func (TimeAt) Fields() []ent.Field {
 	var fields []ent.Field
@@ -77,6 +118,19 @@ func (TimeAt) Fields() []ent.Field {
 	return fields
 }
 
+// Interceptors 开启软删除时过滤已删除的记录，见 DeletedAt
+func (t TimeAt) Interceptors() []ent.Interceptor {
+	return DeletedAt{SoftDelete: t.SoftDelete}.Interceptors()
+}
+
+func (t TimeAt) Hooks() []ent.Hook {
+	var hooks []ent.Hook
+	hooks = append(hooks, CreatedAt{}.Hooks()...)
+	hooks = append(hooks, UpdatedAt{}.Hooks()...)
+	hooks = append(hooks, DeletedAt{SoftDelete: t.SoftDelete}.Hooks()...)
+	return hooks
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*CreateTime)(nil)
@@ -94,6 +148,10 @@ func (CreateTime) Fields() []ent.Field {
 	}
 }
 
+func (CreateTime) Hooks() []ent.Hook {
+	return []ent.Hook{createTimeHook("create_time", timeValue)}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*UpdateTime)(nil)
@@ -110,11 +168,21 @@ func (UpdateTime) Fields() []ent.Field {
 	}
 }
 
+func (UpdateTime) Hooks() []ent.Hook {
+	return []ent.Hook{updateTimeHook("update_time", timeValue)}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*DeleteTime)(nil)
 
-type DeleteTime struct{ mixin.Schema }
+// DeleteTime 删除时间，SoftDelete 为 true 时开启软删除，同 DeletedAt
+type DeleteTime struct {
+	mixin.Schema
+
+	// SoftDelete 是否开启软删除，默认只添加字段
+	SoftDelete bool
+}
 
 func (DeleteTime) Fields() []ent.Field {
 	return []ent.Field{
@@ -133,11 +201,32 @@ func (DeleteTime) Indexes() []ent.Index {
 	}
 }
 
+// Interceptors 开启软删除时过滤已删除的记录
+func (d DeleteTime) Interceptors() []ent.Interceptor {
+	if !d.SoftDelete {
+		return nil
+	}
+	return []ent.Interceptor{softDeleteInterceptor("delete_time")}
+}
+
+// Hooks 开启软删除时将删除改写为更新 delete_time
+func (d DeleteTime) Hooks() []ent.Hook {
+	if !d.SoftDelete {
+		return nil
+	}
+	return []ent.Hook{softDeleteHook("delete_time", "", func() ent.Value { return now() })}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*Time)(nil)
 
-type Time struct{ mixin.Schema }
+type Time struct {
+	mixin.Schema
+
+	// SoftDelete 是否开启软删除，见 DeleteTime
+	SoftDelete bool
+}
 
 func (Time) Fields() []ent.Field {
 	var fields []ent.Field
@@ -147,4 +236,17 @@ func (Time) Fields() []ent.Field {
 	return fields
 }
 
+// Interceptors 开启软删除时过滤已删除的记录，见 DeleteTime
+func (t Time) Interceptors() []ent.Interceptor {
+	return DeleteTime{SoftDelete: t.SoftDelete}.Interceptors()
+}
+
+func (t Time) Hooks() []ent.Hook {
+	var hooks []ent.Hook
+	hooks = append(hooks, CreateTime{}.Hooks()...)
+	hooks = append(hooks, UpdateTime{}.Hooks()...)
+	hooks = append(hooks, DeleteTime{SoftDelete: t.SoftDelete}.Hooks()...)
+	return hooks
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
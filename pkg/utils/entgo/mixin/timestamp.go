@@ -1,13 +1,16 @@
 package mixin
 
 import (
-	"time"
-
 	"entgo.io/ent"
 	"entgo.io/ent/schema/field"
 	"entgo.io/ent/schema/mixin"
+
+	"github.com/heyinLab/common/pkg/utils/entgo/annotation"
 )
 
+// unixMilli 标记毫秒时间戳字段，列表查询时日期字符串会被转换为毫秒
+var unixMilli = annotation.FieldTypeAnnotation{Type: annotation.FieldTypeUnixMilli}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*CreateTimestamp)(nil)
@@ -19,13 +22,18 @@ func (CreateTimestamp) Fields() []ent.Field {
 		// 创建时间，毫秒
 		field.Int64("create_time").
 			Comment("创建时间").
+			Annotations(unixMilli).
 			Immutable().
 			Optional().
 			Nillable().
-			DefaultFunc(time.Now().UnixMilli),
+			DefaultFunc(nowMilli),
 	}
 }
 
+func (CreateTimestamp) Hooks() []ent.Hook {
+	return []ent.Hook{createTimeHook("create_time", milliValue)}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*UpdateTimestamp)(nil)
@@ -35,36 +43,69 @@ type UpdateTimestamp struct{ mixin.Schema }
 func (UpdateTimestamp) Fields() []ent.Field {
 	return []ent.Field{
 		// 更新时间，毫秒
-		// 需要注意的是，如果不是程序自动更新，那么这个字段不会被更新，除非在数据库里面下触发器更新。
+		// UpdateDefault 不会作用于批量更新，由 Hooks 在每次更新时填充
 		field.Int64("update_time").
 			Comment("更新时间").
+			Annotations(unixMilli).
 			Optional().
 			Nillable().
-			UpdateDefault(time.Now().UnixMilli),
+			UpdateDefault(nowMilli),
 	}
 }
 
+func (UpdateTimestamp) Hooks() []ent.Hook {
+	return []ent.Hook{updateTimeHook("update_time", milliValue)}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*DeleteTimestamp)(nil)
 
-type DeleteTimestamp struct{ mixin.Schema }
+// DeleteTimestamp 删除时间（毫秒），SoftDelete 为 true 时开启软删除，同 DeletedAt
+type DeleteTimestamp struct {
+	mixin.Schema
+
+	// SoftDelete 是否开启软删除，默认只添加字段
+	SoftDelete bool
+}
 
 func (DeleteTimestamp) Fields() []ent.Field {
 	return []ent.Field{
 		// 删除时间，毫秒
 		field.Int64("delete_time").
 			Comment("删除时间").
+			Annotations(unixMilli).
 			Optional().
 			Nillable(),
 	}
 }
 
+// Interceptors 开启软删除时过滤已删除的记录
+func (d DeleteTimestamp) Interceptors() []ent.Interceptor {
+	if !d.SoftDelete {
+		return nil
+	}
+	return []ent.Interceptor{softDeleteInterceptor("delete_time")}
+}
+
+// Hooks 开启软删除时将删除改写为更新 delete_time（毫秒）
+func (d DeleteTimestamp) Hooks() []ent.Hook {
+	if !d.SoftDelete {
+		return nil
+	}
+	return []ent.Hook{softDeleteHook("delete_time", "", func() ent.Value { return nowMilli() })}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*Timestamp)(nil)
 
-type Timestamp struct{ mixin.Schema }
+type Timestamp struct {
+	mixin.Schema
+
+	// SoftDelete 是否开启软删除，见 DeleteTimestamp
+	SoftDelete bool
+}
 
 func (Timestamp) Fields() []ent.Field {
 	var fields []ent.Field
@@ -74,6 +115,19 @@ func (Timestamp) Fields() []ent.Field {
 	return fields
 }
 
+// Interceptors 开启软删除时过滤已删除的记录，见 DeleteTimestamp
+func (t Timestamp) Interceptors() []ent.Interceptor {
+	return DeleteTimestamp{SoftDelete: t.SoftDelete}.Interceptors()
+}
+
+func (t Timestamp) Hooks() []ent.Hook {
+	var hooks []ent.Hook
+	hooks = append(hooks, CreateTimestamp{}.Hooks()...)
+	hooks = append(hooks, UpdateTimestamp{}.Hooks()...)
+	hooks = append(hooks, DeleteTimestamp{SoftDelete: t.SoftDelete}.Hooks()...)
+	return hooks
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*CreatedAtTimestamp)(nil)
@@ -85,13 +139,18 @@ func (CreatedAtTimestamp) Fields() []ent.Field {
 		// 创建时间，毫秒
 		field.Int64("created_at").
 			Comment("创建时间").
+			Annotations(unixMilli).
 			Immutable().
 			Optional().
 			Nillable().
-			DefaultFunc(time.Now().UnixMilli),
+			DefaultFunc(nowMilli),
 	}
 }
 
+func (CreatedAtTimestamp) Hooks() []ent.Hook {
+	return []ent.Hook{createTimeHook("created_at", milliValue)}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*UpdatedAtTimestamp)(nil)
@@ -101,36 +160,69 @@ type UpdatedAtTimestamp struct{ mixin.Schema }
 func (UpdatedAtTimestamp) Fields() []ent.Field {
 	return []ent.Field{
 		// 更新时间，毫秒
-		// 需要注意的是，如果不是程序自动更新，那么这个字段不会被更新，除非在数据库里面下触发器更新。
+		// UpdateDefault 不会作用于批量更新，由 Hooks 在每次更新时填充
 		field.Int64("updated_at").
 			Comment("更新时间").
+			Annotations(unixMilli).
 			Optional().
 			Nillable().
-			UpdateDefault(time.Now().UnixMilli),
+			UpdateDefault(nowMilli),
 	}
 }
 
+func (UpdatedAtTimestamp) Hooks() []ent.Hook {
+	return []ent.Hook{updateTimeHook("updated_at", milliValue)}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*DeletedAtTimestamp)(nil)
 
-type DeletedAtTimestamp struct{ mixin.Schema }
+// DeletedAtTimestamp 删除时间（毫秒），SoftDelete 为 true 时开启软删除，同 DeletedAt
+type DeletedAtTimestamp struct {
+	mixin.Schema
+
+	// SoftDelete 是否开启软删除，默认只添加字段
+	SoftDelete bool
+}
 
 func (DeletedAtTimestamp) Fields() []ent.Field {
 	return []ent.Field{
 		// 删除时间，毫秒
 		field.Int64("deleted_at").
 			Comment("删除时间").
+			Annotations(unixMilli).
 			Optional().
 			Nillable(),
 	}
 }
 
+// Interceptors 开启软删除时过滤已删除的记录
+func (d DeletedAtTimestamp) Interceptors() []ent.Interceptor {
+	if !d.SoftDelete {
+		return nil
+	}
+	return []ent.Interceptor{softDeleteInterceptor(FieldDeletedAt)}
+}
+
+// Hooks 开启软删除时将删除改写为更新 deleted_at（毫秒）
+func (d DeletedAtTimestamp) Hooks() []ent.Hook {
+	if !d.SoftDelete {
+		return nil
+	}
+	return []ent.Hook{softDeleteHook(FieldDeletedAt, "", func() ent.Value { return nowMilli() })}
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 var _ ent.Mixin = (*TimestampAt)(nil)
 
-type TimestampAt struct{ mixin.Schema }
+type TimestampAt struct {
+	mixin.Schema
+
+	// SoftDelete 是否开启软删除，见 DeletedAtTimestamp
+	SoftDelete bool
+}
 
 func (TimestampAt) Fields() []ent.Field {
 	var fields []ent.Field
@@ -139,3 +231,16 @@ func (TimestampAt) Fields() []ent.Field {
 	fields = append(fields, DeletedAtTimestamp{}.Fields()...)
 	return fields
 }
+
+// Interceptors 开启软删除时过滤已删除的记录，见 DeletedAtTimestamp
+func (t TimestampAt) Interceptors() []ent.Interceptor {
+	return DeletedAtTimestamp{SoftDelete: t.SoftDelete}.Interceptors()
+}
+
+func (t TimestampAt) Hooks() []ent.Hook {
+	var hooks []ent.Hook
+	hooks = append(hooks, CreatedAtTimestamp{}.Hooks()...)
+	hooks = append(hooks, UpdatedAtTimestamp{}.Hooks()...)
+	hooks = append(hooks, DeletedAtTimestamp{SoftDelete: t.SoftDelete}.Hooks()...)
+	return hooks
+}
@@ -1,22 +1,192 @@
 package mixin
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+
 	"entgo.io/ent"
+	"entgo.io/ent/dialect/sql"
 	"entgo.io/ent/schema/field"
 	"entgo.io/ent/schema/mixin"
+
+	businessErrors "github.com/heyinLab/common/pkg/errors"
 )
 
-// 注意：使用此 Mixin 时，需要在 Ent 客户端配置 Hook 或 Interceptor
-// 来在更新操作前自动检查并递增版本号。
+const FieldVersion = "version"
 
 var _ ent.Mixin = (*Version)(nil)
 
+// Version 版本号/乐观锁，更新时自动检查并递增版本号：
+//
+//   - 调用方通过 SetVersion(读取时的版本号) 指定期望版本，更新时追加 version = 期望版本 条件，
+//     并将版本号设置为期望版本 + 1，未更新任何记录时返回 *VersionConflictError；
+//   - 批量更新时可通过 WithExpectedVersions 为指定实体类型的下一次批量更新指定每条记录的期望版本，
+//     更新行数不一致时返回 *VersionConflictError，此时部分记录可能已被更新，应在事务中使用并回滚；
+//   - 未指定期望版本时只递增版本号。
+//
+// *VersionConflictError 可通过 errors.Is(err, errors.ErrDataConflict) 判断。
 type Version struct{ mixin.Schema }
 
 func (Version) Fields() []ent.Field {
 	return []ent.Field{
-		field.Uint32("version").
+		field.Uint32(FieldVersion).
 			Comment("版本号/乐观锁").
 			Default(1), // 初始版本为 1
 	}
 }
+
+// Hooks 检查并递增版本号
+func (Version) Hooks() []ent.Hook {
+	return []ent.Hook{versionHook}
+}
+
+// VersionConflictError 乐观锁版本冲突
+type VersionConflictError struct {
+	Type     string // 实体类型
+	Want     int    // 期望更新的行数，0 表示至少一行
+	Affected int    // 实际更新的行数
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("ent: %s version conflict: %d of %d rows updated", e.Type, e.Affected, e.Want)
+}
+
+// Unwrap 映射为 ErrDataConflict
+func (e *VersionConflictError) Unwrap() error {
+	return businessErrors.ErrDataConflict
+}
+
+// ExpectedVersion 批量更新时单条记录的期望版本
+type ExpectedVersion struct {
+	ID      any
+	Version uint32
+}
+
+type expectedVersionsKey struct{}
+
+// expectedVersions context 中的期望版本，只被一次批量更新使用
+type expectedVersions struct {
+	typ      string
+	idColumn string
+	versions []ExpectedVersion
+	used     atomic.Bool
+}
+
+// take 返回 m 对应的期望版本，第一次匹配后标记为已使用
+func (e *expectedVersions) take(ctx context.Context, m ent.Mutation) []ExpectedVersion {
+	if !m.Op().Is(ent.OpUpdate) || m.Type() != e.typ || isSoftDeleting(ctx, m) || !e.used.CompareAndSwap(false, true) {
+		return nil
+	}
+	return e.versions
+}
+
+// WithExpectedVersions 为批量更新指定每条记录的期望版本
+//
+// typ 为 Mutation.Type() 返回的实体类型。期望版本只应用于 ctx 中该类型的第一次批量更新（不包括软删除改写的更新），
+// 同一 ctx 中的其他实体类型、软删除以及之后的更新不受影响。idColumn 为 ID 列名，为空时为 id。
+//
+// 使用示例:
+//
+//	ctx = mixin.WithExpectedVersions(ctx, ent.TypeOrder, order.FieldID,
+//	    mixin.ExpectedVersion{ID: 1, Version: 3},
+//	    mixin.ExpectedVersion{ID: 2, Version: 5},
+//	)
+//	err := tx.Order.Update().SetStatus("closed").Exec(ctx)
+func WithExpectedVersions(ctx context.Context, typ, idColumn string, versions ...ExpectedVersion) context.Context {
+	if idColumn == "" {
+		idColumn = "id"
+	}
+	return context.WithValue(ctx, expectedVersionsKey{}, &expectedVersions{typ: typ, idColumn: idColumn, versions: versions})
+}
+
+func versionHook(next ent.Mutator) ent.Mutator {
+	return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+		if !m.Op().Is(ent.OpUpdate | ent.OpUpdateOne) {
+			return next.Mutate(ctx, m)
+		}
+
+		w, ok := m.(interface{ WhereP(...func(*sql.Selector)) })
+		if !ok {
+			return nil, fmt.Errorf("ent: unexpected mutation type %T", m)
+		}
+
+		want := 0
+		expected, hasExpected := m.Field(FieldVersion)
+		var versions []ExpectedVersion
+		e, _ := ctx.Value(expectedVersionsKey{}).(*expectedVersions)
+		if e != nil {
+			versions = e.take(ctx, m)
+		}
+
+		switch {
+		case len(versions) > 0:
+			if hasExpected {
+				return nil, errors.New("ent: SetVersion cannot be combined with WithExpectedVersions")
+			}
+			w.WhereP(expectedVersionsPredicate(e.idColumn, versions))
+			if err := m.AddField(FieldVersion, int32(1)); err != nil {
+				return nil, err
+			}
+			want = len(versions)
+
+		case hasExpected:
+			version, _ := expected.(uint32)
+			w.WhereP(sql.FieldEQ(FieldVersion, version))
+			if err := m.SetField(FieldVersion, version+1); err != nil {
+				return nil, err
+			}
+
+		default:
+			// 未指定期望版本，只递增版本号
+			if err := m.AddField(FieldVersion, int32(1)); err != nil {
+				return nil, err
+			}
+			return next.Mutate(ctx, m)
+		}
+
+		v, err := next.Mutate(ctx, m)
+		if err != nil {
+			if m.Op().Is(ent.OpUpdateOne) && isNotFound(err) {
+				return nil, &VersionConflictError{Type: m.Type(), Want: 1}
+			}
+			return nil, err
+		}
+
+		// 按 ID 更新返回实体，批量更新返回行数
+		if n, ok := v.(int); ok && (n == 0 || (want > 0 && n != want)) {
+			return nil, &VersionConflictError{Type: m.Type(), Want: want, Affected: n}
+		}
+		return v, nil
+	})
+}
+
+// expectedVersionsPredicate (id = ? AND version = ?) OR ...
+func expectedVersionsPredicate(idColumn string, versions []ExpectedVersion) func(*sql.Selector) {
+	return func(s *sql.Selector) {
+		ps := make([]*sql.Predicate, 0, len(versions))
+		for _, v := range versions {
+			ps = append(ps, sql.And(
+				sql.EQ(s.C(idColumn), v.ID),
+				sql.EQ(s.C(FieldVersion), v.Version),
+			))
+		}
+		s.Where(sql.Or(ps...))
+	}
+}
+
+// isNotFound 判断是否为 ent 生成代码中的 NotFoundError
+func isNotFound(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		t := reflect.TypeOf(err)
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Name() == "NotFoundError" {
+			return true
+		}
+	}
+	return false
+}
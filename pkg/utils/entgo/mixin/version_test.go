@@ -0,0 +1,113 @@
+package mixin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"entgo.io/ent"
+	"github.com/stretchr/testify/assert"
+
+	businessErrors "github.com/heyinLab/common/pkg/errors"
+)
+
+type NotFoundError struct{ label string }
+
+func (e *NotFoundError) Error() string { return "ent: " + e.label + " not found" }
+
+func TestVersionHookUpdateOne(t *testing.T) {
+	hook := Version{}.Hooks()[0]
+	ctx := context.Background()
+
+	m := newFakeMutation(ent.OpUpdateOne)
+	m.fields[FieldVersion] = uint32(3)
+	_, err := runHookWith(ctx, hook, m, func() (ent.Value, error) { return struct{}{}, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(4), m.fields[FieldVersion])
+	query, args := whereSQL(m.preds)
+	assert.Equal(t, "SELECT * FROM `orders` WHERE `orders`.`version` = ?", query)
+	assert.Equal(t, []any{uint32(3)}, args)
+
+	// 版本不匹配时 ent 返回 NotFoundError
+	m = newFakeMutation(ent.OpUpdateOne)
+	m.fields[FieldVersion] = uint32(3)
+	_, err = runHookWith(ctx, hook, m, func() (ent.Value, error) { return nil, &NotFoundError{label: "order"} })
+	var conflict *VersionConflictError
+	assert.True(t, errors.As(err, &conflict))
+	assert.True(t, errors.Is(err, businessErrors.ErrDataConflict))
+	assert.Equal(t, "DATA_CONFLICT", businessErrors.ClassifyError(err).Type)
+
+	// 未指定期望版本时只递增
+	m = newFakeMutation(ent.OpUpdateOne)
+	_, err = runHookWith(ctx, hook, m, func() (ent.Value, error) { return struct{}{}, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), m.added[FieldVersion])
+	assert.Empty(t, m.preds)
+}
+
+func TestVersionHookBulk(t *testing.T) {
+	hook := Version{}.Hooks()[0]
+	newCtx := func() context.Context {
+		return WithExpectedVersions(context.Background(), "Order", "",
+			ExpectedVersion{ID: 1, Version: 3},
+			ExpectedVersion{ID: 2, Version: 5},
+		)
+	}
+
+	m := newFakeMutation(ent.OpUpdate)
+	_, err := runHookWith(newCtx(), hook, m, func() (ent.Value, error) { return 2, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), m.added[FieldVersion])
+	query, args := whereSQL(m.preds)
+	assert.Equal(t, "SELECT * FROM `orders` WHERE (`orders`.`id` = ? AND `orders`.`version` = ?) OR (`orders`.`id` = ? AND `orders`.`version` = ?)", query)
+	assert.Equal(t, []any{1, uint32(3), 2, uint32(5)}, args)
+
+	// 部分记录版本不匹配
+	m = newFakeMutation(ent.OpUpdate)
+	_, err = runHookWith(newCtx(), hook, m, func() (ent.Value, error) { return 1, nil })
+	var conflict *VersionConflictError
+	assert.True(t, errors.As(err, &conflict))
+	assert.Equal(t, 2, conflict.Want)
+	assert.Equal(t, 1, conflict.Affected)
+
+	// 批量更新指定统一的期望版本
+	m = newFakeMutation(ent.OpUpdate)
+	m.fields[FieldVersion] = uint32(3)
+	_, err = runHookWith(context.Background(), hook, m, func() (ent.Value, error) { return 0, nil })
+	assert.ErrorIs(t, err, businessErrors.ErrDataConflict)
+
+	// 指定 ID 列名
+	m = newFakeMutation(ent.OpUpdate)
+	ctx := WithExpectedVersions(context.Background(), "Order", "order_id", ExpectedVersion{ID: 1, Version: 3})
+	_, err = runHookWith(ctx, hook, m, func() (ent.Value, error) { return 1, nil })
+	assert.NoError(t, err)
+	query, _ = whereSQL(m.preds)
+	assert.Equal(t, "SELECT * FROM `orders` WHERE `orders`.`order_id` = ? AND `orders`.`version` = ?", query)
+}
+
+func TestVersionHookBulkScope(t *testing.T) {
+	hook := Version{}.Hooks()[0]
+	ctx := WithExpectedVersions(context.Background(), "Order", "", ExpectedVersion{ID: 1, Version: 3})
+	update := func(m *fakeMutation, ctx context.Context) {
+		_, err := runHookWith(ctx, hook, m, func() (ent.Value, error) { return 5, nil })
+		assert.NoError(t, err)
+		assert.Empty(t, m.preds)
+		assert.Equal(t, int32(1), m.added[FieldVersion])
+	}
+
+	// 其他实体类型不受影响
+	other := newFakeMutation(ent.OpUpdate)
+	other.typ = "OrderItem"
+	update(other, ctx)
+
+	// 软删除改写的更新不受影响
+	deleting := newFakeMutation(ent.OpUpdate)
+	update(deleting, context.WithValue(ctx, softDeletingKey{}, ent.Mutation(deleting)))
+
+	// 只应用于第一次批量更新
+	m := newFakeMutation(ent.OpUpdate)
+	_, err := runHookWith(ctx, hook, m, func() (ent.Value, error) { return 1, nil })
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m.preds)
+	update(newFakeMutation(ent.OpUpdate), ctx)
+}
@@ -0,0 +1,478 @@
+package entgo
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+
+	"github.com/heyinLab/common/pkg/utils/query_parser"
+)
+
+type AggregateFunc int
+
+const (
+	AggregateCount         AggregateFunc = iota // 计数，不指定字段时为 COUNT(*)
+	AggregateCountDistinct                      // 去重计数
+	AggregateSum                                // 求和
+	AggregateAvg                                // 平均值
+	AggregateMin                                // 最小值
+	AggregateMax                                // 最大值
+)
+
+var aggregateFuncs = [...]string{
+	AggregateCount:         "count",
+	AggregateCountDistinct: "count_distinct",
+	AggregateSum:           "sum",
+	AggregateAvg:           "avg",
+	AggregateMin:           "min",
+	AggregateMax:           "max",
+}
+
+// havingOps HAVING 条件支持的操作符
+var havingOps = []string{
+	ops[FilterNot],
+	ops[FilterIn],
+	ops[FilterNotIn],
+	ops[FilterGTE],
+	ops[FilterGT],
+	ops[FilterLTE],
+	ops[FilterLT],
+	ops[FilterRange],
+}
+
+// aggregateAliasPattern 分组字段和聚合项的写法，同时作为结果的列名
+var aggregateAliasPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// hasAggregateFunc 是否为聚合函数
+func hasAggregateFunc(str string) bool {
+	str = strings.ToLower(str)
+	for _, item := range aggregateFuncs {
+		if str == item {
+			return true
+		}
+	}
+	return false
+}
+
+// AggregateRequest 聚合查询参数
+//
+// 结果的列名为分组字段和聚合项的原始写法，如 status、create_time__month、size__sum，只能包含字母、数字和下划线。
+type AggregateRequest struct {
+	GroupBy    []string // 分组字段，支持日期部分，如 ["status", "create_time__month"]
+	Aggregates []string // 聚合项，{字段名}__{聚合函数}，如 ["count", "id__count_distinct", "size__sum"]
+	Having     string   // 分组过滤条件，json object，键为 {聚合项}__{操作符}，如 {"size__sum__gte":"100"}
+	Query      string   // AND过滤条件，同 BuildFilterSelector
+	OrQuery    string   // OR过滤条件，同 BuildFilterSelector
+	OrderBy    []string // 排序，分组字段或聚合项，前加'-'为降序，如 ["-count"]
+	Limit      int32    // 返回的最大行数，为 0 时不限制
+}
+
+// aggregateItem 解析后的聚合项
+type aggregateItem struct {
+	alias  string
+	fn     string
+	column string // 为空时为 COUNT(*)
+}
+
+// expr 聚合表达式
+func (a aggregateItem) expr(s *sql.Selector) string {
+	if a.column == "" {
+		return "COUNT(*)"
+	}
+
+	column := s.C(a.column)
+	switch a.fn {
+	case aggregateFuncs[AggregateCountDistinct]:
+		return "COUNT(DISTINCT " + column + ")"
+	default:
+		return strings.ToUpper(a.fn) + "(" + column + ")"
+	}
+}
+
+// groupItem 解析后的分组字段
+type groupItem struct {
+	alias    string
+	column   string
+	datePart string
+}
+
+// expr 分组表达式
+func (g groupItem) expr(s *sql.Selector) string {
+	if g.datePart == "" {
+		return s.C(g.column)
+	}
+	return filterDatePartField(s, g.datePart, g.column)
+}
+
+// BuildAggregateSelector 构建聚合查询选择器，见 QueryPolicy.BuildAggregateSelector
+func BuildAggregateSelector(req *AggregateRequest) (error, []func(s *sql.Selector)) {
+	return (*QueryPolicy)(nil).BuildAggregateSelector(req)
+}
+
+// BuildAggregateSelector 按策略校验并构建聚合查询选择器，p 为 nil 时不做限制
+//
+// 过滤条件的校验规则同 BuildFilterSelector；分组字段需 Groupable，聚合函数需在 Aggregates 之中，
+// 日期部分需在 Operators 之中，COUNT(*) 始终允许。设置 MaxPageSize 后 Limit 不能为 0 或超过上限。
+//
+// 使用示例:
+//
+//	err, selectors := userQueryPolicy.BuildAggregateSelector(&entgo.AggregateRequest{
+//	    GroupBy:    []string{"status", "create_time__month"},
+//	    Aggregates: []string{"count", "size__sum"},
+//	    Having:     `{"count__gt":"10"}`,
+//	    OrderBy:    []string{"-count"},
+//	})
+//	rows, err := entgo.QueryAggregate[statusCount](ctx, client.Driver(), user.Table, selectors)
+func (p *QueryPolicy) BuildAggregateSelector(req *AggregateRequest) (error, []func(s *sql.Selector)) {
+	if req == nil || len(req.Aggregates) == 0 {
+		return newPolicyError("aggregates", "at least one aggregate is required"), nil
+	}
+	if err := p.CheckPaging(req.Limit, req.Limit == 0); err != nil {
+		return err, nil
+	}
+
+	err, groups := p.parseGroupBy(req.GroupBy)
+	if err != nil {
+		return err, nil
+	}
+	err, aggregates := p.parseAggregates(req.Aggregates)
+	if err != nil {
+		return err, nil
+	}
+
+	having, err := parseFilterCommand(req.Having)
+	if err != nil {
+		return newPolicyError("having", err.Error()), nil
+	}
+	if err = checkHaving(having, aggregates); err != nil {
+		return err, nil
+	}
+
+	err, orders := aggregateOrders(req.OrderBy, groups, aggregates)
+	if err != nil {
+		return err, nil
+	}
+
+	err, selectors := p.BuildFilterSelector(req.Query, req.OrQuery)
+	if err != nil {
+		return err, nil
+	}
+
+	return nil, append(selectors, func(s *sql.Selector) {
+		d := s.Builder.Dialect()
+
+		columns := make([]string, 0, len(groups)+len(aggregates))
+		groupBy := make([]string, 0, len(groups))
+		exprs := make(map[string]string, len(groups)+len(aggregates))
+		for _, g := range groups {
+			expr := g.expr(s)
+			exprs[g.alias] = expr
+			groupBy = append(groupBy, expr)
+			columns = append(columns, expr+" AS "+quoteIdent(d, g.alias))
+		}
+		for _, a := range aggregates {
+			expr := a.expr(s)
+			exprs[a.alias] = expr
+			columns = append(columns, expr+" AS "+quoteIdent(d, a.alias))
+		}
+
+		s.Select(columns...)
+		if len(groupBy) > 0 {
+			s.GroupBy(groupBy...)
+		}
+		if hp := havingPredicate(having, exprs); hp != nil {
+			s.Having(hp)
+		}
+		for _, o := range orders {
+			if strings.HasPrefix(o, "-") {
+				s.OrderBy(quoteIdent(d, o[1:]) + " DESC")
+			} else {
+				s.OrderBy(quoteIdent(d, o) + " ASC")
+			}
+		}
+		if req.Limit > 0 {
+			s.Limit(int(req.Limit))
+		}
+	})
+}
+
+// QueryAggregate 执行聚合查询，结果按列名扫描到 T 中
+//
+// T 的字段通过 json 或 sql 标签指定列名，如:
+//
+//	type statusCount struct {
+//	    Status string `json:"status"`
+//	    Count  int64  `json:"count"`
+//	}
+func QueryAggregate[T any](ctx context.Context, drv dialect.Driver, table string, selectors []func(s *sql.Selector)) ([]T, error) {
+	s := sql.Dialect(drv.Dialect()).Select().From(sql.Table(table))
+	for _, selector := range selectors {
+		selector(s)
+	}
+
+	query, args := s.Query()
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	rows := &sql.Rows{}
+	if err := drv.Query(ctx, query, args, rows); err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []T
+	if err := sql.ScanSlice(rows, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// parseGroupBy 解析并校验分组字段
+func (p *QueryPolicy) parseGroupBy(groupBy []string) (error, []groupItem) {
+	groups := make([]groupItem, 0, len(groupBy))
+	for _, key := range groupBy {
+		if !aggregateAliasPattern.MatchString(key) {
+			return newPolicyError(key, "invalid group by field"), nil
+		}
+		keys := splitQueryKey(key)
+		if len(keys[0]) == 0 || len(keys) > 2 || isJsonFieldKey(keys[0]) {
+			return newPolicyError(key, "invalid group by field"), nil
+		}
+
+		fp, ok := p.lookupField(keys[0])
+		if !ok {
+			return newPolicyError(keys[0], "field is not allowed"), nil
+		}
+		if !fp.Groupable {
+			return newPolicyError(keys[0], "field is not groupable"), nil
+		}
+
+		g := groupItem{alias: key, column: fp.column(keys[0])}
+		if len(keys) == 2 {
+			part := strings.ToLower(keys[1])
+			if !IsDatePart(part) {
+				return newPolicyError(keys[0], fmt.Sprintf("invalid date part %q", keys[1])), nil
+			}
+			if !fp.allows(part) {
+				return newPolicyError(keys[0], fmt.Sprintf("operator %q is not allowed", keys[1])), nil
+			}
+			g.datePart = part
+		}
+		groups = append(groups, g)
+	}
+	return nil, groups
+}
+
+// parseAggregates 解析并校验聚合项
+func (p *QueryPolicy) parseAggregates(aggregates []string) (error, []aggregateItem) {
+	items := make([]aggregateItem, 0, len(aggregates))
+	for _, key := range aggregates {
+		if !aggregateAliasPattern.MatchString(key) {
+			return newPolicyError(key, "invalid aggregate"), nil
+		}
+		keys := splitQueryKey(key)
+		switch {
+		case len(keys) == 1 && strings.ToLower(keys[0]) == aggregateFuncs[AggregateCount]:
+			items = append(items, aggregateItem{alias: key, fn: aggregateFuncs[AggregateCount]})
+			continue
+		case len(keys) != 2 || len(keys[0]) == 0 || isJsonFieldKey(keys[0]) || !hasAggregateFunc(keys[1]):
+			return newPolicyError(key, "invalid aggregate"), nil
+		}
+
+		fp, ok := p.lookupField(keys[0])
+		if !ok {
+			return newPolicyError(keys[0], "field is not allowed"), nil
+		}
+		fn := strings.ToLower(keys[1])
+		if !fp.allowsAggregate(fn) {
+			return newPolicyError(keys[0], fmt.Sprintf("aggregate %q is not allowed", keys[1])), nil
+		}
+		items = append(items, aggregateItem{alias: key, fn: fn, column: fp.column(keys[0])})
+	}
+	return nil, items
+}
+
+// splitHavingKey 拆分 HAVING 条件的键为聚合项和操作符
+func splitHavingKey(key string) (alias, op string) {
+	if i := strings.LastIndex(key, QueryDelimiter); i > 0 {
+		op = strings.ToLower(key[i+len(QueryDelimiter):])
+		for _, item := range havingOps {
+			if op == item {
+				return key[:i], op
+			}
+		}
+	}
+	return key, ""
+}
+
+// checkHaving 校验 HAVING 条件只引用了请求中的聚合项，且集合操作符的值有效
+func checkHaving(expr *query_parser.FilterExpr, aggregates []aggregateItem) error {
+	if expr == nil {
+		return nil
+	}
+	if expr.Kind != query_parser.FilterExprLeaf {
+		for _, child := range expr.Children {
+			if err := checkHaving(child, aggregates); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	alias, op := splitHavingKey(expr.Key)
+	for _, a := range aggregates {
+		if a.alias != alias {
+			continue
+		}
+		if _, err := havingValues(alias, op, expr.Value); err != nil {
+			return newPolicyError(alias, err.Error())
+		}
+		return nil
+	}
+	return newPolicyError(alias, "having must reference a requested aggregate")
+}
+
+// havingPredicate 将 HAVING 条件转换为谓词，exprs 为聚合项对应的表达式
+func havingPredicate(expr *query_parser.FilterExpr, exprs map[string]string) *sql.Predicate {
+	if expr == nil {
+		return nil
+	}
+
+	switch expr.Kind {
+	case query_parser.FilterExprLeaf:
+		alias, op := splitHavingKey(expr.Key)
+		return havingCondition(exprs[alias], op, expr.Value)
+
+	case query_parser.FilterExprNot:
+		if len(expr.Children) == 0 {
+			return nil
+		}
+		if p := havingPredicate(expr.Children[0], exprs); p != nil {
+			return sql.Not(p)
+		}
+		return nil
+
+	default:
+		var ps []*sql.Predicate
+		for _, child := range expr.Children {
+			if p := havingPredicate(child, exprs); p != nil {
+				ps = append(ps, p)
+			}
+		}
+		switch {
+		case len(ps) == 0:
+			return nil
+		case len(ps) == 1:
+			return ps[0]
+		case expr.Kind == query_parser.FilterExprOr:
+			return sql.Or(ps...)
+		default:
+			return sql.And(ps...)
+		}
+	}
+}
+
+// havingCondition 聚合表达式的比较条件，数值按数字传入
+func havingCondition(expr, op, value string) *sql.Predicate {
+	switch op {
+	case "":
+		return sql.EQ(expr, havingValue(value))
+	case ops[FilterNot]:
+		return sql.Not(sql.EQ(expr, havingValue(value)))
+	case ops[FilterGTE]:
+		return sql.GTE(expr, havingValue(value))
+	case ops[FilterGT]:
+		return sql.GT(expr, havingValue(value))
+	case ops[FilterLTE]:
+		return sql.LTE(expr, havingValue(value))
+	case ops[FilterLT]:
+		return sql.LT(expr, havingValue(value))
+	}
+
+	// 值已由 checkHaving 校验
+	values, err := havingValues(expr, op, value)
+	if err != nil {
+		return nil
+	}
+
+	switch op {
+	case ops[FilterIn]:
+		return sql.In(expr, values...)
+	case ops[FilterNotIn]:
+		return sql.NotIn(expr, values...)
+	case ops[FilterRange]:
+		return sql.And(sql.GTE(expr, values[0]), sql.LTE(expr, values[1]))
+	default:
+		return nil
+	}
+}
+
+// havingValues 解析 in、not_in、range 的值，其他操作符返回 nil
+func havingValues(field, op, value string) ([]any, error) {
+	switch op {
+	case ops[FilterIn], ops[FilterNotIn], ops[FilterRange]:
+	default:
+		return nil, nil
+	}
+
+	values, err := CollectionValues(field, value)
+	if err != nil {
+		return nil, err
+	}
+	if op == ops[FilterRange] && len(values) != 2 {
+		return nil, fmt.Errorf("invalid value %q for %s: range expects 2 values", value, field)
+	}
+	for i, v := range values {
+		values[i] = havingValue(JsonItemString(v))
+	}
+	return values, nil
+}
+
+// havingValue 将值转换为整数或浮点数，无法转换时原样返回
+func havingValue(value string) any {
+	value = strings.TrimSpace(value)
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	return value
+}
+
+// aggregateOrders 校验排序字段为分组字段或聚合项
+func aggregateOrders(orderBys []string, groups []groupItem, aggregates []aggregateItem) (error, []string) {
+	orders := make([]string, 0, len(orderBys))
+	for _, v := range orderBys {
+		name := strings.TrimPrefix(v, "-")
+		if len(name) == 0 {
+			continue
+		}
+
+		found := false
+		for _, g := range groups {
+			found = found || g.alias == name
+		}
+		for _, a := range aggregates {
+			found = found || a.alias == name
+		}
+		if !found {
+			return newPolicyError(name, "order by must reference a group by field or an aggregate"), nil
+		}
+		orders = append(orders, v)
+	}
+	return nil, orders
+}
+
+// quoteIdent 按数据库方言引用标识符
+func quoteIdent(dialectName, ident string) string {
+	b := &sql.Builder{}
+	b.SetDialect(dialectName)
+	return b.Quote(ident)
+}
@@ -0,0 +1,127 @@
+package entgo
+
+import (
+	"context"
+	"testing"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildAggregateSelector(t *testing.T) {
+	req := &AggregateRequest{
+		GroupBy:    []string{"status", "create_time__month"},
+		Aggregates: []string{"count", "size__sum", "user_id__count_distinct"},
+		Having:     `{"count__gt":"10","size__sum__range":["100","200"]}`,
+		Query:      `{"tenant_id":"1"}`,
+		OrderBy:    []string{"-count", "status"},
+		Limit:      20,
+	}
+
+	testcases := []struct {
+		dialect string
+		sql     string
+	}{
+		{
+			dialect.MySQL,
+			"SELECT `files`.`status` AS `status`, MONTH(`files`.`create_time`) AS `create_time__month`, COUNT(*) AS `count`, SUM(`files`.`size`) AS `size__sum`, COUNT(DISTINCT `files`.`user_id`) AS `user_id__count_distinct` FROM `files` WHERE `files`.`tenant_id` = ? GROUP BY `files`.`status`, MONTH(`files`.`create_time`) HAVING COUNT(*) > ? AND (SUM(`files`.`size`) >= ? AND SUM(`files`.`size`) <= ?) ORDER BY `count` DESC, `status` ASC LIMIT 20",
+		},
+		{
+			dialect.Postgres,
+			`SELECT "files"."status" AS "status", EXTRACT('MONTH' FROM "files"."create_time") AS "create_time__month", COUNT(*) AS "count", SUM("files"."size") AS "size__sum", COUNT(DISTINCT "files"."user_id") AS "user_id__count_distinct" FROM "files" WHERE "files"."tenant_id" = $1 GROUP BY "files"."status", EXTRACT('MONTH' FROM "files"."create_time") HAVING COUNT(*) > $2 AND (SUM("files"."size") >= $3 AND SUM("files"."size") <= $4) ORDER BY "count" DESC, "status" ASC LIMIT 20`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.dialect, func(t *testing.T) {
+			err, selectors := BuildAggregateSelector(req)
+			require.NoError(t, err)
+
+			s := sql.Dialect(tc.dialect).Select().From(sql.Table("files"))
+			for _, fnc := range selectors {
+				fnc(s)
+			}
+			query, args := s.Query()
+			require.NoError(t, s.Err())
+			require.Equal(t, tc.sql, query)
+			require.Equal(t, []any{"1", int64(10), int64(100), int64(200)}, args)
+		})
+	}
+}
+
+func TestBuildAggregateSelectorInvalid(t *testing.T) {
+	policy := &QueryPolicy{
+		Fields: map[string]FieldPolicy{
+			"status":     {Groupable: true},
+			"createTime": {Column: "create_time", Operators: []string{"month"}, Groupable: true},
+			"size":       {Aggregates: []string{"sum"}},
+		},
+		MaxPageSize: 100,
+	}
+
+	err, _ := policy.BuildAggregateSelector(&AggregateRequest{
+		GroupBy:    []string{"createTime__month"},
+		Aggregates: []string{"count", "size__sum"},
+		Limit:      10,
+	})
+	require.NoError(t, err)
+
+	for _, req := range []*AggregateRequest{
+		{Limit: 10},
+		{Aggregates: []string{"count"}},
+		{Aggregates: []string{"count"}, Limit: 1000},
+		{GroupBy: []string{"size"}, Aggregates: []string{"count"}, Limit: 10},
+		{GroupBy: []string{"createTime__year"}, Aggregates: []string{"count"}, Limit: 10},
+		{GroupBy: []string{"name"}, Aggregates: []string{"count"}, Limit: 10},
+		{Aggregates: []string{"size__avg"}, Limit: 10},
+		{Aggregates: []string{"size__median"}, Limit: 10},
+		{Aggregates: []string{"count"}, Having: `{"size__sum__gt":"1"}`, Limit: 10},
+		{Aggregates: []string{"count"}, Having: `{"count__in":"1"}`, Limit: 10},
+		{Aggregates: []string{"count"}, Having: `{"count__not_in":"[]"}`, Limit: 10},
+		{Aggregates: []string{"count"}, Having: `{"count__range":"[1]"}`, Limit: 10},
+		{Aggregates: []string{"count"}, Having: `{"count__range":"[1, 2, 3]"}`, Limit: 10},
+		{Aggregates: []string{"count"}, OrderBy: []string{"-size"}, Limit: 10},
+	} {
+		err, _ = policy.BuildAggregateSelector(req)
+		require.Error(t, err, "%+v", req)
+	}
+
+	// 不限制策略时，别名中的引号同样被拒绝
+	for _, req := range []*AggregateRequest{
+		{GroupBy: []string{"a` FROM users UNION SELECT password, 1 FROM admins -- "}, Aggregates: []string{"count"}},
+		{GroupBy: []string{`a" FROM users --`}, Aggregates: []string{"count"}},
+		{Aggregates: []string{"size__sum`"}},
+		{Aggregates: []string{`count"`}},
+		{Aggregates: []string{"count"}, OrderBy: []string{"count` --"}},
+	} {
+		err, _ = BuildAggregateSelector(req)
+		require.Error(t, err, "%+v", req)
+	}
+}
+
+func TestQueryAggregate(t *testing.T) {
+	drv := sql.OpenDB(dialect.SQLite, openSQLite(t))
+
+	type yearStat struct {
+		Year  int64   `json:"created_at__year"`
+		Count int64   `json:"count"`
+		Sum   int64   `json:"age__sum"`
+		Avg   float64 `json:"age__avg"`
+	}
+
+	err, selectors := BuildAggregateSelector(&AggregateRequest{
+		GroupBy:    []string{"created_at__year"},
+		Aggregates: []string{"count", "age__sum", "age__avg"},
+		Having:     `{"or":[{"count__gte":"2"},{"age__sum__gt":"45"}]}`,
+		Query:      `{"age__gte":"20"}`,
+		OrderBy:    []string{"-count", "created_at__year"},
+	})
+	require.NoError(t, err)
+
+	rows, err := QueryAggregate[yearStat](context.Background(), drv, "users", selectors)
+	require.NoError(t, err)
+	require.Equal(t, []yearStat{
+		{Year: 2023, Count: 2, Sum: 50, Avg: 25},
+		{Year: 2021, Count: 1, Sum: 50, Avg: 50},
+	}, rows)
+}
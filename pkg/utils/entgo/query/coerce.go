@@ -0,0 +1,362 @@
+package entgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/sql"
+	"entgo.io/ent/schema/field"
+
+	"github.com/heyinLab/common/pkg/utils/entgo/annotation"
+	"github.com/heyinLab/common/pkg/utils/stringcase"
+)
+
+// FieldType 过滤值的目标类型，定义在 annotation 包中，schema 可以不依赖本包
+type FieldType = annotation.FieldType
+
+const (
+	FieldTypeString    = annotation.FieldTypeString    // 字符串，不做转换
+	FieldTypeInt       = annotation.FieldTypeInt       // 有符号整数
+	FieldTypeUint      = annotation.FieldTypeUint      // 无符号整数
+	FieldTypeFloat     = annotation.FieldTypeFloat     // 浮点数
+	FieldTypeBool      = annotation.FieldTypeBool      // 布尔值
+	FieldTypeTime      = annotation.FieldTypeTime      // 时间
+	FieldTypeUnixMilli = annotation.FieldTypeUnixMilli // 毫秒时间戳，可传入日期字符串
+	FieldTypeEnum      = annotation.FieldTypeEnum      // 枚举，值必须在登记的枚举值之中
+	FieldTypeArray     = annotation.FieldTypeArray     // PostgreSQL 原生数组，集合操作符按数组生成查询
+)
+
+// FieldTypeAnnotation 在 ent schema 中声明字段的过滤值类型，见 annotation.FieldTypeAnnotation
+type FieldTypeAnnotation = annotation.FieldTypeAnnotation
+
+// 日期字符串支持的格式，按顺序尝试
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	time.DateTime,
+	"2006-01-02T15:04:05",
+	time.DateOnly,
+}
+
+type fieldSpec struct {
+	typ   FieldType
+	enums []string
+}
+
+// FieldRegistry 字段类型登记表，用于将字符串形式的过滤值转换为字段对应的 Go 类型
+//
+// 字段名为数据库列名，日期字符串按本地时区解析。
+type FieldRegistry struct {
+	fields map[string]fieldSpec
+	search SearchOptions
+}
+
+// NewFieldRegistry 创建空的字段类型登记表
+func NewFieldRegistry() *FieldRegistry {
+	return &FieldRegistry{
+		fields: make(map[string]fieldSpec),
+	}
+}
+
+// NewFieldRegistryFromSchema 根据 ent schema（含 Mixin）中声明的字段构建登记表
+//
+// 未声明 id 字段时按 ent 的默认规则登记为整数。
+func NewFieldRegistryFromSchema(schemas ...ent.Interface) *FieldRegistry {
+	r := NewFieldRegistry()
+	for _, schema := range schemas {
+		var fields []ent.Field
+		for _, m := range schema.Mixin() {
+			fields = append(fields, m.Fields()...)
+		}
+		fields = append(fields, schema.Fields()...)
+
+		for _, f := range fields {
+			r.AddField(f)
+		}
+		if _, ok := r.fields["id"]; !ok {
+			r.Add("id", FieldTypeInt)
+		}
+	}
+	return r
+}
+
+// Add 登记字段类型，enums 为枚举字段的可选值
+func (r *FieldRegistry) Add(column string, typ FieldType, enums ...string) *FieldRegistry {
+	r.fields[column] = fieldSpec{typ: typ, enums: enums}
+	return r
+}
+
+// AddField 登记 ent 字段，无法转换的类型（如 JSON、Bytes）将被忽略
+func (r *FieldRegistry) AddField(f ent.Field) *FieldRegistry {
+	d := f.Descriptor()
+	if d == nil || d.Info == nil {
+		return r
+	}
+
+	column := d.StorageKey
+	if column == "" {
+		column = d.Name
+	}
+
+	for _, a := range d.Annotations {
+		if ann, ok := a.(FieldTypeAnnotation); ok {
+			return r.Add(column, ann.Type)
+		}
+	}
+
+	switch t := d.Info.Type; {
+	case t == field.TypeBool:
+		r.Add(column, FieldTypeBool)
+	case t == field.TypeTime:
+		r.Add(column, FieldTypeTime)
+	case t == field.TypeFloat32, t == field.TypeFloat64:
+		r.Add(column, FieldTypeFloat)
+	case t == field.TypeEnum:
+		enums := make([]string, 0, len(d.Enums))
+		for _, e := range d.Enums {
+			enums = append(enums, e.V)
+		}
+		r.Add(column, FieldTypeEnum, enums...)
+	case t == field.TypeString, t == field.TypeUUID:
+		r.Add(column, FieldTypeString)
+	case t.Integer():
+		if strings.HasPrefix(t.String(), "uint") {
+			r.Add(column, FieldTypeUint)
+		} else {
+			r.Add(column, FieldTypeInt)
+		}
+	}
+
+	return r
+}
+
+// WithSearchOptions 返回使用指定全文搜索选项（field__search）的登记表副本，r 为 nil 时返回空登记表
+//
+// 使用示例:
+//
+//	types := entgo.NewFieldRegistryFromSchema(schema.Post{}).
+//	    WithSearchOptions(entgo.WithSearchConfig(entgo.PostgresSearchConfigChinese))
+//	err, whereSelectors, querySelectors := (&entgo.QueryPolicy{Types: types}).BuildQuerySelector(...)
+func (r *FieldRegistry) WithSearchOptions(opts ...SearchOption) *FieldRegistry {
+	c := NewFieldRegistry()
+	if r != nil {
+		for column, spec := range r.fields {
+			c.fields[column] = spec
+		}
+		c.search = r.search
+	}
+	for _, opt := range opts {
+		opt(&c.search)
+	}
+	return c
+}
+
+// searchOptions 返回全文搜索选项
+func (r *FieldRegistry) searchOptions() SearchOptions {
+	if r == nil {
+		return SearchOptions{}
+	}
+	return r.search
+}
+
+// Lookup 查找字段类型，字段名按 snake_case 处理
+func (r *FieldRegistry) Lookup(column string) (FieldType, bool) {
+	spec, ok := r.lookup(column)
+	return spec.typ, ok
+}
+
+func (r *FieldRegistry) lookup(column string) (fieldSpec, bool) {
+	if r == nil {
+		return fieldSpec{}, false
+	}
+	if spec, ok := r.fields[column]; ok {
+		return spec, true
+	}
+	spec, ok := r.fields[stringcase.ToSnakeCase(column)]
+	return spec, ok
+}
+
+// Coerce 将单个过滤值转换为字段对应的 Go 类型，未登记的字段原样返回
+func (r *FieldRegistry) Coerce(column, value string) (any, error) {
+	spec, ok := r.lookup(column)
+	if !ok {
+		return value, nil
+	}
+	return spec.coerce(column, value)
+}
+
+// coerceOp 按操作符转换过滤值，in、not_in、range 的值为 JSON 数组，返回 []any
+//
+// 操作符不需要转换（如 contains、isnull）或字段未登记时，ok 为 false。
+func (r *FieldRegistry) coerceOp(column, op, value string) (v any, ok bool, err error) {
+	spec, found := r.lookup(column)
+	if !found || spec.typ == FieldTypeString || spec.typ == FieldTypeArray {
+		return nil, false, nil
+	}
+
+	switch strings.ToLower(op) {
+	case "", ops[FilterNot], ops[FilterGTE], ops[FilterGT], ops[FilterLTE], ops[FilterLT]:
+		v, err = spec.coerce(column, value)
+		return v, err == nil, err
+
+	case ops[FilterIn], ops[FilterNotIn], ops[FilterRange]:
+		var items []any
+		if err = json.Unmarshal([]byte(value), &items); err != nil {
+			return nil, false, fmt.Errorf("invalid value %q for %s: expected a json array", value, column)
+		}
+		if strings.ToLower(op) == ops[FilterRange] && len(items) != 2 {
+			return nil, false, fmt.Errorf("invalid value %q for %s: range requires 2 values", value, column)
+		}
+
+		values := make([]any, 0, len(items))
+		for _, item := range items {
+			c, err := spec.coerce(column, JsonItemString(item))
+			if err != nil {
+				return nil, false, err
+			}
+			values = append(values, c)
+		}
+		return values, true, nil
+
+	default:
+		return nil, false, nil
+	}
+}
+
+// JsonItemString 将 JSON 数组元素转换回字符串形式
+func JsonItemString(item any) string {
+	switch v := item.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (spec fieldSpec) coerce(column, value string) (any, error) {
+	value = strings.TrimSpace(value)
+
+	var (
+		v   any
+		err error
+	)
+	switch spec.typ {
+	case FieldTypeString:
+		return value, nil
+	case FieldTypeInt:
+		v, err = strconv.ParseInt(value, 10, 64)
+	case FieldTypeUint:
+		v, err = strconv.ParseUint(value, 10, 64)
+	case FieldTypeFloat:
+		v, err = strconv.ParseFloat(value, 64)
+	case FieldTypeBool:
+		v, err = strconv.ParseBool(value)
+	case FieldTypeTime:
+		v, err = parseTime(value)
+	case FieldTypeUnixMilli:
+		if i, e := strconv.ParseInt(value, 10, 64); e == nil {
+			return i, nil
+		}
+		var t time.Time
+		if t, err = parseTime(value); err == nil {
+			v = t.UnixMilli()
+		}
+	case FieldTypeEnum:
+		if !slices.Contains(spec.enums, value) {
+			return nil, fmt.Errorf("invalid value %q for %s: expected one of %s", value, column, strings.Join(spec.enums, ", "))
+		}
+		return value, nil
+	default:
+		return value, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q for %s: expected %s", value, column, spec.typ)
+	}
+	return v, nil
+}
+
+// parseTime 按 timeLayouts 解析日期字符串，不带时区的按本地时区处理
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported time format %q", value)
+}
+
+// makeFieldFilter 构建字段过滤器，普通列的等值和比较查询使用转换后的值
+//
+// 登记为 FieldTypeArray 的列，集合操作符按 PostgreSQL 原生数组生成查询。
+func (r *FieldRegistry) makeFieldFilter(s *sql.Selector, keys []string, value string) *sql.Predicate {
+	if column, op, ok := typedFilterKey(keys); ok && len(value) > 0 {
+		if isCollectionOp(op) {
+			spec, _ := r.lookup(column)
+			return filterCollection(s, sql.P(), op, column, value, spec.typ == FieldTypeArray)
+		}
+
+		v, ok, err := r.coerceOp(column, op, value)
+		if err != nil {
+			s.AddError(err)
+			return nil
+		}
+		if ok {
+			return typedFieldFilter(s, sql.P(), op, column, v)
+		}
+	}
+	return makeFieldFilter(s, keys, value, r.searchOptions())
+}
+
+// typedFilterKey 解析需要转换值的列名和操作符，JSON 字段和日期部分不做转换
+func typedFilterKey(keys []string) (column, op string, ok bool) {
+	if len(keys) == 0 || len(keys) > 2 || len(keys[0]) == 0 || isJsonFieldKey(keys[0]) {
+		return "", "", false
+	}
+	if len(keys) == 2 {
+		op = strings.ToLower(keys[1])
+		if !IsOperator(op) {
+			return "", "", false
+		}
+	}
+	return stringcase.ToSnakeCase(keys[0]), op, true
+}
+
+// typedFieldFilter 使用转换后的值构建等值和比较条件，in、not_in、range 的值为 []any
+func typedFieldFilter(s *sql.Selector, p *sql.Predicate, op, field string, v any) *sql.Predicate {
+	switch op {
+	case "":
+		return p.EQ(s.C(field), v)
+	case ops[FilterNot]:
+		return p.Not().EQ(s.C(field), v)
+	case ops[FilterGTE]:
+		return p.GTE(s.C(field), v)
+	case ops[FilterGT]:
+		return p.GT(s.C(field), v)
+	case ops[FilterLTE]:
+		return p.LTE(s.C(field), v)
+	case ops[FilterLT]:
+		return p.LT(s.C(field), v)
+	case ops[FilterIn]:
+		return p.In(s.C(field), v.([]any)...)
+	case ops[FilterNotIn]:
+		return p.NotIn(s.C(field), v.([]any)...)
+	case ops[FilterRange]:
+		values := v.([]any)
+		return sql.And(
+			sql.GTE(s.C(field), values[0]),
+			sql.LTE(s.C(field), values[1]),
+		)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,138 @@
+package entgo
+
+import (
+	"testing"
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"entgo.io/ent/schema/field"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/heyinLab/common/pkg/utils/query_parser"
+)
+
+type testUserSchema struct{ ent.Schema }
+
+func (testUserSchema) Fields() []ent.Field {
+	return []ent.Field{
+		field.Uint32("id"),
+		field.String("name"),
+		field.Bool("is_enabled"),
+		field.Float("score"),
+		field.Enum("status").Values("ON", "OFF"),
+		field.Time("birthday"),
+		field.Int64("create_time").Annotations(FieldTypeAnnotation{Type: FieldTypeUnixMilli}),
+		field.JSON("meta", map[string]any{}),
+	}
+}
+
+func TestNewFieldRegistryFromSchema(t *testing.T) {
+	r := NewFieldRegistryFromSchema(testUserSchema{})
+
+	for column, want := range map[string]FieldType{
+		"id":          FieldTypeUint,
+		"name":        FieldTypeString,
+		"is_enabled":  FieldTypeBool,
+		"score":       FieldTypeFloat,
+		"status":      FieldTypeEnum,
+		"birthday":    FieldTypeTime,
+		"create_time": FieldTypeUnixMilli,
+		"createTime":  FieldTypeUnixMilli,
+	} {
+		typ, ok := r.Lookup(column)
+		require.True(t, ok, column)
+		require.Equal(t, want, typ, column)
+	}
+
+	_, ok := r.Lookup("meta")
+	require.False(t, ok)
+}
+
+func TestFieldRegistryCoerce(t *testing.T) {
+	r := NewFieldRegistryFromSchema(testUserSchema{})
+
+	millis := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local).UnixMilli()
+
+	testcases := []struct {
+		column string
+		value  string
+		want   any
+	}{
+		{"id", "42", uint64(42)},
+		{"is_enabled", "True", true},
+		{"score", "1.5", 1.5},
+		{"status", "ON", "ON"},
+		{"name", "42", "42"},
+		{"create_time", "2024-01-01", millis},
+		{"create_time", "2024-01-01 00:00:00", millis},
+		{"create_time", "1704067200000", int64(1704067200000)},
+		{"birthday", "2024-01-01T00:00:00Z", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"unknown", "x", "x"},
+	}
+	for _, tc := range testcases {
+		v, err := r.Coerce(tc.column, tc.value)
+		require.NoError(t, err, tc.column)
+		require.Equal(t, tc.want, v, tc.column)
+	}
+
+	for column, value := range map[string]string{
+		"id":          "-1",
+		"is_enabled":  "yes",
+		"status":      "DELETED",
+		"create_time": "2024/01/01",
+	} {
+		_, err := r.Coerce(column, value)
+		require.Error(t, err, column)
+	}
+}
+
+func TestFieldRegistryFilterExpr(t *testing.T) {
+	r := NewFieldRegistryFromSchema(testUserSchema{})
+
+	expr, err := query_parser.ParseFilterExprJSON(`{"id__in":["1",2],"create_time__range":["2024-01-01","2024-01-02"],"is_enabled":"false","name__contains":"1"}`)
+	require.NoError(t, err)
+
+	s := sql.Dialect(dialect.Postgres).Select("*").From(sql.Table("users"))
+	r.BuildFilterExprSelector(expr)(s)
+	query, args := s.Query()
+	require.NoError(t, s.Err())
+	require.Equal(t, `SELECT * FROM "users" WHERE "users"."id" IN ($1, $2) AND ("users"."create_time" >= $3 AND "users"."create_time" <= $4) AND NOT "users"."is_enabled" AND "users"."name" LIKE $5`, query)
+	require.Equal(t, []any{
+		uint64(1), uint64(2),
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local).UnixMilli(),
+		time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local).UnixMilli(),
+		"%1%",
+	}, args)
+
+	// 转换失败的错误通过 Err 返回
+	expr, err = query_parser.ParseFilterExprJSON(`{"id":"abc"}`)
+	require.NoError(t, err)
+
+	s = sql.Dialect(dialect.Postgres).Select("*").From(sql.Table("users"))
+	r.BuildFilterExprSelector(expr)(s)
+	require.Error(t, s.Err())
+}
+
+func TestQueryPolicyCoerce(t *testing.T) {
+	policy := &QueryPolicy{Types: NewFieldRegistryFromSchema(testUserSchema{})}
+
+	err, whereSelectors := policy.BuildFilterSelector(`{"createTime__gte":"2024-01-01"}`, "")
+	require.NoError(t, err)
+
+	s := sql.Dialect(dialect.MySQL).Select("*").From(sql.Table("users"))
+	for _, fnc := range whereSelectors {
+		fnc(s)
+	}
+	query, args := s.Query()
+	require.Equal(t, "SELECT * FROM `users` WHERE `users`.`create_time` >= ?", query)
+	require.Equal(t, []any{time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local).UnixMilli()}, args)
+
+	err, _ = policy.BuildFilterSelector(`{"status__in":["ON","DELETED"]}`, "")
+	require.Error(t, err)
+	e := errors.FromError(err)
+	require.Equal(t, "INVALID_PARAMETER", e.Reason)
+	require.Equal(t, "status", e.Metadata["field"])
+}
@@ -0,0 +1,269 @@
+package entgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+)
+
+// 数组长度操作符对应的比较运算
+var lenOps = map[string]sql.Op{
+	ops[FilterLen]:    sql.OpEQ,
+	ops[FilterLenGT]:  sql.OpGT,
+	ops[FilterLenGTE]: sql.OpGTE,
+	ops[FilterLenLT]:  sql.OpLT,
+	ops[FilterLenLTE]: sql.OpLTE,
+}
+
+const (
+	// mysqlArrayElementType JSON_TABLE 中数组元素的类型，按二进制比较，与 JSON_CONTAINS 一致区分大小写
+	mysqlArrayElementType = "VARCHAR(255) COLLATE utf8mb4_bin"
+	// sqliteArrayElement json_each 元素的文本，json_each 中布尔值为 1、0
+	sqliteArrayElement = "CASE WHEN json_each.type IN ('true', 'false') THEN json_each.type ELSE CAST(json_each.value AS TEXT) END"
+)
+
+// isCollectionOp 是否为数组、JSON 对象的集合操作符
+func isCollectionOp(op string) bool {
+	switch strings.ToLower(op) {
+	case ops[FilterHas], ops[FilterHasAny], ops[FilterHasAll], ops[FilterOverlap],
+		ops[FilterHasKey], ops[FilterHasKeys], ops[FilterHasAnyKeys]:
+		return true
+	default:
+		_, ok := lenOps[strings.ToLower(op)]
+		return ok
+	}
+}
+
+// filterCollection 数组和 JSON 对象的集合操作
+//
+// 默认按 JSON 数组（如 mixin.Tag 的 tags 字段）处理，nativeArray 为 true 时按 PostgreSQL 原生数组处理。
+// 值无效或数据库不支持时，错误通过 sql.Selector 的 Err 返回。
+func filterCollection(s *sql.Selector, p *sql.Predicate, op, field, value string, nativeArray bool) *sql.Predicate {
+	op = strings.ToLower(op)
+
+	switch op {
+	case ops[FilterHas]:
+		return filterArrayContains(s, p, field, []any{value}, true, nativeArray)
+
+	case ops[FilterHasAll], ops[FilterHasAny], ops[FilterOverlap]:
+		values, err := CollectionValues(field, value)
+		if err != nil {
+			s.AddError(err)
+			return nil
+		}
+		return filterArrayContains(s, p, field, values, op == ops[FilterHasAll], nativeArray)
+
+	case ops[FilterHasKey], ops[FilterHasKeys], ops[FilterHasAnyKeys]:
+		if nativeArray {
+			s.AddError(fmt.Errorf("operator %q is not supported for array field %s", op, field))
+			return nil
+		}
+		if op == ops[FilterHasKey] {
+			return filterHasKeys(s, p, field, []string{value}, true)
+		}
+		values, err := CollectionValues(field, value)
+		if err != nil {
+			s.AddError(err)
+			return nil
+		}
+		keys := make([]string, 0, len(values))
+		for _, v := range values {
+			keys = append(keys, JsonItemString(v))
+		}
+		return filterHasKeys(s, p, field, keys, op == ops[FilterHasKeys])
+
+	default:
+		cmp, ok := lenOps[op]
+		if !ok {
+			return nil
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			s.AddError(fmt.Errorf("invalid value %q for %s: expected int", value, field))
+			return nil
+		}
+		return filterArrayLen(s, p, field, cmp, n, nativeArray)
+	}
+}
+
+// CollectionValues 解析集合操作符的值，必须为非空的 JSON 数组
+func CollectionValues(field, value string) ([]any, error) {
+	var values []any
+	if err := json.Unmarshal([]byte(value), &values); err != nil || len(values) == 0 {
+		return nil, fmt.Errorf("invalid value %q for %s: expected a non-empty json array", value, field)
+	}
+	return values, nil
+}
+
+// filterArrayContains 数组包含，all 为 true 时包含全部元素，否则包含任意一个元素
+// 查询值没有类型，JSON 数组的元素按文本比较，"5" 与 5 都匹配数组中的 5 或 "5"，各数据库结果一致
+// PostgreSQL: WHERE (SELECT COUNT(DISTINCT value) FROM jsonb_array_elements_text("tags") WHERE value IN ('a', 'b')) = 2
+// 或 WHERE EXISTS (SELECT 1 FROM jsonb_array_elements_text("tags") WHERE value IN ('a', 'b'))
+// PostgreSQL 原生数组: WHERE "tags" @> ARRAY['a', 'b'] 或 WHERE "tags" && ARRAY['a', 'b']
+// MySQL: 同 PostgreSQL，元素来自 JSON_TABLE(`tags`, '$[*]' COLUMNS (value ... PATH '$')) AS elements
+// SQLite: 同 PostgreSQL，元素来自 json_each(`tags`)，布尔值转换为 'true'、'false'
+func filterArrayContains(s *sql.Selector, p *sql.Predicate, field string, values []any, all, nativeArray bool) *sql.Predicate {
+	p.Append(func(b *sql.Builder) {
+		var elements func()
+		var value string
+		switch d := s.Builder.Dialect(); {
+		case d == dialect.Postgres && nativeArray:
+			b.Ident(s.C(field))
+			if all {
+				b.WriteString(" @> ")
+			} else {
+				b.WriteString(" && ")
+			}
+			b.WriteString("ARRAY[").Args(values...).WriteString("]")
+			return
+
+		case d == dialect.Postgres:
+			elements = func() {
+				b.WriteString("jsonb_array_elements_text(").Ident(s.C(field)).WriteString(")")
+			}
+			value = "value"
+
+		case d == dialect.MySQL:
+			elements = func() {
+				b.WriteString("JSON_TABLE(").Ident(s.C(field)).
+					WriteString(", '$[*]' COLUMNS (value " + mysqlArrayElementType + " PATH '$')) AS elements")
+			}
+			value = "elements.value"
+
+		case d == dialect.SQLite:
+			elements = func() {
+				b.WriteString("json_each(").Ident(s.C(field)).WriteString(")")
+			}
+			value = sqliteArrayElement
+
+		default:
+			b.AddError(fmt.Errorf("array operators are not supported for dialect %q", d))
+			return
+		}
+
+		args := stringArgs(values)
+		if all {
+			b.WriteString("(SELECT COUNT(DISTINCT " + value + ") FROM ")
+			elements()
+			b.WriteString(" WHERE " + value + " IN (").Args(args...).
+				WriteString(")) = ").WriteString(strconv.Itoa(distinctCount(args)))
+		} else {
+			b.WriteString("EXISTS (SELECT 1 FROM ")
+			elements()
+			b.WriteString(" WHERE " + value + " IN (").Args(args...).WriteString("))")
+		}
+	})
+	return p
+}
+
+// filterArrayLen 数组长度比较
+// PostgreSQL: WHERE jsonb_array_length("tags") > 2，原生数组: WHERE cardinality("tags") > 2
+// MySQL: WHERE JSON_LENGTH(`tags`) > 2
+// SQLite: WHERE json_array_length(`tags`) > 2
+func filterArrayLen(s *sql.Selector, p *sql.Predicate, field string, cmp sql.Op, n int, nativeArray bool) *sql.Predicate {
+	p.Append(func(b *sql.Builder) {
+		switch d := s.Builder.Dialect(); {
+		case d == dialect.Postgres && nativeArray:
+			b.WriteString("cardinality(")
+		case d == dialect.Postgres:
+			b.WriteString("jsonb_array_length(")
+		case d == dialect.MySQL:
+			b.WriteString("JSON_LENGTH(")
+		case d == dialect.SQLite:
+			b.WriteString("json_array_length(")
+		default:
+			b.AddError(fmt.Errorf("array operators are not supported for dialect %q", d))
+			return
+		}
+		b.Ident(s.C(field)).WriteString(")").WriteOp(cmp).Arg(n)
+	})
+	return p
+}
+
+// filterHasKeys JSON 对象包含键，all 为 true 时包含全部键，否则包含任意一个键
+// PostgreSQL: WHERE "meta" ? 'a'、WHERE "meta" ?& ARRAY['a', 'b'] 或 WHERE "meta" ?| ARRAY['a', 'b']
+// MySQL: WHERE JSON_CONTAINS_PATH(`meta`, 'all', '$."a"', '$."b"')
+// SQLite: WHERE (json_type(`meta`, '$."a"') IS NOT NULL AND json_type(`meta`, '$."b"') IS NOT NULL)
+func filterHasKeys(s *sql.Selector, p *sql.Predicate, field string, keys []string, all bool) *sql.Predicate {
+	p.Append(func(b *sql.Builder) {
+		switch d := s.Builder.Dialect(); d {
+		case dialect.Postgres:
+			b.Ident(s.C(field))
+			switch {
+			case len(keys) == 1:
+				b.WriteString(" ? ").Arg(keys[0])
+			case all:
+				b.WriteString(" ?& ARRAY[").Args(anyArgs(keys)...).WriteString("]")
+			default:
+				b.WriteString(" ?| ARRAY[").Args(anyArgs(keys)...).WriteString("]")
+			}
+
+		case dialect.MySQL:
+			b.WriteString("JSON_CONTAINS_PATH(").Ident(s.C(field))
+			if all {
+				b.WriteString(", 'all'")
+			} else {
+				b.WriteString(", 'one'")
+			}
+			for _, key := range keys {
+				b.Comma().Arg(jsonKeyPath(key))
+			}
+			b.WriteString(")")
+
+		case dialect.SQLite:
+			b.WriteString("(")
+			for i, key := range keys {
+				if i > 0 {
+					if all {
+						b.WriteString(" AND ")
+					} else {
+						b.WriteString(" OR ")
+					}
+				}
+				b.WriteString("json_type(").Ident(s.C(field)).Comma().Arg(jsonKeyPath(key)).WriteString(") IS NOT NULL")
+			}
+			b.WriteString(")")
+
+		default:
+			b.AddError(fmt.Errorf("json key operators are not supported for dialect %q", d))
+		}
+	})
+	return p
+}
+
+// jsonKeyPath JSON 路径，键名使用双引号包裹：$."key"
+func jsonKeyPath(key string) string {
+	return "$." + strconv.Quote(key)
+}
+
+// stringArgs 将元素转换为字符串参数
+func stringArgs(values []any) []any {
+	args := make([]any, 0, len(values))
+	for _, v := range values {
+		args = append(args, JsonItemString(v))
+	}
+	return args
+}
+
+// anyArgs 将字符串转换为参数
+func anyArgs(values []string) []any {
+	args := make([]any, 0, len(values))
+	for _, v := range values {
+		args = append(args, v)
+	}
+	return args
+}
+
+// distinctCount 不重复的元素个数
+func distinctCount(values []any) int {
+	seen := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		data, _ := json.Marshal(v)
+		seen[string(data)] = struct{}{}
+	}
+	return len(seen)
+}
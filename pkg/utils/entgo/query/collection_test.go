@@ -0,0 +1,148 @@
+package entgo
+
+import (
+	"testing"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"github.com/stretchr/testify/require"
+
+	"github.com/heyinLab/common/pkg/utils/query_parser"
+)
+
+func TestFilterCollection(t *testing.T) {
+	testcases := []struct {
+		name    string
+		dialect string
+		query   string
+		sql     string
+		args    []any
+	}{
+		{"PostgreSQL_Has", dialect.Postgres, `{"tags__has":"go"}`, `SELECT * FROM "users" WHERE (SELECT COUNT(DISTINCT value) FROM jsonb_array_elements_text("users"."tags") WHERE value IN ($1)) = 1`, []any{"go"}},
+		{"PostgreSQL_HasNumber", dialect.Postgres, `{"tags__has":5}`, `SELECT * FROM "users" WHERE (SELECT COUNT(DISTINCT value) FROM jsonb_array_elements_text("users"."tags") WHERE value IN ($1)) = 1`, []any{"5"}},
+		{"PostgreSQL_HasAll", dialect.Postgres, `{"tags__has_all":["go","db","go"]}`, `SELECT * FROM "users" WHERE (SELECT COUNT(DISTINCT value) FROM jsonb_array_elements_text("users"."tags") WHERE value IN ($1, $2, $3)) = 2`, []any{"go", "db", "go"}},
+		{"PostgreSQL_HasAllNumbers", dialect.Postgres, `{"tags__has_all":[1,2.5]}`, `SELECT * FROM "users" WHERE (SELECT COUNT(DISTINCT value) FROM jsonb_array_elements_text("users"."tags") WHERE value IN ($1, $2)) = 2`, []any{"1", "2.5"}},
+		{"PostgreSQL_HasAny", dialect.Postgres, `{"tags__has_any":["go","db"]}`, `SELECT * FROM "users" WHERE EXISTS (SELECT 1 FROM jsonb_array_elements_text("users"."tags") WHERE value IN ($1, $2))`, []any{"go", "db"}},
+		{"PostgreSQL_OverlapNumbers", dialect.Postgres, `{"tags__overlap":[1,"go",true]}`, `SELECT * FROM "users" WHERE EXISTS (SELECT 1 FROM jsonb_array_elements_text("users"."tags") WHERE value IN ($1, $2, $3))`, []any{"1", "go", "true"}},
+		{"PostgreSQL_LenGT", dialect.Postgres, `{"tags__len_gt":"2"}`, `SELECT * FROM "users" WHERE jsonb_array_length("users"."tags") > $1`, []any{2}},
+		{"PostgreSQL_HasKey", dialect.Postgres, `{"meta__has_key":"level"}`, `SELECT * FROM "users" WHERE "users"."meta" ? $1`, []any{"level"}},
+		{"PostgreSQL_HasKeys", dialect.Postgres, `{"meta__has_keys":["a","b"]}`, `SELECT * FROM "users" WHERE "users"."meta" ?& ARRAY[$1, $2]`, []any{"a", "b"}},
+		{"PostgreSQL_HasAnyKeys", dialect.Postgres, `{"meta__has_any_keys":["a","b"]}`, `SELECT * FROM "users" WHERE "users"."meta" ?| ARRAY[$1, $2]`, []any{"a", "b"}},
+
+		{"MySQL_Has", dialect.MySQL, `{"tags__has":"go"}`, "SELECT * FROM `users` WHERE (SELECT COUNT(DISTINCT elements.value) FROM JSON_TABLE(`users`.`tags`, '$[*]' COLUMNS (value VARCHAR(255) COLLATE utf8mb4_bin PATH '$')) AS elements WHERE elements.value IN (?)) = 1", []any{"go"}},
+		{"MySQL_HasNumber", dialect.MySQL, `{"tags__has":5}`, "SELECT * FROM `users` WHERE (SELECT COUNT(DISTINCT elements.value) FROM JSON_TABLE(`users`.`tags`, '$[*]' COLUMNS (value VARCHAR(255) COLLATE utf8mb4_bin PATH '$')) AS elements WHERE elements.value IN (?)) = 1", []any{"5"}},
+		{"MySQL_HasAll", dialect.MySQL, `{"tags__has_all":["go","db","go"]}`, "SELECT * FROM `users` WHERE (SELECT COUNT(DISTINCT elements.value) FROM JSON_TABLE(`users`.`tags`, '$[*]' COLUMNS (value VARCHAR(255) COLLATE utf8mb4_bin PATH '$')) AS elements WHERE elements.value IN (?, ?, ?)) = 2", []any{"go", "db", "go"}},
+		{"MySQL_Overlap", dialect.MySQL, `{"tags__overlap":[1,"go",true]}`, "SELECT * FROM `users` WHERE EXISTS (SELECT 1 FROM JSON_TABLE(`users`.`tags`, '$[*]' COLUMNS (value VARCHAR(255) COLLATE utf8mb4_bin PATH '$')) AS elements WHERE elements.value IN (?, ?, ?))", []any{"1", "go", "true"}},
+		{"MySQL_Len", dialect.MySQL, `{"tags__len":"0"}`, "SELECT * FROM `users` WHERE JSON_LENGTH(`users`.`tags`) = ?", []any{0}},
+		{"MySQL_HasKeys", dialect.MySQL, `{"meta__has_keys":["a","b"]}`, "SELECT * FROM `users` WHERE JSON_CONTAINS_PATH(`users`.`meta`, 'all', ?, ?)", []any{`$."a"`, `$."b"`}},
+		{"MySQL_HasAnyKeys", dialect.MySQL, `{"meta__has_any_keys":["a"]}`, "SELECT * FROM `users` WHERE JSON_CONTAINS_PATH(`users`.`meta`, 'one', ?)", []any{`$."a"`}},
+
+		{"SQLite_Has", dialect.SQLite, `{"tags__has":"go"}`, "SELECT * FROM `users` WHERE (SELECT COUNT(DISTINCT CASE WHEN json_each.type IN ('true', 'false') THEN json_each.type ELSE CAST(json_each.value AS TEXT) END) FROM json_each(`users`.`tags`) WHERE CASE WHEN json_each.type IN ('true', 'false') THEN json_each.type ELSE CAST(json_each.value AS TEXT) END IN (?)) = 1", []any{"go"}},
+		{"SQLite_HasNumber", dialect.SQLite, `{"tags__has":5}`, "SELECT * FROM `users` WHERE (SELECT COUNT(DISTINCT CASE WHEN json_each.type IN ('true', 'false') THEN json_each.type ELSE CAST(json_each.value AS TEXT) END) FROM json_each(`users`.`tags`) WHERE CASE WHEN json_each.type IN ('true', 'false') THEN json_each.type ELSE CAST(json_each.value AS TEXT) END IN (?)) = 1", []any{"5"}},
+		{"SQLite_Overlap", dialect.SQLite, `{"tags__overlap":[1,"go",true]}`, "SELECT * FROM `users` WHERE EXISTS (SELECT 1 FROM json_each(`users`.`tags`) WHERE CASE WHEN json_each.type IN ('true', 'false') THEN json_each.type ELSE CAST(json_each.value AS TEXT) END IN (?, ?, ?))", []any{"1", "go", "true"}},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err, selectors := BuildFilterSelector(tc.query, "")
+			require.NoError(t, err)
+
+			s := sql.Dialect(tc.dialect).Select("*").From(sql.Table("users"))
+			for _, fnc := range selectors {
+				fnc(s)
+			}
+			query, args := s.Query()
+			require.NoError(t, s.Err())
+			require.Equal(t, tc.sql, query)
+			require.Equal(t, tc.args, args)
+		})
+	}
+}
+
+// TestFilterCollectionDialects 各数据库的数组元素按同样的文本参数比较
+func TestFilterCollectionDialects(t *testing.T) {
+	for _, query := range []string{
+		`{"tags__has":"5"}`,
+		`{"tags__has":5}`,
+		`{"tags__has_all":["go",1.5,true]}`,
+		`{"tags__has_any":[5,"5"]}`,
+		`{"tags__overlap":[false,"go"]}`,
+	} {
+		err, selectors := BuildFilterSelector(query, "")
+		require.NoError(t, err)
+
+		var expected []any
+		for _, d := range []string{dialect.Postgres, dialect.MySQL, dialect.SQLite} {
+			s := sql.Dialect(d).Select("*").From(sql.Table("users"))
+			for _, fnc := range selectors {
+				fnc(s)
+			}
+			_, args := s.Query()
+			require.NoError(t, s.Err())
+			if expected == nil {
+				expected = args
+			}
+			require.Equal(t, expected, args, "%s %s", d, query)
+			for _, arg := range args {
+				require.IsType(t, "", arg, "%s %s", d, query)
+			}
+		}
+	}
+}
+
+func TestFilterCollectionNativeArray(t *testing.T) {
+	r := NewFieldRegistry().Add("tags", FieldTypeArray)
+
+	build := func(query string) (string, []any, error) {
+		expr, err := query_parser.ParseFilterExprJSON(query)
+		require.NoError(t, err)
+
+		s := sql.Dialect(dialect.Postgres).Select("*").From(sql.Table("users"))
+		r.BuildFilterExprSelector(expr)(s)
+		q, args := s.Query()
+		return q, args, s.Err()
+	}
+
+	query, args, err := build(`{"tags__has":"go"}`)
+	require.NoError(t, err)
+	require.Equal(t, `SELECT * FROM "users" WHERE "users"."tags" @> ARRAY[$1]`, query)
+	require.Equal(t, []any{"go"}, args)
+
+	query, _, err = build(`{"tags__overlap":["go","db"]}`)
+	require.NoError(t, err)
+	require.Equal(t, `SELECT * FROM "users" WHERE "users"."tags" && ARRAY[$1, $2]`, query)
+
+	query, _, err = build(`{"tags__len_lte":"3"}`)
+	require.NoError(t, err)
+	require.Equal(t, `SELECT * FROM "users" WHERE cardinality("users"."tags") <= $1`, query)
+
+	_, _, err = build(`{"tags__has_key":"go"}`)
+	require.Error(t, err)
+}
+
+func TestFilterCollectionInvalid(t *testing.T) {
+	for _, query := range []string{
+		`{"tags__has_any":"go"}`,
+		`{"tags__has_all":[]}`,
+		`{"tags__len":"x"}`,
+	} {
+		err, selectors := BuildFilterSelector(query, "")
+		require.NoError(t, err)
+
+		s := sql.Dialect(dialect.MySQL).Select("*").From(sql.Table("users"))
+		for _, fnc := range selectors {
+			fnc(s)
+		}
+		_, _ = s.Query()
+		require.Error(t, s.Err(), query)
+	}
+
+	// 不支持的数据库
+	err, selectors := BuildFilterSelector(`{"tags__has":"go"}`, "")
+	require.NoError(t, err)
+	s := sql.Dialect(DialectSQLServer).Select("*").From(sql.Table("users"))
+	for _, fnc := range selectors {
+		fnc(s)
+	}
+	_, _ = s.Query()
+	require.Error(t, s.Err())
+}
@@ -0,0 +1,421 @@
+package entgo
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"entgo.io/ent/dialect/sql"
+
+	paging "github.com/heyinLab/common/pkg/utils/pagination"
+	"github.com/heyinLab/common/pkg/utils/stringcase"
+)
+
+var (
+	// ErrInvalidCursor 游标格式错误、签名不匹配或与当前排序条件不一致
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// CursorOption 游标编解码器选项
+type CursorOption func(*CursorCodec)
+
+// WithCursorKeyField 设置保证排序唯一的主键列，默认为 id
+//
+// 排序条件中不包含该列时，会以升序追加到排序条件末尾。
+func WithCursorKeyField(field string) CursorOption {
+	return func(c *CursorCodec) {
+		c.keyField = field
+	}
+}
+
+// CursorCodec 游标编解码器，使用 HMAC-SHA256 对游标签名，防止客户端篡改
+type CursorCodec struct {
+	key      []byte
+	keyField string
+}
+
+// NewCursorCodec 创建游标编解码器，key 为签名密钥，不能为空
+func NewCursorCodec(key []byte, opts ...CursorOption) *CursorCodec {
+	if len(key) == 0 {
+		panic("entgo: cursor signing key is empty")
+	}
+
+	c := &CursorCodec{
+		key:      key,
+		keyField: "id",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// cursorOrder 排序列
+type cursorOrder struct {
+	column string
+	desc   bool
+}
+
+// cursorValue 带类型标记的游标值，避免 JSON 数字丢失精度
+type cursorValue struct {
+	T string `json:"t"`
+	V string `json:"v"`
+}
+
+// cursorPayload 游标内容
+type cursorPayload struct {
+	Order    string        `json:"o"`           // 排序条件，防止游标用于其他排序
+	Values   []cursorValue `json:"v"`           // 边界行的排序列的值
+	Backward bool          `json:"b,omitempty"` // 是否向前翻页
+}
+
+// CursorQuery 游标分页查询，由 CursorCodec.Parse 创建
+type CursorQuery struct {
+	codec    *CursorCodec
+	orders   []cursorOrder
+	pageSize int
+	values   []any
+	backward bool
+}
+
+// CursorPageInfo 游标分页的分页信息
+type CursorPageInfo struct {
+	PageSize   int32  `json:"pageSize"`
+	HasNext    bool   `json:"hasNext"`
+	HasPrev    bool   `json:"hasPrev"`
+	NextCursor string `json:"nextCursor,omitempty"` // 下一页游标，没有下一页时为空
+	PrevCursor string `json:"prevCursor,omitempty"` // 上一页游标，没有上一页时为空
+}
+
+// Parse 解析游标并创建游标分页查询，cursor 为空时查询第一页
+//
+// orderBys 的格式与 BuildOrderSelector 一致，为空时按 defaultOrderField 降序。
+// 排序列的值不能为 NULL。
+//
+// 使用示例:
+//
+//	err, q := codec.Parse(req.Cursor, req.PageSize, req.OrderBy, "create_time")
+//	rows, err := client.User.Query().Where(q.Selector()).All(ctx)
+//	rows, pageInfo, err := entgo.CursorPageOf(q, rows)
+func (c *CursorCodec) Parse(cursor string, pageSize int32, orderBys []string, defaultOrderField string) (error, *CursorQuery) {
+	if pageSize < 1 {
+		pageSize = paging.DefaultPageSize
+	}
+
+	q := &CursorQuery{
+		codec:    c,
+		orders:   c.cursorOrders(orderBys, defaultOrderField),
+		pageSize: int(pageSize),
+	}
+
+	if cursor == "" {
+		return nil, q
+	}
+
+	payload, err := c.decode(cursor)
+	if err != nil {
+		return err, nil
+	}
+	if payload.Order != q.orderKey() || len(payload.Values) != len(q.orders) {
+		return ErrInvalidCursor, nil
+	}
+
+	q.values = make([]any, 0, len(payload.Values))
+	for _, v := range payload.Values {
+		value, err := v.value()
+		if err != nil {
+			return ErrInvalidCursor, nil
+		}
+		q.values = append(q.values, value)
+	}
+	q.backward = payload.Backward
+
+	return nil, q
+}
+
+// cursorOrders 解析排序条件并追加主键列
+func (c *CursorCodec) cursorOrders(orderBys []string, defaultOrderField string) []cursorOrder {
+	var orders []cursorOrder
+	for _, v := range orderBys {
+		desc := strings.HasPrefix(v, "-")
+		field := strings.TrimPrefix(v, "-")
+		if len(field) == 0 {
+			continue
+		}
+		orders = append(orders, cursorOrder{column: stringcase.ToSnakeCase(field), desc: desc})
+	}
+	if len(orders) == 0 && defaultOrderField != "" {
+		orders = append(orders, cursorOrder{column: defaultOrderField, desc: true})
+	}
+
+	if !slices.ContainsFunc(orders, func(o cursorOrder) bool { return o.column == c.keyField }) {
+		orders = append(orders, cursorOrder{column: c.keyField})
+	}
+	return orders
+}
+
+// Selector 返回排序、游标定位和 LIMIT 的选择器，会多查询一行用于判断是否还有更多数据
+func (q *CursorQuery) Selector() func(s *sql.Selector) {
+	return func(s *sql.Selector) {
+		if len(q.values) > 0 {
+			s.Where(q.seekPredicate(s))
+		}
+
+		for _, o := range q.orders {
+			// 向前翻页时反向排序，结果由 CursorPageOf 恢复为原顺序
+			if o.desc != q.backward {
+				s.OrderBy(sql.Desc(s.C(o.column)))
+			} else {
+				s.OrderBy(sql.Asc(s.C(o.column)))
+			}
+		}
+
+		s.Limit(q.pageSize + 1)
+	}
+}
+
+// seekPredicate 构建游标定位条件
+//
+// 所有列的排序方向一致时使用 (a, b) > (?, ?) 的行比较，否则展开为
+// a > ? OR (a = ? AND b < ?) 的形式。
+func (q *CursorQuery) seekPredicate(s *sql.Selector) *sql.Predicate {
+	// greater 表示该列需要取大于游标值的行
+	greater := func(o cursorOrder) bool { return o.desc == q.backward }
+
+	uniform := true
+	for _, o := range q.orders[1:] {
+		if greater(o) != greater(q.orders[0]) {
+			uniform = false
+			break
+		}
+	}
+
+	if uniform {
+		columns := make([]string, 0, len(q.orders))
+		for _, o := range q.orders {
+			columns = append(columns, s.C(o.column))
+		}
+		if greater(q.orders[0]) {
+			return sql.CompositeGT(columns, q.values...)
+		}
+		return sql.CompositeLT(columns, q.values...)
+	}
+
+	ors := make([]*sql.Predicate, 0, len(q.orders))
+	for i, o := range q.orders {
+		ands := make([]*sql.Predicate, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, sql.EQ(s.C(q.orders[j].column), q.values[j]))
+		}
+		if greater(o) {
+			ands = append(ands, sql.GT(s.C(o.column), q.values[i]))
+		} else {
+			ands = append(ands, sql.LT(s.C(o.column), q.values[i]))
+		}
+		ors = append(ors, sql.And(ands...))
+	}
+	return sql.Or(ors...)
+}
+
+// orderKey 排序条件的字符串形式
+func (q *CursorQuery) orderKey() string {
+	keys := make([]string, 0, len(q.orders))
+	for _, o := range q.orders {
+		if o.desc {
+			keys = append(keys, "-"+o.column)
+		} else {
+			keys = append(keys, o.column)
+		}
+	}
+	return strings.Join(keys, ",")
+}
+
+// CursorPageOf 处理按 CursorQuery.Selector 查询到的结果，返回当前页的数据和分页信息
+//
+// 排序列的值按 json 标签（ent 生成的结构体即为列名）或字段名的 snake_case 从行中读取。
+func CursorPageOf[T any](q *CursorQuery, rows []T) ([]T, *CursorPageInfo, error) {
+	more := len(rows) > q.pageSize
+	if more {
+		rows = rows[:q.pageSize]
+	}
+	if q.backward {
+		slices.Reverse(rows)
+	}
+
+	info := &CursorPageInfo{PageSize: int32(q.pageSize)}
+	if q.backward {
+		info.HasPrev = more
+		info.HasNext = true
+	} else {
+		info.HasPrev = len(q.values) > 0
+		info.HasNext = more
+	}
+
+	if len(rows) == 0 {
+		return rows, info, nil
+	}
+
+	var err error
+	if info.HasNext {
+		if info.NextCursor, err = q.encodeRow(rows[len(rows)-1], false); err != nil {
+			return nil, nil, err
+		}
+	}
+	if info.HasPrev {
+		if info.PrevCursor, err = q.encodeRow(rows[0], true); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return rows, info, nil
+}
+
+// encodeRow 读取行中排序列的值并编码为游标
+func (q *CursorQuery) encodeRow(row any, backward bool) (string, error) {
+	payload := cursorPayload{
+		Order:    q.orderKey(),
+		Values:   make([]cursorValue, 0, len(q.orders)),
+		Backward: backward,
+	}
+	for _, o := range q.orders {
+		v, err := rowValue(row, o.column)
+		if err != nil {
+			return "", err
+		}
+		cv, err := newCursorValue(v)
+		if err != nil {
+			return "", fmt.Errorf("cursor column %s: %w", o.column, err)
+		}
+		payload.Values = append(payload.Values, cv)
+	}
+	return q.codec.encode(payload)
+}
+
+func (c *CursorCodec) encode(payload cursorPayload) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data) + "." +
+		base64.RawURLEncoding.EncodeToString(c.sign(data)), nil
+}
+
+func (c *CursorCodec) decode(cursor string) (*cursorPayload, error) {
+	encoded, signature, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, c.sign(data)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var payload cursorPayload
+	if err = json.Unmarshal(data, &payload); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &payload, nil
+}
+
+func (c *CursorCodec) sign(data []byte) []byte {
+	h := hmac.New(sha256.New, c.key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// rowValue 按列名读取结构体字段的值，指针会被解引用
+func rowValue(row any, column string) (any, error) {
+	v := reflect.ValueOf(row)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, fmt.Errorf("cursor row is nil")
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cursor row must be a struct, got %s", v.Kind())
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			name = stringcase.ToSnakeCase(f.Name)
+		}
+		if name != column {
+			continue
+		}
+
+		fv := v.Field(i)
+		for fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				return nil, fmt.Errorf("cursor column %s is null", column)
+			}
+			fv = fv.Elem()
+		}
+		return fv.Interface(), nil
+	}
+
+	return nil, fmt.Errorf("cursor column %s not found in %s", column, t)
+}
+
+func newCursorValue(v any) (cursorValue, error) {
+	switch x := v.(type) {
+	case time.Time:
+		return cursorValue{T: "t", V: x.Format(time.RFC3339Nano)}, nil
+	case fmt.Stringer:
+		return cursorValue{T: "s", V: x.String()}, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cursorValue{T: "i", V: strconv.FormatInt(rv.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cursorValue{T: "u", V: strconv.FormatUint(rv.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return cursorValue{T: "f", V: strconv.FormatFloat(rv.Float(), 'g', -1, 64)}, nil
+	case reflect.Bool:
+		return cursorValue{T: "b", V: strconv.FormatBool(rv.Bool())}, nil
+	case reflect.String:
+		return cursorValue{T: "s", V: rv.String()}, nil
+	default:
+		return cursorValue{}, fmt.Errorf("unsupported cursor value type %T", v)
+	}
+}
+
+func (v cursorValue) value() (any, error) {
+	switch v.T {
+	case "t":
+		return time.Parse(time.RFC3339Nano, v.V)
+	case "i":
+		return strconv.ParseInt(v.V, 10, 64)
+	case "u":
+		return strconv.ParseUint(v.V, 10, 64)
+	case "f":
+		return strconv.ParseFloat(v.V, 64)
+	case "b":
+		return strconv.ParseBool(v.V)
+	case "s":
+		return v.V, nil
+	default:
+		return nil, fmt.Errorf("unknown cursor value type %q", v.T)
+	}
+}
@@ -0,0 +1,134 @@
+package entgo
+
+import (
+	"testing"
+	"time"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"github.com/stretchr/testify/require"
+)
+
+type testCursorRow struct {
+	ID         uint64     `json:"id,omitempty"`
+	CreateTime *time.Time `json:"create_time,omitempty"`
+	Score      float64
+}
+
+func cursorQuerySQL(q *CursorQuery) (string, []any) {
+	s := sql.Dialect(dialect.Postgres).Select("*").From(sql.Table("users"))
+	q.Selector()(s)
+	return s.Query()
+}
+
+func testCursorRows(ids ...uint64) []*testCursorRow {
+	rows := make([]*testCursorRow, 0, len(ids))
+	for _, id := range ids {
+		t := time.Date(2024, 1, 1, 0, 0, int(id), 0, time.UTC)
+		rows = append(rows, &testCursorRow{ID: id, CreateTime: &t})
+	}
+	return rows
+}
+
+func TestCursorPagination(t *testing.T) {
+	codec := NewCursorCodec([]byte("secret"))
+
+	// 第一页
+	err, q := codec.Parse("", 2, []string{"-createTime"}, "id")
+	require.NoError(t, err)
+
+	query, args := cursorQuerySQL(q)
+	require.Equal(t, `SELECT * FROM "users" ORDER BY "users"."create_time" DESC, "users"."id" ASC LIMIT 3`, query)
+	require.Empty(t, args)
+
+	rows, info, err := CursorPageOf(q, testCursorRows(9, 8, 7))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.True(t, info.HasNext)
+	require.False(t, info.HasPrev)
+	require.NotEmpty(t, info.NextCursor)
+	require.Empty(t, info.PrevCursor)
+
+	// 下一页，排序方向不一致时展开为 OR 条件
+	err, q = codec.Parse(info.NextCursor, 2, []string{"-createTime"}, "id")
+	require.NoError(t, err)
+
+	query, args = cursorQuerySQL(q)
+	require.Equal(t, `SELECT * FROM "users" WHERE "users"."create_time" < $1 OR ("users"."create_time" = $2 AND "users"."id" > $3) ORDER BY "users"."create_time" DESC, "users"."id" ASC LIMIT 3`, query)
+	eight := time.Date(2024, 1, 1, 0, 0, 8, 0, time.UTC)
+	require.Equal(t, []any{eight, eight, uint64(8)}, args)
+
+	rows, info, err = CursorPageOf(q, testCursorRows(7, 6))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.False(t, info.HasNext)
+	require.True(t, info.HasPrev)
+
+	// 上一页，反向排序后恢复为原顺序
+	err, q = codec.Parse(info.PrevCursor, 2, []string{"-createTime"}, "id")
+	require.NoError(t, err)
+
+	query, _ = cursorQuerySQL(q)
+	require.Equal(t, `SELECT * FROM "users" WHERE "users"."create_time" > $1 OR ("users"."create_time" = $2 AND "users"."id" < $3) ORDER BY "users"."create_time" ASC, "users"."id" DESC LIMIT 3`, query)
+
+	rows, info, err = CursorPageOf(q, testCursorRows(8, 9))
+	require.NoError(t, err)
+	require.Equal(t, uint64(9), rows[0].ID)
+	require.Equal(t, uint64(8), rows[1].ID)
+	require.True(t, info.HasNext)
+	require.False(t, info.HasPrev)
+}
+
+func TestCursorUniformOrder(t *testing.T) {
+	codec := NewCursorCodec([]byte("secret"))
+
+	err, q := codec.Parse("", 2, nil, "score")
+	require.NoError(t, err)
+
+	_, info, err := CursorPageOf(q, []testCursorRow{{ID: 3, Score: 1.5}, {ID: 2, Score: 1.5}, {ID: 1, Score: 1}})
+	require.NoError(t, err)
+
+	err, q = codec.Parse(info.NextCursor, 2, nil, "score")
+	require.NoError(t, err)
+
+	query, args := cursorQuerySQL(q)
+	require.Equal(t, `SELECT * FROM "users" WHERE "users"."score" < $1 OR ("users"."score" = $2 AND "users"."id" > $3) ORDER BY "users"."score" DESC, "users"."id" ASC LIMIT 3`, query)
+	require.Equal(t, []any{1.5, 1.5, uint64(2)}, args)
+
+	err, q = codec.Parse("", 2, []string{"id"}, "")
+	require.NoError(t, err)
+	_, info, err = CursorPageOf(q, testCursorRows(1, 2, 3))
+	require.NoError(t, err)
+
+	err, q = codec.Parse(info.NextCursor, 2, []string{"id"}, "")
+	require.NoError(t, err)
+	query, args = cursorQuerySQL(q)
+	require.Equal(t, `SELECT * FROM "users" WHERE ("users"."id") > ($1) ORDER BY "users"."id" ASC LIMIT 3`, query)
+	require.Equal(t, []any{uint64(2)}, args)
+}
+
+func TestCursorInvalid(t *testing.T) {
+	codec := NewCursorCodec([]byte("secret"))
+
+	err, q := codec.Parse("", 1, []string{"id"}, "")
+	require.NoError(t, err)
+	_, info, err := CursorPageOf(q, testCursorRows(1, 2))
+	require.NoError(t, err)
+
+	// 篡改
+	err, _ = codec.Parse(info.NextCursor+"x", 1, []string{"id"}, "")
+	require.ErrorIs(t, err, ErrInvalidCursor)
+
+	// 其他密钥签名
+	err, _ = NewCursorCodec([]byte("other")).Parse(info.NextCursor, 1, []string{"id"}, "")
+	require.ErrorIs(t, err, ErrInvalidCursor)
+
+	// 排序条件不一致
+	err, _ = codec.Parse(info.NextCursor, 1, []string{"-id"}, "")
+	require.ErrorIs(t, err, ErrInvalidCursor)
+
+	// 查询策略
+	policy := &QueryPolicy{Fields: map[string]FieldPolicy{"name": {}}}
+	err, _ = policy.ParseCursor(codec, "", 1, []string{"name"}, "id")
+	require.Error(t, err)
+}
@@ -0,0 +1,145 @@
+package entgo
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"entgo.io/ent/dialect"
+)
+
+// ent 未内置的数据库方言，名称与 gorm 的驱动名称一致，通过 sql.Dialect(DialectClickHouse) 使用
+const (
+	DialectClickHouse = "clickhouse"
+	DialectSQLServer  = "sqlserver"
+)
+
+// sqliteDateParts SQLite 提取日期部分的表达式
+// strftime 返回文本，数值部分整体用 CAST 转换为整数，与文本参数比较时按数值比较；ISO 年、周按所在周的星期四计算
+var sqliteDateParts = map[string]string{
+	dateParts[DatePartDate]:        "date(%s)",
+	dateParts[DatePartYear]:        "CAST(strftime('%%Y', %s) AS INTEGER)",
+	dateParts[DatePartISOYear]:     "CAST(strftime('%%Y', %s, '-3 days', 'weekday 4') AS INTEGER)",
+	dateParts[DatePartQuarter]:     "CAST((strftime('%%m', %s) + 2) / 3 AS INTEGER)",
+	dateParts[DatePartMonth]:       "CAST(strftime('%%m', %s) AS INTEGER)",
+	dateParts[DatePartWeek]:        "CAST((strftime('%%j', %s, '-3 days', 'weekday 4') - 1) / 7 + 1 AS INTEGER)",
+	dateParts[DatePartWeekDay]:     "CAST(strftime('%%w', %s) + 1 AS INTEGER)",
+	dateParts[DatePartISOWeekDay]:  "CAST((strftime('%%w', %s) + 6) %% 7 + 1 AS INTEGER)",
+	dateParts[DatePartDay]:         "CAST(strftime('%%d', %s) AS INTEGER)",
+	dateParts[DatePartTime]:        "time(%s)",
+	dateParts[DatePartHour]:        "CAST(strftime('%%H', %s) AS INTEGER)",
+	dateParts[DatePartMinute]:      "CAST(strftime('%%M', %s) AS INTEGER)",
+	dateParts[DatePartSecond]:      "CAST(strftime('%%S', %s) AS INTEGER)",
+	dateParts[DatePartMicrosecond]: "CAST(ROUND(strftime('%%f', %s) * 1000000) %% 1000000 AS INTEGER)",
+}
+
+// clickHouseDateParts ClickHouse 提取日期部分的表达式
+var clickHouseDateParts = map[string]string{
+	dateParts[DatePartDate]:        "toDate(%s)",
+	dateParts[DatePartYear]:        "toYear(%s)",
+	dateParts[DatePartISOYear]:     "toISOYear(%s)",
+	dateParts[DatePartQuarter]:     "toQuarter(%s)",
+	dateParts[DatePartMonth]:       "toMonth(%s)",
+	dateParts[DatePartWeek]:        "toISOWeek(%s)",
+	dateParts[DatePartWeekDay]:     "(toDayOfWeek(%s) %% 7 + 1)",
+	dateParts[DatePartISOWeekDay]:  "toDayOfWeek(%s)",
+	dateParts[DatePartDay]:         "toDayOfMonth(%s)",
+	dateParts[DatePartTime]:        "formatDateTime(%s, '%%H:%%i:%%S')",
+	dateParts[DatePartHour]:        "toHour(%s)",
+	dateParts[DatePartMinute]:      "toMinute(%s)",
+	dateParts[DatePartSecond]:      "toSecond(%s)",
+	dateParts[DatePartMicrosecond]: "(toUnixTimestamp64Micro(%s) %% 1000000)",
+}
+
+// sqlServerDateParts SQL Server 提取日期部分的表达式，星期几的计算与 SET DATEFIRST 无关
+var sqlServerDateParts = map[string]string{
+	dateParts[DatePartDate]:        "CAST(%s AS DATE)",
+	dateParts[DatePartYear]:        "DATEPART(year, %s)",
+	dateParts[DatePartISOYear]:     "YEAR(DATEADD(day, 26 - DATEPART(iso_week, %[1]s), %[1]s))",
+	dateParts[DatePartQuarter]:     "DATEPART(quarter, %s)",
+	dateParts[DatePartMonth]:       "DATEPART(month, %s)",
+	dateParts[DatePartWeek]:        "DATEPART(iso_week, %s)",
+	dateParts[DatePartWeekDay]:     "((DATEPART(weekday, %s) + @@DATEFIRST - 1) %% 7 + 1)",
+	dateParts[DatePartISOWeekDay]:  "((DATEPART(weekday, %s) + @@DATEFIRST + 5) %% 7 + 1)",
+	dateParts[DatePartDay]:         "DATEPART(day, %s)",
+	dateParts[DatePartTime]:        "CAST(%s AS TIME)",
+	dateParts[DatePartHour]:        "DATEPART(hour, %s)",
+	dateParts[DatePartMinute]:      "DATEPART(minute, %s)",
+	dateParts[DatePartSecond]:      "DATEPART(second, %s)",
+	dateParts[DatePartMicrosecond]: "DATEPART(microsecond, %s)",
+}
+
+// datePartExpr 提取日期部分的表达式，不支持的数据库返回空字符串
+// PostgreSQL: EXTRACT('YEAR' FROM "users"."created_at")
+// MySQL: YEAR(`users`.`created_at`)
+// SQLite: CAST(strftime('%Y', `users`.`created_at`) AS INTEGER)
+// ClickHouse: toYear(`users`.`created_at`)
+// SQL Server: DATEPART(year, "users"."created_at")
+func datePartExpr(dialectName, datePart, column string) string {
+	var format string
+	switch dialectName {
+	case dialect.Postgres:
+		return fmt.Sprintf("EXTRACT('%s' FROM %s)", strings.ToUpper(datePart), column)
+
+	case dialect.MySQL:
+		return fmt.Sprintf("%s(%s)", strings.ToUpper(datePart), column)
+
+	case dialect.SQLite:
+		format = sqliteDateParts[strings.ToLower(datePart)]
+
+	case DialectClickHouse:
+		format = clickHouseDateParts[strings.ToLower(datePart)]
+
+	case DialectSQLServer:
+		format = sqlServerDateParts[strings.ToLower(datePart)]
+		column = sqlServerIdent(column)
+	}
+	if format == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(format, column)
+}
+
+// jsonKeyPattern 可以直接写入SQL的JSON字段名
+var jsonKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// jsonExtractExpr 提取JSON字段的表达式
+// PostgreSQL: "users"."meta" ->> 'title'
+// MySQL: JSON_EXTRACT(`users`.`meta`, '$.title')
+// SQLite: json_extract(`users`.`meta`, '$.title')
+// ClickHouse: JSON_VALUE(`users`.`meta`, '$.title')
+// SQL Server: JSON_VALUE("users"."meta", '$.title')
+//
+// 字段名直接写入SQL，MySQL 等数据库会把反斜杠当作转义符，因此只允许字母、数字和下划线。
+func jsonExtractExpr(dialectName, key, column string) (string, error) {
+	if !jsonKeyPattern.MatchString(key) {
+		return "", fmt.Errorf("invalid json field %q", key)
+	}
+
+	switch dialectName {
+	case dialect.Postgres:
+		return column + " ->> '" + key + "'", nil
+
+	case dialect.MySQL:
+		return fmt.Sprintf("JSON_EXTRACT(%s, '$.%s')", column, key), nil
+
+	case dialect.SQLite:
+		return fmt.Sprintf("json_extract(%s, '$.%s')", column, key), nil
+
+	case DialectClickHouse:
+		return fmt.Sprintf("JSON_VALUE(%s, '$.%s')", column, key), nil
+
+	case DialectSQLServer:
+		return fmt.Sprintf("JSON_VALUE(%s, '$.%s')", sqlServerIdent(column), key), nil
+
+	default:
+		return "", fmt.Errorf("json field is not supported for dialect %q", dialectName)
+	}
+}
+
+// sqlServerIdent 将 ent 按 MySQL 方式引用的标识符改为 SQL Server 支持的双引号
+// ent 对 PostgreSQL 以外的方言都使用反引号，SQL Server 不支持反引号
+func sqlServerIdent(column string) string {
+	return strings.ReplaceAll(column, "`", `"`)
+}
@@ -0,0 +1,225 @@
+package entgo
+
+import (
+	dsql "database/sql"
+	"regexp"
+	"sync"
+	"testing"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatePartExpr(t *testing.T) {
+	testcases := []struct {
+		dialect  string
+		datePart string
+		expr     string
+	}{
+		{dialect.Postgres, "year", "EXTRACT('YEAR' FROM \"users\".\"created_at\")"},
+		{dialect.MySQL, "year", "YEAR(`users`.`created_at`)"},
+		{dialect.SQLite, "year", "CAST(strftime('%Y', `users`.`created_at`) AS INTEGER)"},
+		{dialect.SQLite, "date", "date(`users`.`created_at`)"},
+		{DialectClickHouse, "year", "toYear(`users`.`created_at`)"},
+		{DialectClickHouse, "week_day", "(toDayOfWeek(`users`.`created_at`) % 7 + 1)"},
+		{DialectSQLServer, "year", `DATEPART(year, "users"."created_at")`},
+		{DialectSQLServer, "iso_year", `YEAR(DATEADD(day, 26 - DATEPART(iso_week, "users"."created_at"), "users"."created_at"))`},
+		{dialect.Gremlin, "year", ""},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.dialect+"_"+tc.datePart, func(t *testing.T) {
+			s := sql.Dialect(tc.dialect).Select("*").From(sql.Table("users"))
+			require.Equal(t, tc.expr, datePartExpr(tc.dialect, tc.datePart, s.C("created_at")))
+		})
+	}
+
+	// 所有日期部分在各数据库下均有表达式
+	for _, d := range []string{dialect.SQLite, DialectClickHouse, DialectSQLServer} {
+		for _, part := range dateParts {
+			require.NotEmpty(t, datePartExpr(d, part, "created_at"), "%s %s", d, part)
+		}
+	}
+}
+
+func TestJsonExtractExpr(t *testing.T) {
+	for d, expr := range map[string]string{
+		dialect.Postgres:  "`users`.`meta` ->> 'title'",
+		dialect.MySQL:     "JSON_EXTRACT(`users`.`meta`, '$.title')",
+		dialect.SQLite:    "json_extract(`users`.`meta`, '$.title')",
+		DialectClickHouse: "JSON_VALUE(`users`.`meta`, '$.title')",
+		DialectSQLServer:  `JSON_VALUE("users"."meta", '$.title')`,
+	} {
+		str, err := jsonExtractExpr(d, "title", "`users`.`meta`")
+		require.NoError(t, err)
+		require.Equal(t, expr, str)
+
+		// 字段名中的引号、反斜杠不能写入SQL
+		for _, key := range []string{`x\' OR 1=1 -- `, "it's", `a"b`, "a.b", ""} {
+			_, err = jsonExtractExpr(d, key, "`users`.`meta`")
+			require.Error(t, err, "%s %q", d, key)
+		}
+	}
+	_, err := jsonExtractExpr(dialect.Gremlin, "title", "meta")
+	require.Error(t, err)
+
+	// 不支持的数据库在构建查询时返回错误
+	err, selectors := BuildFilterSelector(`{"meta.title":"x"}`, "")
+	require.NoError(t, err)
+	s := sql.Dialect(dialect.Gremlin).Select("*").From(sql.Table("users"))
+	for _, fnc := range selectors {
+		fnc(s)
+	}
+	_, _ = s.Query()
+	require.Error(t, s.Err())
+
+	// 非法的字段名在构建查询时返回错误
+	err, selectors = BuildFilterSelector(`{"meta__x\\' OR 1=1 -- ":"1"}`, "")
+	require.NoError(t, err)
+	s = sql.Dialect(dialect.MySQL).Select("*").From(sql.Table("users"))
+	for _, fnc := range selectors {
+		fnc(s)
+	}
+	_, _ = s.Query()
+	require.ErrorContains(t, s.Err(), "invalid json field")
+}
+
+var registerSQLiteOnce sync.Once
+
+// openSQLite 打开内存数据库，注册 REGEXP 函数并写入测试数据
+func openSQLite(t *testing.T) *dsql.DB {
+	registerSQLiteOnce.Do(func() {
+		dsql.Register("sqlite3_query_test", &sqlite3.SQLiteDriver{
+			ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+				return conn.RegisterFunc("regexp", func(pattern, s string) (bool, error) {
+					return regexp.MatchString(pattern, s)
+				}, true)
+			},
+		})
+	})
+
+	db, err := dsql.Open("sqlite3_query_test", "file::memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err = db.Exec(`
+CREATE TABLE users (
+	id         INTEGER PRIMARY KEY,
+	name       TEXT,
+	age        INTEGER,
+	meta       TEXT,
+	created_at DATETIME,
+	deleted_at DATETIME,
+	tags       TEXT
+);
+INSERT INTO users VALUES
+	(1, 'Alice', 20, '{"city":"Beijing","level":"gold"}', '2023-01-15 08:30:45.123', NULL, '["go","db"]'),
+	(2, 'bob', 30, '{"city":"Shanghai","level":"silver"}', '2023-06-10 12:00:00', '2023-07-01 00:00:00', '["go"]'),
+	(3, 'Carol', 40, '{"city":"beijing"}', '2024-03-31 23:59:59', NULL, '[]'),
+	(4, 'Dave', 50, '{"city":"Shenzhen","zip":"518000"}', '2021-01-01 00:00:00', NULL, '["rust","db","wasm",5,true]');
+`)
+	require.NoError(t, err)
+
+	return db
+}
+
+// TestSQLiteConformance 在 SQLite 内存数据库中执行各操作符生成的查询
+//
+// search 需要 FTS5 虚拟表，istartswith、iendswith 依赖 EqualFold，不在此覆盖。
+func TestSQLiteConformance(t *testing.T) {
+	db := openSQLite(t)
+
+	testcases := []struct {
+		name  string
+		query string
+		ids   []int
+	}{
+		{"equal", `{"name":"Alice"}`, []int{1}},
+		{"not", `{"name__not":"Alice"}`, []int{2, 3, 4}},
+		{"in", `{"age__in":"[20, 40]"}`, []int{1, 3}},
+		{"not_in", `{"age__not_in":"[20, 40]"}`, []int{2, 4}},
+		{"gte", `{"age__gte":"30"}`, []int{2, 3, 4}},
+		{"gt", `{"age__gt":"30"}`, []int{3, 4}},
+		{"lte", `{"age__lte":"30"}`, []int{1, 2}},
+		{"lt", `{"age__lt":"30"}`, []int{1}},
+		{"range", `{"age__range":"[30, 40]"}`, []int{2, 3}},
+		{"isnull", `{"deleted_at__isnull":"True"}`, []int{1, 3, 4}},
+		{"not_isnull", `{"deleted_at__not_isnull":"True"}`, []int{2}},
+		{"contains", `{"name__contains":"o"}`, []int{2, 3}},
+		{"icontains", `{"name__icontains":"A"}`, []int{1, 3, 4}},
+		{"startswith", `{"name__startswith":"C"}`, []int{3}},
+		{"endswith", `{"name__endswith":"e"}`, []int{1, 4}},
+		{"exact", `{"name__exact":"bob"}`, []int{2}},
+		{"iexact", `{"name__iexact":"BOB"}`, []int{2}},
+		{"regex", `{"name__regex":"^[A-C]"}`, []int{1, 3}},
+		{"iregex", `{"name__iregex":"^[a-c]"}`, []int{1, 2, 3}},
+
+		{"date", `{"created_at__date":"2023-06-10"}`, []int{2}},
+		{"year", `{"created_at__year":"2023"}`, []int{1, 2}},
+		{"iso_year", `{"created_at__iso_year":"2020"}`, []int{4}},
+		{"quarter", `{"created_at__quarter":"1"}`, []int{1, 3, 4}},
+		{"month", `{"created_at__month":"6"}`, []int{2}},
+		{"week", `{"created_at__week":"53"}`, []int{4}},
+		{"week_day", `{"created_at__week_day":"1"}`, []int{1, 3}},
+		{"iso_week_day", `{"created_at__iso_week_day":"6"}`, []int{2}},
+		{"day", `{"created_at__day":"31"}`, []int{3}},
+		{"time", `{"created_at__time":"12:00:00"}`, []int{2}},
+		{"hour", `{"created_at__hour":"8"}`, []int{1}},
+		{"minute", `{"created_at__minute":"59"}`, []int{3}},
+		{"second", `{"created_at__second":"45"}`, []int{1}},
+		{"microsecond", `{"created_at__microsecond":"123000"}`, []int{1}},
+		{"year_gte", `{"created_at__year__gte":"2023"}`, []int{1, 2, 3}},
+		{"date_range", `{"created_at__date__range":"[\"2023-01-01\", \"2023-12-31\"]"}`, []int{1, 2}},
+
+		{"json", `{"meta.city":"Beijing"}`, []int{1}},
+		{"json_key", `{"meta__level":"gold"}`, []int{1}},
+		{"json_icontains", `{"meta.city__icontains":"BEI"}`, []int{1, 3}},
+		{"json_not", `{"meta__city__not":"Beijing"}`, []int{2, 3, 4}},
+		{"json_isnull", `{"meta__level__isnull":"True"}`, []int{3, 4}},
+
+		{"has", `{"tags__has":"go"}`, []int{1, 2}},
+		{"has_any", `{"tags__has_any":["db","rust"]}`, []int{1, 4}},
+		{"has_all", `{"tags__has_all":["go","db","go"]}`, []int{1}},
+		{"overlap", `{"tags__overlap":["wasm","go"]}`, []int{1, 2, 4}},
+		{"has_number", `{"tags__has":"5"}`, []int{4}},
+		{"has_number_value", `{"tags__has":5}`, []int{4}},
+		{"has_all_mixed", `{"tags__has_all":["db",5,"true"]}`, []int{4}},
+		{"has_any_bool", `{"tags__has_any":[true,"go"]}`, []int{1, 2, 4}},
+		{"len", `{"tags__len":"0"}`, []int{3}},
+		{"len_gt", `{"tags__len_gt":"1"}`, []int{1, 4}},
+		{"len_gte", `{"tags__len_gte":"1"}`, []int{1, 2, 4}},
+		{"len_lt", `{"tags__len_lt":"2"}`, []int{2, 3}},
+		{"len_lte", `{"tags__len_lte":"2"}`, []int{1, 2, 3}},
+		{"has_key", `{"meta__has_key":"level"}`, []int{1, 2}},
+		{"has_keys", `{"meta__has_keys":["city","level"]}`, []int{1, 2}},
+		{"has_any_keys", `{"meta__has_any_keys":["level","zip"]}`, []int{1, 2, 4}},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err, selectors := BuildFilterSelector(tc.query, "")
+			require.NoError(t, err)
+
+			s := sql.Dialect(dialect.SQLite).Select("id").From(sql.Table("users")).OrderBy("id")
+			for _, fnc := range selectors {
+				fnc(s)
+			}
+			query, args := s.Query()
+			require.NoError(t, s.Err())
+
+			rows, err := db.Query(query, args...)
+			require.NoError(t, err, query)
+			defer rows.Close()
+
+			var ids []int
+			for rows.Next() {
+				var id int
+				require.NoError(t, rows.Scan(&id))
+				ids = append(ids, id)
+			}
+			require.NoError(t, rows.Err())
+			require.Equal(t, tc.ids, ids, query)
+		})
+	}
+}
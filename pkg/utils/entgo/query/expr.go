@@ -0,0 +1,102 @@
+package entgo
+
+import (
+	"entgo.io/ent/dialect/sql"
+
+	"github.com/heyinLab/common/pkg/utils/query_parser"
+)
+
+// BuildFilterExprSelector 构建过滤表达式的选择器
+//
+// 使用示例:
+//
+//	// status = 'ON' AND (name ILIKE '%x%' OR code LIKE 'x%')
+//	expr, err := query_parser.ParseFilterExprQueryString("status:ON,or(name__icontains:x,code__startswith:x)")
+//	selector := BuildFilterExprSelector(expr)
+func BuildFilterExprSelector(expr *query_parser.FilterExpr) func(s *sql.Selector) {
+	return (*FieldRegistry)(nil).BuildFilterExprSelector(expr)
+}
+
+// FilterExprPredicate 将过滤表达式转换为嵌套的 sql.Predicate，没有有效条件时返回 nil
+func FilterExprPredicate(s *sql.Selector, expr *query_parser.FilterExpr) *sql.Predicate {
+	return (*FieldRegistry)(nil).FilterExprPredicate(s, expr)
+}
+
+// BuildFilterExprSelector 构建过滤表达式的选择器，过滤值按登记的字段类型转换
+//
+// 转换失败的错误通过 sql.Selector 的 Err 返回，需要提前校验时使用 QueryPolicy。
+func (r *FieldRegistry) BuildFilterExprSelector(expr *query_parser.FilterExpr) func(s *sql.Selector) {
+	if expr.IsEmpty() {
+		return nil
+	}
+	return func(s *sql.Selector) {
+		if p := r.FilterExprPredicate(s, expr); p != nil {
+			s.Where(p)
+		}
+	}
+}
+
+// FilterExprPredicate 将过滤表达式转换为嵌套的 sql.Predicate，过滤值按登记的字段类型转换
+func (r *FieldRegistry) FilterExprPredicate(s *sql.Selector, expr *query_parser.FilterExpr) *sql.Predicate {
+	if expr == nil {
+		return nil
+	}
+
+	switch expr.Kind {
+	case query_parser.FilterExprLeaf:
+		return r.makeFieldFilter(s, splitQueryKey(expr.Key), expr.Value)
+
+	case query_parser.FilterExprNot:
+		if len(expr.Children) == 0 {
+			return nil
+		}
+		if p := r.FilterExprPredicate(s, expr.Children[0]); p != nil {
+			return sql.Not(p)
+		}
+		return nil
+
+	case query_parser.FilterExprAnd, query_parser.FilterExprOr:
+		ps := r.filterExprPredicates(s, expr.Children)
+		switch {
+		case len(ps) == 0:
+			return nil
+		case len(ps) == 1:
+			return ps[0]
+		case expr.Kind == query_parser.FilterExprOr:
+			return sql.Or(ps...)
+		default:
+			return sql.And(ps...)
+		}
+
+	default:
+		return nil
+	}
+}
+
+// filterExprPredicates 转换多个子表达式，忽略无效条件
+func (r *FieldRegistry) filterExprPredicates(s *sql.Selector, children []*query_parser.FilterExpr) []*sql.Predicate {
+	var ps []*sql.Predicate
+	for _, child := range children {
+		if p := r.FilterExprPredicate(s, child); p != nil {
+			ps = append(ps, p)
+		}
+	}
+	return ps
+}
+
+// topLevelFilterExprs 展开顶层数组中的对象，兼容原有扁平格式的与/或语义
+func topLevelFilterExprs(expr *query_parser.FilterExpr) []*query_parser.FilterExpr {
+	if expr == nil || expr.Kind != query_parser.FilterExprAnd {
+		return []*query_parser.FilterExpr{expr}
+	}
+
+	var items []*query_parser.FilterExpr
+	for _, child := range expr.Children {
+		if child != nil && child.Kind == query_parser.FilterExprAnd {
+			items = append(items, child.Children...)
+		} else {
+			items = append(items, child)
+		}
+	}
+	return items
+}
@@ -9,8 +9,7 @@ import (
 	"entgo.io/ent/dialect"
 	"entgo.io/ent/dialect/sql"
 
-	"github.com/go-kratos/kratos/v2/encoding"
-
+	"github.com/heyinLab/common/pkg/utils/query_parser"
 	"github.com/heyinLab/common/pkg/utils/stringcase"
 )
 
@@ -38,6 +37,18 @@ const (
 	FilterRegex                                 // 正则表达式
 	FilterInsensitiveRegex                      // 不区分大小写，正则表达式
 	FilterSearch                                // 全文搜索
+	FilterHas                                   // 数组包含指定元素
+	FilterHasAny                                // 数组包含任意一个元素
+	FilterHasAll                                // 数组包含全部元素
+	FilterOverlap                               // 数组有交集，同 FilterHasAny
+	FilterLen                                   // 数组长度等于
+	FilterLenGT                                 // 数组长度大于
+	FilterLenGTE                                // 数组长度大于或等于
+	FilterLenLT                                 // 数组长度小于
+	FilterLenLTE                                // 数组长度小于或等于
+	FilterHasKey                                // JSON 对象包含指定键
+	FilterHasKeys                               // JSON 对象包含全部键
+	FilterHasAnyKeys                            // JSON 对象包含任意一个键
 )
 
 var ops = [...]string{
@@ -62,6 +73,18 @@ var ops = [...]string{
 	FilterRegex:                 "regex",
 	FilterInsensitiveRegex:      "iregex",
 	FilterSearch:                "search",
+	FilterHas:                   "has",
+	FilterHasAny:                "has_any",
+	FilterHasAll:                "has_all",
+	FilterOverlap:               "overlap",
+	FilterLen:                   "len",
+	FilterLenGT:                 "len_gt",
+	FilterLenGTE:                "len_gte",
+	FilterLenLT:                 "len_lt",
+	FilterLenLTE:                "len_lte",
+	FilterHasKey:                "has_key",
+	FilterHasKeys:               "has_keys",
+	FilterHasAnyKeys:            "has_any_keys",
 }
 
 type DatePart int
@@ -120,8 +143,8 @@ func isJsonFieldKey(key string) bool {
 	return strings.Contains(key, JsonFieldDelimiter)
 }
 
-// hasOperations 是否有操作
-func hasOperations(str string) bool {
+// IsOperator 是否为支持的过滤操作符，不区分大小写
+func IsOperator(str string) bool {
 	str = strings.ToLower(str)
 	for _, item := range ops {
 		if str == item {
@@ -131,8 +154,8 @@ func hasOperations(str string) bool {
 	return false
 }
 
-// hasDatePart 是否有日期部分
-func hasDatePart(str string) bool {
+// IsDatePart 是否为支持的日期部分，不区分大小写
+func IsDatePart(str string) bool {
 	str = strings.ToLower(str)
 	for _, item := range dateParts {
 		if str == item {
@@ -144,52 +167,34 @@ func hasDatePart(str string) bool {
 
 // BuildFilterSelector 构建过滤选择器
 func BuildFilterSelector(andFilterJsonString, orFilterJsonString string) (error, []func(s *sql.Selector)) {
-	var err error
-	var queryConditions []func(s *sql.Selector)
-
-	var andSelector func(s *sql.Selector)
-	err, andSelector = QueryCommandToWhereConditions(andFilterJsonString, false)
-	if err != nil {
-		return err, nil
-	}
-	if andSelector != nil {
-		queryConditions = append(queryConditions, andSelector)
-	}
+	return (*QueryPolicy)(nil).BuildFilterSelector(andFilterJsonString, orFilterJsonString)
+}
 
-	var orSelector func(s *sql.Selector)
-	err, orSelector = QueryCommandToWhereConditions(orFilterJsonString, true)
-	if err != nil {
+// QueryCommandToWhereConditions 查询命令转换为选择条件
+//
+// 除扁平的 {字段名}__{操作符} 格式外，还支持 and/or/not 嵌套，见 query_parser.ParseFilterExprJSON。
+// isOr 为 true 时，顶层条件之间为或关系。
+func QueryCommandToWhereConditions(strJson string, isOr bool) (error, func(s *sql.Selector)) {
+	expr, err := parseFilterCommand(strJson)
+	if err != nil || expr == nil {
 		return err, nil
 	}
-	if orSelector != nil {
-		queryConditions = append(queryConditions, orSelector)
-	}
 
-	return nil, queryConditions
+	return nil, (*FieldRegistry)(nil).whereConditionsSelector(expr, isOr)
 }
 
-// QueryCommandToWhereConditions 查询命令转换为选择条件
-func QueryCommandToWhereConditions(strJson string, isOr bool) (error, func(s *sql.Selector)) {
+// parseFilterCommand 解析查询命令，为空时返回 nil
+func parseFilterCommand(strJson string) (*query_parser.FilterExpr, error) {
 	if len(strJson) == 0 {
 		return nil, nil
 	}
+	return query_parser.ParseFilterExprJSON(strJson)
+}
 
-	codec := encoding.GetCodec("json")
-
-	queryMap := make(map[string]string)
-	var queryMapArray []map[string]string
-	if err1 := codec.Unmarshal([]byte(strJson), &queryMap); err1 != nil {
-		if err2 := codec.Unmarshal([]byte(strJson), &queryMapArray); err2 != nil {
-			return err2, nil
-		}
-	}
-
-	return nil, func(s *sql.Selector) {
-		var ps []*sql.Predicate
-		ps = append(ps, processQueryMap(s, queryMap)...)
-		for _, v := range queryMapArray {
-			ps = append(ps, processQueryMap(s, v)...)
-		}
+// whereConditionsSelector 将解析后的查询命令转换为选择条件
+func (r *FieldRegistry) whereConditionsSelector(expr *query_parser.FilterExpr, isOr bool) func(s *sql.Selector) {
+	return func(s *sql.Selector) {
+		ps := r.filterExprPredicates(s, topLevelFilterExprs(expr))
 
 		if isOr {
 			s.Where(sql.Or(ps...))
@@ -199,22 +204,8 @@ func QueryCommandToWhereConditions(strJson string, isOr bool) (error, func(s *sq
 	}
 }
 
-// processQueryMap 处理查询映射表
-func processQueryMap(s *sql.Selector, queryMap map[string]string) []*sql.Predicate {
-	var ps []*sql.Predicate
-	for k, v := range queryMap {
-		keys := splitQueryKey(k)
-
-		if cond := makeFieldFilter(s, keys, v); cond != nil {
-			ps = append(ps, cond)
-		}
-	}
-
-	return ps
-}
-
 // makeFieldFilter 构建一个字段过滤器
-func makeFieldFilter(s *sql.Selector, keys []string, value string) *sql.Predicate {
+func makeFieldFilter(s *sql.Selector, keys []string, value string, search SearchOptions) *sql.Predicate {
 	if len(keys) == 0 {
 		return nil
 	}
@@ -268,9 +259,9 @@ func makeFieldFilter(s *sql.Selector, keys []string, value string) *sql.Predicat
 		}
 
 		var cond *sql.Predicate
-		if hasOperations(op) {
-			return processOp(s, p, op, field, value)
-		} else if hasDatePart(op) {
+		if IsOperator(op) {
+			return processOp(s, p, op, field, value, search)
+		} else if IsDatePart(op) {
 			cond = filterDatePart(s, p, op, field).EQ("", value)
 		} else {
 			cond = filterJsonb(s, p, op, field).EQ("", value)
@@ -292,7 +283,7 @@ func makeFieldFilter(s *sql.Selector, keys []string, value string) *sql.Predicat
 		// 第二个参数，要么是提取日期，要么是json字段。
 
 		//var cond *sql.Predicate
-		if hasDatePart(op1) {
+		if IsDatePart(op1) {
 			if isJsonFieldKey(field) {
 				jsonFields := splitJsonFieldKey(field)
 				if len(jsonFields) == 2 {
@@ -305,17 +296,17 @@ func makeFieldFilter(s *sql.Selector, keys []string, value string) *sql.Predicat
 
 			str := filterDatePartField(s, op1, field)
 
-			if hasOperations(op2) {
-				return processOp(s, p, op2, str, value)
+			if IsOperator(op2) {
+				return processOp(s, p, op2, str, value, search)
 			}
 
 			return nil
 		} else {
 			str := filterJsonbField(s, op1, field)
 
-			if hasOperations(op2) {
-				return processOp(s, p, op2, str, value)
-			} else if hasDatePart(op2) {
+			if IsOperator(op2) {
+				return processOp(s, p, op2, str, value, search)
+			} else if IsDatePart(op2) {
 				return filterDatePart(s, p, op2, str)
 			}
 			return nil
@@ -326,7 +317,7 @@ func makeFieldFilter(s *sql.Selector, keys []string, value string) *sql.Predicat
 	}
 }
 
-func processOp(s *sql.Selector, p *sql.Predicate, op, field, value string) *sql.Predicate {
+func processOp(s *sql.Selector, p *sql.Predicate, op, field, value string, search SearchOptions) *sql.Predicate {
 	var cond *sql.Predicate
 
 	switch op {
@@ -371,7 +362,11 @@ func processOp(s *sql.Selector, p *sql.Predicate, op, field, value string) *sql.
 	case ops[FilterInsensitiveRegex]:
 		cond = filterInsensitiveRegex(s, p, field, value)
 	case ops[FilterSearch]:
-		cond = filterSearch(s, p, field, value)
+		cond = filterSearch(s, p, field, value, search)
+	case ops[FilterHas], ops[FilterHasAny], ops[FilterHasAll], ops[FilterOverlap],
+		ops[FilterLen], ops[FilterLenGT], ops[FilterLenGTE], ops[FilterLenLT], ops[FilterLenLTE],
+		ops[FilterHasKey], ops[FilterHasKeys], ops[FilterHasAnyKeys]:
+		cond = filterCollection(s, p, op, field, value, false)
 	default:
 		return nil
 	}
@@ -579,74 +574,52 @@ func filterInsensitiveRegex(s *sql.Selector, p *sql.Predicate, field, value stri
 	return p
 }
 
-// filterSearch 全文搜索
-// SQL:
-func filterSearch(s *sql.Selector, p *sql.Predicate, _, _ string) *sql.Predicate {
+// filterSearch 全文搜索，不支持的数据库会在构建查询时返回错误
+// PostgreSQL: WHERE to_tsvector('simple', "title") @@ plainto_tsquery('simple', 'keyword')
+// MySQL: WHERE MATCH(`title`) AGAINST('keyword' IN NATURAL LANGUAGE MODE)
+// SQLite: WHERE "title" MATCH 'keyword'
+func filterSearch(s *sql.Selector, p *sql.Predicate, field, value string, opts SearchOptions) *sql.Predicate {
 	p.Append(func(b *sql.Builder) {
-		switch s.Builder.Dialect() {
-
-		}
+		writeSearchMatch(b, s, field, value, opts)
 	})
 	return p
 }
 
-// filterDatePart 时间戳提取日期
+// filterDatePart 时间戳提取日期，各数据库的表达式见 datePartExpr
 // SQL: select extract(quarter from timestamp '2018-08-15 12:10:10');
 func filterDatePart(s *sql.Selector, p *sql.Predicate, datePart, field string) *sql.Predicate {
 	p.Append(func(b *sql.Builder) {
-		switch s.Builder.Dialect() {
-		case dialect.Postgres:
-			str := fmt.Sprintf("EXTRACT('%s' FROM %s)", strings.ToUpper(datePart), s.C(field))
-			b.WriteString(str)
-			//b.Arg(strings.ToLower(value))
-			break
-
-		case dialect.MySQL:
-			str := fmt.Sprintf("%s(%s)", strings.ToUpper(datePart), s.C(field))
-			b.WriteString(str)
-			//b.Arg(strings.ToLower(value))
-			break
+		str := datePartExpr(s.Builder.Dialect(), datePart, s.C(field))
+		if str == "" {
+			b.AddError(fmt.Errorf("date part %q is not supported for dialect %q", datePart, s.Builder.Dialect()))
+			return
 		}
+		b.WriteString(str)
 	})
 	return p
 }
 
 // filterDatePartField 日期
 func filterDatePartField(s *sql.Selector, datePart, field string) string {
-	p := sql.P()
-	switch s.Builder.Dialect() {
-	case dialect.Postgres:
-		str := fmt.Sprintf("EXTRACT('%s' FROM %s)", strings.ToUpper(datePart), s.C(field))
-		p.WriteString(str)
-		break
-
-	case dialect.MySQL:
-		str := fmt.Sprintf("%s(%s)", strings.ToUpper(datePart), s.C(field))
-		p.WriteString(str)
-		break
+	str := datePartExpr(s.Builder.Dialect(), datePart, s.C(field))
+	if str == "" {
+		s.AddError(fmt.Errorf("date part %q is not supported for dialect %q", datePart, s.Builder.Dialect()))
 	}
-
-	return p.String()
+	return str
 }
 
-// filterJsonb 提取JSONB字段
+// filterJsonb 提取JSONB字段，各数据库的表达式见 jsonExtractExpr
 // Postgresql: WHERE ("app_profile"."preferences" ->> 'daily_email') = 'true'
 func filterJsonb(s *sql.Selector, p *sql.Predicate, jsonbField, field string) *sql.Predicate {
 	field = stringcase.ToSnakeCase(field)
 
 	p.Append(func(b *sql.Builder) {
-		switch s.Builder.Dialect() {
-		case dialect.Postgres:
-			b.Ident(s.C(field)).WriteString(" ->> ").WriteString("'" + jsonbField + "'")
-			//b.Arg(strings.ToLower(value))
-			break
-
-		case dialect.MySQL:
-			str := fmt.Sprintf("JSON_EXTRACT(%s, '$.%s')", s.C(field), jsonbField)
-			b.WriteString(str)
-			//b.Arg(strings.ToLower(value))
-			break
+		str, err := jsonExtractExpr(s.Builder.Dialect(), jsonbField, s.C(field))
+		if err != nil {
+			b.AddError(err)
+			return
 		}
+		b.WriteString(str)
 	})
 	return p
 }
@@ -655,19 +628,9 @@ func filterJsonb(s *sql.Selector, p *sql.Predicate, jsonbField, field string) *s
 func filterJsonbField(s *sql.Selector, jsonbField, field string) string {
 	field = stringcase.ToSnakeCase(field)
 
-	p := sql.P()
-	switch s.Builder.Dialect() {
-	case dialect.Postgres:
-		p.Ident(s.C(field)).WriteString(" ->> ").WriteString("'" + jsonbField + "'")
-		//b.Arg(strings.ToLower(value))
-		break
-
-	case dialect.MySQL:
-		str := fmt.Sprintf("JSON_EXTRACT(%s, '$.%s')", s.C(field), jsonbField)
-		p.WriteString(str)
-		//b.Arg(strings.ToLower(value))
-		break
+	str, err := jsonExtractExpr(s.Builder.Dialect(), jsonbField, s.C(field))
+	if err != nil {
+		s.AddError(err)
 	}
-
-	return p.String()
+	return str
 }
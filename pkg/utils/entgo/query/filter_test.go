@@ -702,7 +702,7 @@ func TestFilterJsonbField(t *testing.T) {
 
 		p := sql.P()
 
-		p = makeFieldFilter(s, []string{"meta.title"}, "tom")
+		p = makeFieldFilter(s, []string{"meta.title"}, "tom", SearchOptions{})
 		s.Where(p)
 
 		query, args := s.Query()
@@ -715,7 +715,7 @@ func TestFilterJsonbField(t *testing.T) {
 
 		p := sql.P()
 
-		p = makeFieldFilter(s, []string{"meta.title"}, "tom")
+		p = makeFieldFilter(s, []string{"meta.title"}, "tom", SearchOptions{})
 		s.Where(p)
 
 		query, args := s.Query()
@@ -731,7 +731,7 @@ func TestFilterJsonbField(t *testing.T) {
 
 		p := sql.P()
 
-		p = makeFieldFilter(s, []string{"meta.title", "not"}, "tom")
+		p = makeFieldFilter(s, []string{"meta.title", "not"}, "tom", SearchOptions{})
 		s.Where(p)
 
 		query, args := s.Query()
@@ -744,7 +744,7 @@ func TestFilterJsonbField(t *testing.T) {
 
 		p := sql.P()
 
-		p = makeFieldFilter(s, []string{"meta.title", "not"}, "tom")
+		p = makeFieldFilter(s, []string{"meta.title", "not"}, "tom", SearchOptions{})
 		s.Where(p)
 
 		query, args := s.Query()
@@ -760,7 +760,7 @@ func TestFilterJsonbField(t *testing.T) {
 
 		p := sql.P()
 
-		p = makeFieldFilter(s, []string{"meta.title", "date", "not"}, "2023-01-01")
+		p = makeFieldFilter(s, []string{"meta.title", "date", "not"}, "2023-01-01", SearchOptions{})
 		s.Where(p)
 
 		query, args := s.Query()
@@ -773,7 +773,7 @@ func TestFilterJsonbField(t *testing.T) {
 
 		p := sql.P()
 
-		p = makeFieldFilter(s, []string{"meta.title", "date", "not"}, "2023-01-01")
+		p = makeFieldFilter(s, []string{"meta.title", "date", "not"}, "2023-01-01", SearchOptions{})
 		s.Where(p)
 
 		query, args := s.Query()
@@ -782,3 +782,85 @@ func TestFilterJsonbField(t *testing.T) {
 		require.Equal(t, args[0], "'2023-01-01'")
 	})
 }
+
+func TestFilterSearch(t *testing.T) {
+	t.Run("PostgreSQL_FilterSearch", func(t *testing.T) {
+		s := sql.Dialect(dialect.Postgres).Select("*").From(sql.Table("posts"))
+		s.Where(filterSearch(s, sql.P(), "title", "hello world", SearchOptions{}))
+
+		query, args := s.Query()
+		require.NoError(t, s.Err())
+		require.Equal(t, "SELECT * FROM \"posts\" WHERE to_tsvector('simple', \"posts\".\"title\") @@ plainto_tsquery('simple', $1)", query)
+		require.Equal(t, []any{"hello world"}, args)
+	})
+	t.Run("PostgreSQL_FilterSearch_Chinese_Boolean", func(t *testing.T) {
+		opts := []SearchOption{WithSearchConfig(PostgresSearchConfigChinese), WithSearchMode(SearchModeBoolean)}
+		types := NewFieldRegistry().WithSearchOptions(opts...)
+
+		s := sql.Dialect(dialect.Postgres).Select("*").From(sql.Table("posts"))
+		s.Where(types.makeFieldFilter(s, []string{"title", "search"}, "全文 搜索"))
+		BuildSearchRankSelector("title", "全文 搜索", opts...)(s)
+
+		query, args := s.Query()
+		require.Equal(t, "SELECT * FROM \"posts\" WHERE to_tsvector('chinese', \"posts\".\"title\") @@ websearch_to_tsquery('chinese', $1) ORDER BY ts_rank(to_tsvector('chinese', \"posts\".\"title\"), websearch_to_tsquery('chinese', $2)) DESC", query)
+		require.Equal(t, []any{"全文 搜索", "全文 搜索"}, args)
+	})
+	t.Run("PerCallSearchOptions", func(t *testing.T) {
+		// 其他调用不受影响
+		err, selectors := BuildFilterSelector(`{"title__search":"hello"}`, "")
+		require.NoError(t, err)
+		s := sql.Dialect(dialect.Postgres).Select("*").From(sql.Table("posts"))
+		for _, fnc := range selectors {
+			fnc(s)
+		}
+		query, _ := s.Query()
+		require.Equal(t, "SELECT * FROM \"posts\" WHERE to_tsvector('simple', \"posts\".\"title\") @@ plainto_tsquery('simple', $1)", query)
+
+		policy := &QueryPolicy{Types: (*FieldRegistry)(nil).WithSearchOptions(WithSearchConfig(PostgresSearchConfigEnglish))}
+		err, selectors = policy.BuildFilterSelector(`{"title__search":"hello"}`, "")
+		require.NoError(t, err)
+		s = sql.Dialect(dialect.Postgres).Select("*").From(sql.Table("posts"))
+		for _, fnc := range selectors {
+			fnc(s)
+		}
+		query, _ = s.Query()
+		require.Equal(t, "SELECT * FROM \"posts\" WHERE to_tsvector('english', \"posts\".\"title\") @@ plainto_tsquery('english', $1)", query)
+	})
+	t.Run("MySQL_FilterSearch", func(t *testing.T) {
+		s := sql.Dialect(dialect.MySQL).Select("*").From(sql.Table("posts"))
+		s.Where(filterSearch(s, sql.P(), "title", "hello", SearchOptions{}))
+		BuildSearchRankSelector("title", "hello")(s)
+
+		query, args := s.Query()
+		require.Equal(t, "SELECT * FROM `posts` WHERE MATCH(`posts`.`title`) AGAINST(? IN NATURAL LANGUAGE MODE) ORDER BY MATCH(`posts`.`title`) AGAINST(? IN NATURAL LANGUAGE MODE) DESC", query)
+		require.Equal(t, []any{"hello", "hello"}, args)
+	})
+	t.Run("SQLite_FilterSearch", func(t *testing.T) {
+		s := sql.Dialect(dialect.SQLite).Select("*").From(sql.Table("posts"))
+		s.Where(filterSearch(s, sql.P(), "title", "hello", SearchOptions{}))
+		BuildSearchRankSelector("title", "hello")(s)
+
+		query, args := s.Query()
+		require.Equal(t, "SELECT * FROM `posts` WHERE `posts`.`title` MATCH ? ORDER BY bm25(`posts`)", query)
+		require.Equal(t, []any{"hello"}, args)
+	})
+	t.Run("Unsupported_FilterSearch", func(t *testing.T) {
+		s := sql.Dialect(dialect.Gremlin).Select("*").From(sql.Table("posts"))
+		s.Where(filterSearch(s, sql.P(), "title", "hello", SearchOptions{}))
+
+		_, _ = s.Query()
+		require.Error(t, s.Err())
+	})
+	t.Run("InvalidSearchConfig", func(t *testing.T) {
+		opts := SearchOptions{PostgresConfig: "simple'); DROP TABLE posts; --"}
+		s := sql.Dialect(dialect.Postgres).Select("*").From(sql.Table("posts"))
+		s.Where(filterSearch(s, sql.P(), "title", "hello", opts))
+		_, _ = s.Query()
+		require.Error(t, s.Err())
+
+		s = sql.Dialect(dialect.Postgres).Select("*").From(sql.Table("posts"))
+		BuildSearchRankSelector("title", "hello", WithSearchConfig(opts.PostgresConfig))(s)
+		_, _ = s.Query()
+		require.Error(t, s.Err())
+	})
+}
@@ -0,0 +1,220 @@
+package entgo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"entgo.io/ent/dialect/sql"
+
+	commonV1 "github.com/heyinLab/common/api/gen/go/common"
+	paging "github.com/heyinLab/common/pkg/utils/pagination"
+)
+
+// PageResult 分页查询结果
+type PageResult[T any] struct {
+	Items    []T
+	Total    int
+	Page     int32 // 当前页码，不分页时为 0
+	PageSize int32 // 每页的行数，不分页时为 0
+	HasNext  bool
+}
+
+// ToProto 转换为通用分页信息
+func (r *PageResult[T]) ToProto() *commonV1.PagingResponse {
+	return &commonV1.PagingResponse{
+		Total:    uint64(r.Total),
+		Page:     r.Page,
+		PageSize: r.PageSize,
+		HasNext:  r.HasNext,
+		HasPrev:  r.Page > 1,
+	}
+}
+
+// PageOption 分页查询选项
+type PageOption func(*pageOptions)
+
+type pageOptions struct {
+	concurrent     bool
+	skipShortCount bool
+}
+
+// WithConcurrentCount 并发执行分页查询和总数查询
+//
+// context 由 WithSequentialQuery 生成时（如 EntClient.WithTx 的事务中）仍按顺序执行；
+// query 来自 ent 生成的 Tx 时不要使用，事务不能并发执行查询。
+func WithConcurrentCount() PageOption {
+	return func(o *pageOptions) {
+		o.concurrent = true
+	}
+}
+
+// WithSkipCountOnShortPage 当前页不满一页时，根据偏移量和本页行数推算总数，不再执行总数查询
+//
+// 与 WithConcurrentCount 同时使用时，总数查询已并发执行，该选项不生效。
+func WithSkipCountOnShortPage() PageOption {
+	return func(o *pageOptions) {
+		o.skipShortCount = true
+	}
+}
+
+type sequentialQueryKey struct{}
+
+// WithSequentialQuery 返回不并发执行查询的 context，context 中的数据库连接不能并发使用时调用，如事务中
+func WithSequentialQuery(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sequentialQueryKey{}, true)
+}
+
+// IsSequentialQuery 判断是否不能并发执行查询
+func IsSequentialQuery(ctx context.Context) bool {
+	sequential, _ := ctx.Value(sequentialQueryKey{}).(bool)
+	return sequential
+}
+
+// QueryPage 执行分页查询和总数查询，返回当前页的数据、总数以及是否有下一页
+//
+// query 为 ent 生成的 XxxQuery，需要有 Clone、Where、All、Count 方法。
+// querySelectors 和 whereSelectors 为 BuildQuerySelector 的返回值，分页查询使用 querySelectors，
+// 总数查询仅使用 whereSelectors，不包含排序、分页和字段选择。
+// 不分页时不执行总数查询，总数为返回的行数。
+//
+// 使用示例:
+//
+//	err, whereSelectors, querySelectors := entgo.BuildQuerySelector(...)
+//	result, err := entgo.QueryPage[*ent.User](ctx, client.User.Query(), whereSelectors, querySelectors,
+//	    req.GetPage(), req.GetPageSize(), req.GetNoPaging(),
+//	    entgo.WithSkipCountOnShortPage(),
+//	)
+func QueryPage[T any](
+	ctx context.Context,
+	query any,
+	whereSelectors, querySelectors []func(s *sql.Selector),
+	page, pageSize int32, noPaging bool,
+	opts ...PageOption,
+) (*PageResult[T], error) {
+	o := &pageOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if IsSequentialQuery(ctx) {
+		o.concurrent = false
+	}
+
+	q := reflect.ValueOf(query)
+	for _, name := range []string{"Clone", "Where", "All", "Count"} {
+		if !q.IsValid() || !q.MethodByName(name).IsValid() {
+			return nil, fmt.Errorf("entgo: query %T has no %s method", query, name)
+		}
+	}
+
+	result := &PageResult[T]{}
+
+	if noPaging {
+		items, err := queryAll[T](ctx, q, querySelectors)
+		if err != nil {
+			return nil, err
+		}
+		result.Items = items
+		result.Total = len(items)
+		return result, nil
+	}
+
+	if page < 1 {
+		page = paging.DefaultPage
+	}
+	if pageSize < 1 {
+		pageSize = paging.DefaultPageSize
+	}
+	result.Page = page
+	result.PageSize = pageSize
+	offset := paging.GetPageOffset(page, pageSize)
+
+	var (
+		countErr error
+		wg       sync.WaitGroup
+	)
+	if o.concurrent {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result.Total, countErr = queryCount(ctx, q, whereSelectors)
+		}()
+	}
+
+	items, err := queryAll[T](ctx, q, querySelectors)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	if countErr != nil {
+		return nil, countErr
+	}
+	result.Items = items
+
+	switch {
+	case o.concurrent:
+	case o.skipShortCount && len(items) < int(pageSize) && (len(items) > 0 || offset == 0):
+		// 不满一页时，总数即为偏移量加本页行数；越界的空页无法推算，仍需查询
+		result.Total = offset + len(items)
+	default:
+		if result.Total, err = queryCount(ctx, q, whereSelectors); err != nil {
+			return nil, err
+		}
+	}
+
+	result.HasNext = offset+len(items) < result.Total
+
+	return result, nil
+}
+
+// queryAll 克隆查询并应用选择器后执行 All
+func queryAll[T any](ctx context.Context, q reflect.Value, selectors []func(s *sql.Selector)) ([]T, error) {
+	out, err := callQuery(ctx, q, selectors, "All")
+	if err != nil {
+		return nil, err
+	}
+	items, ok := out.Interface().([]T)
+	if !ok {
+		return nil, fmt.Errorf("entgo: query returns %s, not %T", out.Type(), items)
+	}
+	return items, nil
+}
+
+// queryCount 克隆查询并应用选择器后执行 Count
+func queryCount(ctx context.Context, q reflect.Value, selectors []func(s *sql.Selector)) (int, error) {
+	out, err := callQuery(ctx, q, selectors, "Count")
+	if err != nil {
+		return 0, err
+	}
+	return int(out.Int()), nil
+}
+
+// callQuery 克隆查询，将选择器转换为 Where 方法的谓词类型（如 predicate.User）后调用，再执行指定的方法
+func callQuery(ctx context.Context, q reflect.Value, selectors []func(s *sql.Selector), method string) (reflect.Value, error) {
+	q = q.MethodByName("Clone").Call(nil)[0]
+
+	if len(selectors) > 0 {
+		where := q.MethodByName("Where")
+		if !where.Type().IsVariadic() {
+			return reflect.Value{}, fmt.Errorf("entgo: unexpected Where method %s", where.Type())
+		}
+		predicateType := where.Type().In(0).Elem()
+
+		predicates := reflect.MakeSlice(where.Type().In(0), 0, len(selectors))
+		for _, selector := range selectors {
+			v := reflect.ValueOf(selector)
+			if !v.Type().ConvertibleTo(predicateType) {
+				return reflect.Value{}, fmt.Errorf("entgo: cannot use selector as %s", predicateType)
+			}
+			predicates = reflect.Append(predicates, v.Convert(predicateType))
+		}
+		q = where.CallSlice([]reflect.Value{predicates})[0]
+	}
+
+	out := q.MethodByName(method).Call([]reflect.Value{reflect.ValueOf(ctx)})
+	if err, _ := out[1].Interface().(error); err != nil {
+		return reflect.Value{}, err
+	}
+	return out[0], nil
+}
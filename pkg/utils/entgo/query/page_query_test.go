@@ -0,0 +1,139 @@
+package entgo
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"github.com/stretchr/testify/require"
+)
+
+// testPredicate 模拟 ent 生成的 predicate.Xxx
+type testPredicate func(*sql.Selector)
+
+type testPageRow struct{ ID int }
+
+// testPageQuery 模拟 ent 生成的 XxxQuery
+type testPageQuery struct {
+	mu         *sync.Mutex
+	log        *[]string
+	predicates []testPredicate
+	rows       []*testPageRow
+	total      int
+}
+
+func (q *testPageQuery) Clone() *testPageQuery {
+	c := *q
+	c.predicates = append([]testPredicate{}, q.predicates...)
+	return &c
+}
+
+func (q *testPageQuery) Where(ps ...testPredicate) *testPageQuery {
+	q.predicates = append(q.predicates, ps...)
+	return q
+}
+
+func (q *testPageQuery) sql() string {
+	s := sql.Dialect(dialect.MySQL).Select("*").From(sql.Table("users"))
+	for _, p := range q.predicates {
+		p(s)
+	}
+	query, _ := s.Query()
+
+	q.mu.Lock()
+	*q.log = append(*q.log, query)
+	q.mu.Unlock()
+	return query
+}
+
+func (q *testPageQuery) All(context.Context) ([]*testPageRow, error) {
+	q.sql()
+	return q.rows, nil
+}
+
+func (q *testPageQuery) Count(context.Context) (int, error) {
+	q.sql()
+	return q.total, nil
+}
+
+func newTestPageQuery(rows, total int) (*testPageQuery, *[]string) {
+	log := &[]string{}
+	q := &testPageQuery{mu: &sync.Mutex{}, log: log, total: total}
+	for i := 0; i < rows; i++ {
+		q.rows = append(q.rows, &testPageRow{ID: i + 1})
+	}
+	return q, log
+}
+
+func TestQueryPage(t *testing.T) {
+	err, whereSelectors, querySelectors := BuildQuerySelector(`{"status":"ON"}`, "", 2, 10, false, nil, "id", nil)
+	require.NoError(t, err)
+
+	q, log := newTestPageQuery(10, 25)
+	result, err := QueryPage[*testPageRow](context.Background(), q, whereSelectors, querySelectors, 2, 10, false)
+	require.NoError(t, err)
+	require.Len(t, result.Items, 10)
+	require.Equal(t, 25, result.Total)
+	require.True(t, result.HasNext)
+	require.Equal(t, []string{
+		"SELECT * FROM `users` WHERE `users`.`status` = ? ORDER BY `users`.`id` DESC LIMIT 10 OFFSET 10",
+		"SELECT * FROM `users` WHERE `users`.`status` = ?",
+	}, *log)
+	require.Empty(t, q.predicates)
+
+	proto := result.ToProto()
+	require.Equal(t, uint64(25), proto.GetTotal())
+	require.True(t, proto.GetHasPrev())
+
+	// 不满一页时跳过总数查询
+	q, log = newTestPageQuery(5, 25)
+	result, err = QueryPage[*testPageRow](context.Background(), q, whereSelectors, querySelectors, 3, 10, false, WithSkipCountOnShortPage())
+	require.NoError(t, err)
+	require.Equal(t, 25, result.Total)
+	require.False(t, result.HasNext)
+	require.Len(t, *log, 1)
+
+	// 越界的空页仍需查询总数
+	q, log = newTestPageQuery(0, 25)
+	result, err = QueryPage[*testPageRow](context.Background(), q, whereSelectors, querySelectors, 5, 10, false, WithSkipCountOnShortPage())
+	require.NoError(t, err)
+	require.Equal(t, 25, result.Total)
+	require.Len(t, *log, 2)
+
+	// 并发
+	q, log = newTestPageQuery(10, 30)
+	result, err = QueryPage[*testPageRow](context.Background(), q, whereSelectors, querySelectors, 3, 10, false, WithConcurrentCount())
+	require.NoError(t, err)
+	require.Equal(t, 30, result.Total)
+	require.False(t, result.HasNext)
+	require.Len(t, *log, 2)
+
+	// 不能并发时按顺序执行
+	q, log = newTestPageQuery(10, 30)
+	result, err = QueryPage[*testPageRow](WithSequentialQuery(context.Background()), q, whereSelectors, querySelectors, 3, 10, false, WithConcurrentCount())
+	require.NoError(t, err)
+	require.Equal(t, 30, result.Total)
+	require.Equal(t, []string{
+		"SELECT * FROM `users` WHERE `users`.`status` = ? ORDER BY `users`.`id` DESC LIMIT 10 OFFSET 10",
+		"SELECT * FROM `users` WHERE `users`.`status` = ?",
+	}, *log)
+
+	// 不分页
+	q, log = newTestPageQuery(3, 100)
+	result, err = QueryPage[*testPageRow](context.Background(), q, whereSelectors, querySelectors, 0, 0, true)
+	require.NoError(t, err)
+	require.Equal(t, 3, result.Total)
+	require.Zero(t, result.Page)
+	require.Len(t, *log, 1)
+}
+
+func TestQueryPageInvalid(t *testing.T) {
+	_, err := QueryPage[*testPageRow](context.Background(), struct{}{}, nil, nil, 1, 10, false)
+	require.Error(t, err)
+
+	q, _ := newTestPageQuery(1, 1)
+	_, err = QueryPage[string](context.Background(), q, nil, nil, 1, 10, false)
+	require.Error(t, err)
+}
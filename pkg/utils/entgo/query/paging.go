@@ -0,0 +1,82 @@
+package entgo
+
+import (
+	"entgo.io/ent/dialect/sql"
+
+	commonV1 "github.com/heyinLab/common/api/gen/go/common"
+	paging "github.com/heyinLab/common/pkg/utils/pagination"
+)
+
+// BuildPagingSelector 将通用列表查询请求转换为分页过滤查询器，见 BuildQuerySelector
+func BuildPagingSelector(req *commonV1.PagingRequest, defaultOrderField string) (err error, whereSelectors []func(s *sql.Selector), querySelectors []func(s *sql.Selector)) {
+	return (*QueryPolicy)(nil).BuildPagingSelector(req, defaultOrderField)
+}
+
+// BuildPagingSelector 按策略校验通用列表查询请求并转换为分页过滤查询器
+//
+// 使用示例:
+//
+//	err, whereSelectors, querySelectors := userQueryPolicy.BuildPagingSelector(req.GetPaging(), "create_time")
+func (p *QueryPolicy) BuildPagingSelector(req *commonV1.PagingRequest, defaultOrderField string) (err error, whereSelectors []func(s *sql.Selector), querySelectors []func(s *sql.Selector)) {
+	return p.BuildQuerySelector(
+		req.GetQuery(), req.GetOrQuery(),
+		req.GetPage(), req.GetPageSize(), req.GetNoPaging(),
+		req.GetOrderBy(), defaultOrderField,
+		req.GetFieldMask().GetPaths(),
+	)
+}
+
+// ParsePagingCursor 按策略校验通用列表查询请求中的游标和排序条件，并创建游标分页查询
+func (p *QueryPolicy) ParsePagingCursor(codec *CursorCodec, req *commonV1.PagingRequest, defaultOrderField string) (error, *CursorQuery) {
+	return p.ParseCursor(codec, req.GetCursor(), req.GetPageSize(), req.GetOrderBy(), defaultOrderField)
+}
+
+// NewPagingResponse 根据通用列表查询请求和总行数构建分页信息
+func NewPagingResponse(req *commonV1.PagingRequest, total int) *commonV1.PagingResponse {
+	if total < 0 {
+		total = 0
+	}
+
+	resp := &commonV1.PagingResponse{
+		Total: uint64(total),
+	}
+	if req.GetNoPaging() {
+		return resp
+	}
+
+	page, pageSize := req.GetPage(), req.GetPageSize()
+	if page < 1 {
+		page = paging.DefaultPage
+	}
+	if pageSize < 1 {
+		pageSize = paging.DefaultPageSize
+	}
+
+	resp.Page = page
+	resp.PageSize = pageSize
+	resp.HasPrev = page > 1
+	resp.HasNext = int64(page)*int64(pageSize) < int64(total)
+
+	return resp
+}
+
+// NewCursorPagingResponse 根据游标分页信息构建分页信息
+func NewCursorPagingResponse(info *CursorPageInfo) *commonV1.PagingResponse {
+	if info == nil {
+		return &commonV1.PagingResponse{}
+	}
+
+	resp := &commonV1.PagingResponse{
+		PageSize: info.PageSize,
+		HasNext:  info.HasNext,
+		HasPrev:  info.HasPrev,
+	}
+	if info.NextCursor != "" {
+		resp.NextCursor = &info.NextCursor
+	}
+	if info.PrevCursor != "" {
+		resp.PrevCursor = &info.PrevCursor
+	}
+
+	return resp
+}
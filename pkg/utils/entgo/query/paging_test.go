@@ -0,0 +1,62 @@
+package entgo
+
+import (
+	"testing"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	commonV1 "github.com/heyinLab/common/api/gen/go/common"
+)
+
+func TestBuildPagingSelector(t *testing.T) {
+	req := &commonV1.PagingRequest{
+		Page:      proto.Int32(2),
+		PageSize:  proto.Int32(20),
+		Query:     proto.String(`{"status":"ON"}`),
+		OrQuery:   proto.String(`[{"name":"a"},{"name":"b"}]`),
+		OrderBy:   []string{"-create_time"},
+		FieldMask: &fieldmaskpb.FieldMask{Paths: []string{"id", "name"}},
+	}
+
+	err, whereSelectors, querySelectors := BuildPagingSelector(req, "id")
+	require.NoError(t, err)
+	require.Len(t, whereSelectors, 2)
+
+	s := sql.Dialect(dialect.MySQL).Select("*").From(sql.Table("users"))
+	for _, fnc := range querySelectors {
+		fnc(s)
+	}
+	query, _ := s.Query()
+	require.Equal(t, "SELECT `id`, `name` FROM `users` WHERE `users`.`status` = ? AND (`users`.`name` = ? OR `users`.`name` = ?) ORDER BY `users`.`create_time` DESC LIMIT 20 OFFSET 20", query)
+
+	policy := &QueryPolicy{Fields: map[string]FieldPolicy{"status": {}}, MaxPageSize: 10}
+	err, _, _ = policy.BuildPagingSelector(req, "id")
+	require.Error(t, err)
+}
+
+func TestNewPagingResponse(t *testing.T) {
+	resp := NewPagingResponse(&commonV1.PagingRequest{Page: proto.Int32(2), PageSize: proto.Int32(10)}, 25)
+	require.Equal(t, uint64(25), resp.GetTotal())
+	require.Equal(t, int32(2), resp.GetPage())
+	require.Equal(t, int32(10), resp.GetPageSize())
+	require.True(t, resp.GetHasNext())
+	require.True(t, resp.GetHasPrev())
+
+	resp = NewPagingResponse(nil, 10)
+	require.Equal(t, int32(1), resp.GetPage())
+	require.False(t, resp.GetHasNext())
+	require.False(t, resp.GetHasPrev())
+
+	resp = NewPagingResponse(&commonV1.PagingRequest{NoPaging: proto.Bool(true)}, 100)
+	require.Equal(t, uint64(100), resp.GetTotal())
+	require.Zero(t, resp.GetPage())
+	require.False(t, resp.GetHasNext())
+
+	resp = NewCursorPagingResponse(&CursorPageInfo{PageSize: 10, HasNext: true, NextCursor: "next"})
+	require.Equal(t, "next", resp.GetNextCursor())
+	require.Nil(t, resp.PrevCursor)
+}
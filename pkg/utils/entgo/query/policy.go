@@ -0,0 +1,390 @@
+package entgo
+
+import (
+	"fmt"
+	"strings"
+
+	"entgo.io/ent/dialect/sql"
+	"github.com/go-kratos/kratos/v2/errors"
+
+	businessErrors "github.com/heyinLab/common/pkg/errors"
+	"github.com/heyinLab/common/pkg/utils/query_parser"
+	"github.com/heyinLab/common/pkg/utils/stringcase"
+)
+
+// AnyOperator 允许字段使用所有操作符和日期部分
+const AnyOperator = "*"
+
+// FieldPolicy 单个字段的查询策略
+type FieldPolicy struct {
+	Column    string   // 数据库列名，为空时使用字段名的 snake_case，可用于字段别名
+	Operators []string // 允许的操作符和日期部分，等值查询始终允许，AnyOperator 表示全部允许
+	Sortable  bool     // 是否允许排序
+	JSON      bool     // 是否允许按 JSON 子字段过滤
+
+	Groupable  bool     // 是否允许在聚合查询中分组
+	Aggregates []string // 允许的聚合函数，如 sum、count_distinct，AnyOperator 表示全部允许
+}
+
+// QueryPolicy 实体的列表查询策略，限制客户端可以过滤和排序的字段以及查询开销
+//
+// Fields 不为 nil 时，未登记的字段不能用于过滤和排序；各项上限为 0 时不限制。
+// 设置 Types 后，过滤值按字段类型转换，无法转换的值返回参数错误。
+//
+// 使用示例:
+//
+//	var userQueryPolicy = &entgo.QueryPolicy{
+//	    Fields: map[string]entgo.FieldPolicy{
+//	        "name":      {Operators: []string{"icontains", "startswith"}},
+//	        "status":    {Operators: []string{"in"}},
+//	        "createdAt": {Column: "create_time", Operators: []string{"gte", "lte", "range"}, Sortable: true},
+//	    },
+//	    MaxConditions: 10,
+//	    MaxPageSize:   100,
+//	    Types:         entgo.NewFieldRegistryFromSchema(schema.User{}),
+//	}
+//
+//	err, whereSelectors, querySelectors := userQueryPolicy.BuildQuerySelector(...)
+type QueryPolicy struct {
+	Fields map[string]FieldPolicy
+	Types  *FieldRegistry // 字段类型登记表，字段名为数据库列名
+
+	MaxConditions  int   // 过滤条件的最大数量，包含 AND 和 OR 过滤条件
+	MaxPageSize    int32 // 每页的最大行数
+	AllowNoPaging  bool  // 设置 MaxPageSize 后是否仍允许不分页
+	MaxRegexLength int   // regex、iregex 查询值的最大长度
+}
+
+// BuildQuerySelector 按策略校验并构建分页过滤查询器，p 为 nil 时不做限制
+func (p *QueryPolicy) BuildQuerySelector(
+	andFilterJsonString, orFilterJsonString string,
+	page, pageSize int32, noPaging bool,
+	orderBys []string, defaultOrderField string,
+	selectFields []string,
+) (err error, whereSelectors []func(s *sql.Selector), querySelectors []func(s *sql.Selector)) {
+	if err = p.CheckPaging(pageSize, noPaging); err != nil {
+		return err, nil, nil
+	}
+
+	err, whereSelectors = p.BuildFilterSelector(andFilterJsonString, orFilterJsonString)
+	if err != nil {
+		return err, nil, nil
+	}
+
+	var orderSelector func(s *sql.Selector)
+	err, orderSelector = p.BuildOrderSelector(orderBys, defaultOrderField)
+	if err != nil {
+		return err, nil, nil
+	}
+
+	pageSelector := BuildPaginationSelector(page, pageSize, noPaging)
+
+	err, selectFields = p.SelectColumns(selectFields)
+	if err != nil {
+		return err, nil, nil
+	}
+
+	var fieldSelector func(s *sql.Selector)
+	err, fieldSelector = BuildFieldSelector(selectFields)
+
+	if len(whereSelectors) > 0 {
+		querySelectors = append(querySelectors, whereSelectors...)
+	}
+
+	if orderSelector != nil {
+		querySelectors = append(querySelectors, orderSelector)
+	}
+	if pageSelector != nil {
+		querySelectors = append(querySelectors, pageSelector)
+	}
+	if fieldSelector != nil {
+		querySelectors = append(querySelectors, fieldSelector)
+	}
+
+	return
+}
+
+// BuildFilterSelector 按策略校验并构建过滤选择器，字段别名会被替换为实际的列名
+func (p *QueryPolicy) BuildFilterSelector(andFilterJsonString, orFilterJsonString string) (error, []func(s *sql.Selector)) {
+	err, andExpr, orExpr := p.ParseFilters(andFilterJsonString, orFilterJsonString)
+	if err != nil {
+		return err, nil
+	}
+
+	var queryConditions []func(s *sql.Selector)
+	if andExpr != nil {
+		queryConditions = append(queryConditions, p.types().whereConditionsSelector(andExpr, false))
+	}
+	if orExpr != nil {
+		queryConditions = append(queryConditions, p.types().whereConditionsSelector(orExpr, true))
+	}
+
+	return nil, queryConditions
+}
+
+// ParseFilters 解析 AND、OR 过滤条件并按策略校验，字段别名会被替换为实际的列名，条件为空时返回 nil
+//
+// 供其他存储的查询构建器复用同样的过滤语法和校验规则，如 mongo.BuildQuery。
+func (p *QueryPolicy) ParseFilters(andFilterJsonString, orFilterJsonString string) (err error, andExpr, orExpr *query_parser.FilterExpr) {
+	if andExpr, err = parseFilterCommand(andFilterJsonString); err != nil {
+		return err, nil, nil
+	}
+	if orExpr, err = parseFilterCommand(orFilterJsonString); err != nil {
+		return err, nil, nil
+	}
+
+	if err = p.checkFilters(andExpr, orExpr); err != nil {
+		return err, nil, nil
+	}
+	return nil, andExpr, orExpr
+}
+
+// BuildOrderSelector 按策略校验并构建排序选择器，默认排序字段由服务端指定，不做校验
+func (p *QueryPolicy) BuildOrderSelector(orderBys []string, defaultOrderField string) (error, func(s *sql.Selector)) {
+	if p == nil || len(orderBys) == 0 {
+		return BuildOrderSelector(orderBys, defaultOrderField)
+	}
+
+	err, columns := p.OrderColumns(orderBys)
+	if err != nil {
+		return err, nil
+	}
+
+	return QueryCommandToOrderConditions(columns)
+}
+
+// ParseCursor 按策略校验每页行数和排序字段，并创建游标分页查询，见 CursorCodec.Parse
+func (p *QueryPolicy) ParseCursor(codec *CursorCodec, cursor string, pageSize int32, orderBys []string, defaultOrderField string) (error, *CursorQuery) {
+	if err := p.CheckPaging(pageSize, false); err != nil {
+		return err, nil
+	}
+
+	if p != nil {
+		var err error
+		if err, orderBys = p.OrderColumns(orderBys); err != nil {
+			return err, nil
+		}
+	}
+
+	err, q := codec.Parse(cursor, pageSize, orderBys, defaultOrderField)
+	if errors.Is(err, ErrInvalidCursor) {
+		return newPolicyError("cursor", err.Error()), nil
+	}
+	return err, q
+}
+
+// OrderColumns 按策略校验排序字段并替换为实际的列名，保留降序前缀
+func (p *QueryPolicy) OrderColumns(orderBys []string) (error, []string) {
+	columns := make([]string, 0, len(orderBys))
+	for _, v := range orderBys {
+		desc := strings.HasPrefix(v, "-")
+		field := strings.TrimPrefix(v, "-")
+		if len(field) == 0 {
+			continue
+		}
+
+		fp, ok := p.lookupField(field)
+		if !ok {
+			return newPolicyError(field, "field is not allowed"), nil
+		}
+		if !fp.Sortable {
+			return newPolicyError(field, "field is not sortable"), nil
+		}
+
+		column := fp.column(field)
+		if desc {
+			column = "-" + column
+		}
+		columns = append(columns, column)
+	}
+
+	return nil, columns
+}
+
+// SelectColumns 按策略校验查询字段并替换为实际的列名，p 为 nil 时原样返回
+func (p *QueryPolicy) SelectColumns(fields []string) (error, []string) {
+	if p == nil {
+		return nil, fields
+	}
+
+	columns := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if len(field) == 0 {
+			continue
+		}
+
+		fp, ok := p.lookupField(field)
+		if !ok {
+			return newPolicyError(field, "field is not allowed"), nil
+		}
+		columns = append(columns, fp.column(field))
+	}
+
+	return nil, columns
+}
+
+// CheckPaging 校验分页参数，p 为 nil 时不做限制
+func (p *QueryPolicy) CheckPaging(pageSize int32, noPaging bool) error {
+	if p == nil || p.MaxPageSize <= 0 {
+		return nil
+	}
+	if noPaging {
+		if p.AllowNoPaging {
+			return nil
+		}
+		return newPolicyError("noPaging", "paging is required")
+	}
+	if pageSize > p.MaxPageSize {
+		return newPolicyError("pageSize", fmt.Sprintf("page size exceeds %d", p.MaxPageSize))
+	}
+	return nil
+}
+
+// checkFilters 校验过滤条件，并将叶子节点中的字段替换为实际的列名
+func (p *QueryPolicy) checkFilters(exprs ...*query_parser.FilterExpr) error {
+	if p == nil {
+		return nil
+	}
+
+	count := 0
+	for _, expr := range exprs {
+		if err := p.checkFilterExpr(expr, &count); err != nil {
+			return err
+		}
+	}
+
+	if p.MaxConditions > 0 && count > p.MaxConditions {
+		return newPolicyError("query", fmt.Sprintf("number of conditions exceeds %d", p.MaxConditions))
+	}
+
+	return nil
+}
+
+func (p *QueryPolicy) checkFilterExpr(expr *query_parser.FilterExpr, count *int) error {
+	if expr == nil {
+		return nil
+	}
+
+	if expr.Kind != query_parser.FilterExprLeaf {
+		for _, child := range expr.Children {
+			if err := p.checkFilterExpr(child, count); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	*count++
+
+	keys := splitQueryKey(expr.Key)
+	field, jsonField, isJson := strings.Cut(keys[0], JsonFieldDelimiter)
+
+	fp, ok := p.lookupField(field)
+	if !ok {
+		return newPolicyError(field, "field is not allowed")
+	}
+	if isJson && !fp.JSON {
+		return newPolicyError(field, "filtering on json fields is not allowed")
+	}
+
+	for _, op := range keys[1:] {
+		lower := strings.ToLower(op)
+		if !IsOperator(lower) && !IsDatePart(lower) {
+			// 既不是操作符也不是日期部分时，按 JSON 字段处理
+			if !fp.JSON {
+				return newPolicyError(field, fmt.Sprintf("operator %q is not allowed", op))
+			}
+			continue
+		}
+		if !fp.allows(lower) {
+			return newPolicyError(field, fmt.Sprintf("operator %q is not allowed", op))
+		}
+		if (lower == ops[FilterRegex] || lower == ops[FilterInsensitiveRegex]) &&
+			p.MaxRegexLength > 0 && len(expr.Value) > p.MaxRegexLength {
+			return newPolicyError(field, fmt.Sprintf("regex exceeds %d characters", p.MaxRegexLength))
+		}
+	}
+
+	keys[0] = fp.column(field)
+	if isJson {
+		keys[0] += JsonFieldDelimiter + jsonField
+	}
+	expr.Key = strings.Join(keys, QueryDelimiter)
+
+	if column, op, ok := typedFilterKey(keys); ok && len(expr.Value) > 0 {
+		if _, _, err := p.Types.coerceOp(column, op, expr.Value); err != nil {
+			return newPolicyError(field, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// types 返回字段类型登记表，p 为 nil 时返回 nil
+func (p *QueryPolicy) types() *FieldRegistry {
+	if p == nil {
+		return nil
+	}
+	return p.Types
+}
+
+// lookupField 先按原始字段名查找，再按 snake_case 比较，createdAt 与 created_at 视为同一字段
+//
+// p 或 Fields 为 nil 时不限制字段。
+func (p *QueryPolicy) lookupField(field string) (FieldPolicy, bool) {
+	if p == nil || p.Fields == nil {
+		return FieldPolicy{
+			Operators:  []string{AnyOperator},
+			Sortable:   true,
+			JSON:       true,
+			Groupable:  true,
+			Aggregates: []string{AnyOperator},
+		}, true
+	}
+	if fp, ok := p.Fields[field]; ok {
+		return fp, true
+	}
+
+	snake := stringcase.ToSnakeCase(field)
+	for name, fp := range p.Fields {
+		if stringcase.ToSnakeCase(name) == snake {
+			return fp, true
+		}
+	}
+	return FieldPolicy{}, false
+}
+
+func (fp FieldPolicy) column(field string) string {
+	if fp.Column != "" {
+		return fp.Column
+	}
+	return stringcase.ToSnakeCase(field)
+}
+
+func (fp FieldPolicy) allows(op string) bool {
+	for _, item := range fp.Operators {
+		if item == AnyOperator || strings.ToLower(item) == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (fp FieldPolicy) allowsAggregate(fn string) bool {
+	for _, item := range fp.Aggregates {
+		if item == AnyOperator || strings.ToLower(item) == fn {
+			return true
+		}
+	}
+	return false
+}
+
+// newPolicyError 构建违反查询策略的参数错误
+func newPolicyError(field, reason string) error {
+	be := businessErrors.ErrInvalidParameter
+	return errors.New(int(be.HttpCode), be.Type, fmt.Sprintf("invalid query %s: %s", field, reason)).
+		WithMetadata(map[string]string{
+			"field":  field,
+			"reason": reason,
+		})
+}
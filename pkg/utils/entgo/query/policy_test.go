@@ -0,0 +1,97 @@
+package entgo
+
+import (
+	"testing"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func testQueryPolicy() *QueryPolicy {
+	return &QueryPolicy{
+		Fields: map[string]FieldPolicy{
+			"name":      {Operators: []string{"icontains", "regex"}},
+			"status":    {Operators: []string{"in"}},
+			"meta":      {JSON: true},
+			"createdAt": {Column: "create_time", Operators: []string{AnyOperator}, Sortable: true},
+		},
+		MaxConditions:  3,
+		MaxPageSize:    100,
+		MaxRegexLength: 8,
+	}
+}
+
+func TestQueryPolicyBuildQuerySelector(t *testing.T) {
+	policy := testQueryPolicy()
+
+	err, _, querySelectors := policy.BuildQuerySelector(
+		`{"name__icontains":"x","created_at__year__gte":"2023"}`, `{"meta.color":"red"}`,
+		2, 20, false,
+		[]string{"-createdAt"}, "id",
+		[]string{"name", "createdAt"},
+	)
+	require.NoError(t, err)
+
+	s := sql.Dialect(dialect.MySQL).Select("*").From(sql.Table("users"))
+	for _, fnc := range querySelectors {
+		fnc(s)
+	}
+	query, _ := s.Query()
+	require.Equal(t, "SELECT `name`, `create_time` FROM `users` WHERE (`users`.`name` COLLATE utf8mb4_general_ci LIKE ? AND YEAR(`users`.`create_time`) >= ?) AND JSON_EXTRACT(`users`.`meta`, '$.color') = ? ORDER BY `users`.`create_time` DESC LIMIT 20 OFFSET 20", query)
+}
+
+func TestQueryPolicyViolations(t *testing.T) {
+	policy := testQueryPolicy()
+
+	testcases := []struct {
+		name     string
+		and      string
+		or       string
+		pageSize int32
+		noPaging bool
+		orderBys []string
+		field    string
+	}{
+		{"UnknownField", `{"password":"x"}`, "", 10, false, nil, "password"},
+		{"NestedUnknownField", `{"or":[{"name":"a"},{"not":{"password__startswith":"x"}}]}`, "", 10, false, nil, "password"},
+		{"OperatorNotAllowed", `{"status__startswith":"x"}`, "", 10, false, nil, "status"},
+		{"JsonNotAllowed", `{"name.first":"x"}`, "", 10, false, nil, "name"},
+		{"JsonKeyNotAllowed", `{"status__first":"x"}`, "", 10, false, nil, "status"},
+		{"RegexTooLong", `{"name__regex":"^(a|b|c)+$"}`, "", 10, false, nil, "name"},
+		{"TooManyConditions", `{"name":"a","status":"b"}`, `[{"name":"c"},{"name":"d"}]`, 10, false, nil, "query"},
+		{"PageSizeTooLarge", "", "", 101, false, nil, "pageSize"},
+		{"NoPaging", "", "", 10, true, nil, "noPaging"},
+		{"OrderUnknownField", "", "", 10, false, []string{"password"}, "password"},
+		{"OrderNotSortable", "", "", 10, false, []string{"-name"}, "name"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err, _, _ := policy.BuildQuerySelector(tc.and, tc.or, 1, tc.pageSize, tc.noPaging, tc.orderBys, "id", nil)
+			require.Error(t, err)
+
+			e := errors.FromError(err)
+			require.Equal(t, 400, int(e.Code))
+			require.Equal(t, "INVALID_PARAMETER", e.Reason)
+			require.Equal(t, tc.field, e.Metadata["field"])
+		})
+	}
+
+	// 查询字段
+	err, _, _ := policy.BuildQuerySelector("", "", 1, 10, false, nil, "id", []string{"name", "password"})
+	require.Error(t, err)
+	require.Equal(t, "password", errors.FromError(err).Metadata["field"])
+
+	policy.AllowNoPaging = true
+	err, _, _ = policy.BuildQuerySelector("", "", 1, 10, true, nil, "id", nil)
+	require.NoError(t, err)
+}
+
+func TestNilQueryPolicy(t *testing.T) {
+	var policy *QueryPolicy
+
+	err, whereSelectors, _ := policy.BuildQuerySelector(`{"password":"x"}`, "", 1, 1000, false, []string{"secret"}, "id", nil)
+	require.NoError(t, err)
+	require.Len(t, whereSelectors, 1)
+}
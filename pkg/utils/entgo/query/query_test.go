@@ -12,6 +12,9 @@ import (
 	_ "github.com/go-kratos/kratos/v2/encoding/json"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/heyinLab/common/pkg/utils/query_parser"
 )
 
 func TestKratosJsonCodec(t *testing.T) {
@@ -152,3 +155,40 @@ func TestBuildQuerySelectorDefault(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildFilterSelectorNested(t *testing.T) {
+	err, selectors := BuildFilterSelector(`{"status":"ON","or":[{"name__icontains":"x"},{"code__startswith":"x"}],"not":{"type":"3"}}`, "")
+	require.NoError(t, err)
+
+	s := sql.Dialect(dialect.MySQL).Select("*").From(sql.Table("users"))
+	for _, selector := range selectors {
+		selector(s)
+	}
+	query, args := s.Query()
+	require.Equal(t, "SELECT * FROM `users` WHERE `users`.`status` = ? AND (`users`.`name` COLLATE utf8mb4_general_ci LIKE ? OR `users`.`code` LIKE ?) AND (NOT (`users`.`type` = ?))", query)
+	require.Equal(t, []any{"ON", "%x%", "x%", "3"}, args)
+
+	// 原有的扁平或条件
+	err, selectors = BuildFilterSelector("", `[{"name":"a"},{"code":"b"}]`)
+	require.NoError(t, err)
+
+	s = sql.Dialect(dialect.MySQL).Select("*").From(sql.Table("users"))
+	for _, selector := range selectors {
+		selector(s)
+	}
+	query, _ = s.Query()
+	require.Equal(t, "SELECT * FROM `users` WHERE `users`.`name` = ? OR `users`.`code` = ?", query)
+}
+
+func TestBuildFilterExprSelector(t *testing.T) {
+	expr, err := query_parser.ParseFilterExprQueryString("status:ON,or(name__icontains:x,not(code__startswith:x))")
+	require.NoError(t, err)
+
+	s := sql.Dialect(dialect.Postgres).Select("*").From(sql.Table("users"))
+	BuildFilterExprSelector(expr)(s)
+	query, args := s.Query()
+	require.Equal(t, `SELECT * FROM "users" WHERE "users"."status" = $1 AND ("users"."name" ILIKE $2 OR (NOT ("users"."code" LIKE $3)))`, query)
+	require.Equal(t, []any{"ON", "%x%", "x%"}, args)
+
+	require.Nil(t, BuildFilterExprSelector(nil))
+}
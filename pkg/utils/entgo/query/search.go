@@ -0,0 +1,178 @@
+package entgo
+
+import (
+	"fmt"
+	"regexp"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+)
+
+// SearchMode 全文搜索模式
+type SearchMode int
+
+const (
+	// SearchModeNatural 自然语言模式
+	// PostgreSQL: plainto_tsquery，MySQL: IN NATURAL LANGUAGE MODE
+	SearchModeNatural SearchMode = iota
+	// SearchModeBoolean 布尔模式，支持引号、+、-、OR 等语法
+	// PostgreSQL: websearch_to_tsquery，MySQL: IN BOOLEAN MODE
+	SearchModeBoolean
+)
+
+// PostgreSQL 常用的全文搜索配置
+const (
+	PostgresSearchConfigSimple  = "simple"
+	PostgresSearchConfigEnglish = "english"
+	// PostgresSearchConfigChinese 基于 zhparser 的中文分词配置，需预先执行：
+	// CREATE EXTENSION zhparser;
+	// CREATE TEXT SEARCH CONFIGURATION chinese (PARSER = zhparser);
+	// ALTER TEXT SEARCH CONFIGURATION chinese ADD MAPPING FOR n,v,a,i,e,l WITH simple;
+	PostgresSearchConfigChinese = "chinese"
+	// PostgresSearchConfigJieba 基于 pg_jieba 的中文分词配置
+	PostgresSearchConfigJieba = "jiebacfg"
+)
+
+// SearchOptions 全文搜索选项
+type SearchOptions struct {
+	// PostgresConfig PostgreSQL 的全文搜索配置（regconfig），默认 simple
+	PostgresConfig string
+	// Mode 搜索模式，默认自然语言模式
+	Mode SearchMode
+}
+
+// SearchOption 全文搜索选项
+//
+// PostgreSQL 的表达式索引需与查询中的配置完全一致才能命中，例如：
+// CREATE INDEX ON posts USING GIN (to_tsvector('chinese', title));
+type SearchOption func(*SearchOptions)
+
+// WithSearchConfig 设置 PostgreSQL 的全文搜索配置，如 PostgresSearchConfigChinese
+func WithSearchConfig(config string) SearchOption {
+	return func(o *SearchOptions) {
+		o.PostgresConfig = config
+	}
+}
+
+// WithSearchMode 设置搜索模式
+func WithSearchMode(mode SearchMode) SearchOption {
+	return func(o *SearchOptions) {
+		o.Mode = mode
+	}
+}
+
+var searchConfigPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// newSearchOptions 应用全文搜索选项
+func newSearchOptions(opts ...SearchOption) SearchOptions {
+	var o SearchOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// postgresConfig 返回 PostgreSQL 的全文搜索配置，为空时使用 simple
+func (o SearchOptions) postgresConfig() string {
+	if o.PostgresConfig == "" {
+		return PostgresSearchConfigSimple
+	}
+	return o.PostgresConfig
+}
+
+// writeSearchMatch 写入全文搜索的匹配表达式
+// PostgreSQL: to_tsvector('simple', "title") @@ plainto_tsquery('simple', $1)
+// MySQL: MATCH(`title`) AGAINST(? IN NATURAL LANGUAGE MODE)
+// SQLite: `title` MATCH ?（FTS5 虚拟表）
+func writeSearchMatch(b *sql.Builder, s *sql.Selector, field, value string, opts SearchOptions) {
+	switch s.Builder.Dialect() {
+	case dialect.Postgres:
+		if !checkSearchConfig(b, opts) {
+			return
+		}
+		writeTsVector(b, s, field, opts)
+		b.WriteString(" @@ ")
+		writeTsQuery(b, value, opts)
+
+	case dialect.MySQL:
+		b.WriteString("MATCH(").Ident(s.C(field)).WriteString(") AGAINST(")
+		b.Arg(value)
+		if opts.Mode == SearchModeBoolean {
+			b.WriteString(" IN BOOLEAN MODE)")
+		} else {
+			b.WriteString(" IN NATURAL LANGUAGE MODE)")
+		}
+
+	case dialect.SQLite:
+		b.Ident(s.C(field)).WriteString(" MATCH ")
+		b.Arg(value)
+
+	default:
+		b.AddError(fmt.Errorf("full-text search is not supported for dialect %q", s.Builder.Dialect()))
+	}
+}
+
+// checkSearchConfig 检查 PostgreSQL 的全文搜索配置，配置直接写入 SQL，只允许标识符
+func checkSearchConfig(b *sql.Builder, opts SearchOptions) bool {
+	if !searchConfigPattern.MatchString(opts.postgresConfig()) {
+		b.AddError(fmt.Errorf("invalid postgres search config: %q", opts.PostgresConfig))
+		return false
+	}
+	return true
+}
+
+// writeTsVector PostgreSQL: to_tsvector('simple', "title")
+func writeTsVector(b *sql.Builder, s *sql.Selector, field string, opts SearchOptions) {
+	b.WriteString("to_tsvector('").WriteString(opts.postgresConfig()).WriteString("', ")
+	b.Ident(s.C(field))
+	b.WriteString(")")
+}
+
+// writeTsQuery PostgreSQL: plainto_tsquery('simple', $1) 或 websearch_to_tsquery('simple', $1)
+func writeTsQuery(b *sql.Builder, value string, opts SearchOptions) {
+	if opts.Mode == SearchModeBoolean {
+		b.WriteString("websearch_to_tsquery('")
+	} else {
+		b.WriteString("plainto_tsquery('")
+	}
+	b.WriteString(opts.postgresConfig()).WriteString("', ")
+	b.Arg(value)
+	b.WriteString(")")
+}
+
+// BuildSearchRankSelector 构建按全文搜索相关度降序排序的选择器，通常与 field__search 过滤条件配合使用
+// PostgreSQL: ORDER BY ts_rank(to_tsvector('simple', "title"), plainto_tsquery('simple', $1)) DESC
+// MySQL: ORDER BY MATCH(`title`) AGAINST(? IN NATURAL LANGUAGE MODE) DESC
+// SQLite: ORDER BY bm25(`posts`)（FTS5，值越小越相关）
+//
+// opts 需要与过滤条件使用的选项（FieldRegistry.WithSearchOptions）一致。
+func BuildSearchRankSelector(field, value string, opts ...SearchOption) func(s *sql.Selector) {
+	o := newSearchOptions(opts...)
+	return func(s *sql.Selector) {
+		s.OrderExpr(sql.ExprFunc(func(b *sql.Builder) {
+			switch s.Builder.Dialect() {
+			case dialect.Postgres:
+				// 排序表达式中 Builder 的错误不会传递给 Selector
+				if !searchConfigPattern.MatchString(o.postgresConfig()) {
+					s.AddError(fmt.Errorf("invalid postgres search config: %q", o.PostgresConfig))
+					return
+				}
+				b.WriteString("ts_rank(")
+				writeTsVector(b, s, field, o)
+				b.WriteString(", ")
+				writeTsQuery(b, value, o)
+				b.WriteString(") DESC")
+
+			case dialect.MySQL:
+				writeSearchMatch(b, s, field, value, o)
+				b.WriteString(" DESC")
+
+			case dialect.SQLite:
+				b.WriteString("bm25(").Ident(s.TableName()).WriteString(")")
+
+			default:
+				s.AddError(fmt.Errorf("full-text search is not supported for dialect %q", s.Builder.Dialect()))
+			}
+		}))
+	}
+}
@@ -0,0 +1,445 @@
+package entgo
+
+import (
+	"context"
+	stdsql "database/sql"
+	"errors"
+	"fmt"
+
+	"entgo.io/ent/dialect"
+	entSql "entgo.io/ent/dialect/sql"
+
+	"github.com/heyinLab/common/pkg/utils/entgo/mixin"
+)
+
+var (
+	// ErrTreeNodeNotFound 节点不存在或已被软删除
+	ErrTreeNodeNotFound = errors.New("tree node not found")
+	// ErrTreeCycle 移动节点后会形成环，即新的父节点是节点自身或其子孙节点
+	ErrTreeCycle = errors.New("tree node cannot be moved under itself or its descendants")
+	// ErrTreeMaxDepth 移动节点后树的层数超过限制
+	ErrTreeMaxDepth = errors.New("tree depth exceeds the limit")
+)
+
+const (
+	// treeCTE 递归查询使用的公用表名称
+	treeCTE = "tree_nodes"
+	// defaultTreeMaxDepth 未设置最大层数时递归查询的层数上限，避免数据中存在环时无限递归
+	defaultTreeMaxDepth = 256
+)
+
+// TreeNode 树节点
+type TreeNode[ID comparable] struct {
+	ID       ID
+	ParentID *ID // 根节点为 nil
+	Name     string
+	Depth    int             // 相对于查询节点的层级，查询节点为 0，子节点和父节点为 1
+	Children []*TreeNode[ID] // 仅 Subtree 和 BuildTree 填充
+}
+
+// TreeOption 树查询选项
+type TreeOption func(*treeOptions)
+
+type treeOptions struct {
+	idColumn        string
+	parentColumn    string
+	nameColumn      string
+	deletedAtColumn string
+	maxDepth        int
+}
+
+// WithTreeColumns 设置 ID 和父节点 ID 的字段名，默认为 id 和 parent_id
+func WithTreeColumns(idColumn, parentColumn string) TreeOption {
+	return func(o *treeOptions) {
+		o.idColumn = idColumn
+		o.parentColumn = parentColumn
+	}
+}
+
+// WithTreeNameColumn 设置名称字段，默认为 name，为空时不查询名称
+func WithTreeNameColumn(column string) TreeOption {
+	return func(o *treeOptions) {
+		o.nameColumn = column
+	}
+}
+
+// WithTreeSoftDelete 跳过软删除的节点及其子树，column 为空时使用 mixin.FieldDeletedAt
+//
+// context 由 mixin.SkipSoftDelete 生成时不过滤。
+func WithTreeSoftDelete(column string) TreeOption {
+	return func(o *treeOptions) {
+		if column == "" {
+			column = mixin.FieldDeletedAt
+		}
+		o.deletedAtColumn = column
+	}
+}
+
+// WithTreeMaxDepth 设置树的最大层数，根节点为第 1 层
+//
+// 递归查询最多向下或向上查询 depth 层，数据中存在环时也不会无限递归；移动节点时超过层数返回 ErrTreeMaxDepth。
+// 未设置时移动节点不限制层数，递归查询最多 256 层。
+func WithTreeMaxDepth(depth int) TreeOption {
+	return func(o *treeOptions) {
+		o.maxDepth = depth
+	}
+}
+
+// Tree 基于 parent_id 的树形表查询，使用 WITH RECURSIVE 实现，支持 MySQL 8、PostgreSQL 和 SQLite
+//
+// 使用示例:
+//
+//	tree := entgo.NewTree[uint32](entClient.DriverContext(ctx), "sys_departments", entgo.WithTreeSoftDelete(""))
+//	ids, err := tree.DescendantIDs(ctx, 1)
+//	path, err := tree.Path(ctx, 5)
+//	err = tree.Move(ctx, 5, &newParentID)
+type Tree[ID comparable] struct {
+	drv   dialect.Driver
+	table string
+	treeOptions
+}
+
+// NewTree 创建树查询，ID 为节点 ID 的类型，事务中使用时传入 EntClient.DriverContext 返回的 Driver
+func NewTree[ID comparable](drv dialect.Driver, table string, opts ...TreeOption) *Tree[ID] {
+	t := &Tree[ID]{
+		drv:   drv,
+		table: table,
+		treeOptions: treeOptions{
+			idColumn:     "id",
+			parentColumn: "parent_id",
+			nameColumn:   "name",
+		},
+	}
+	for _, opt := range opts {
+		opt(&t.treeOptions)
+	}
+	return t
+}
+
+// Node 查询单个节点，节点不存在时返回 ErrTreeNodeNotFound
+func (t *Tree[ID]) Node(ctx context.Context, id ID) (*TreeNode[ID], error) {
+	b, err := t.builder()
+	if err != nil {
+		return nil, err
+	}
+
+	b.WriteString("SELECT ")
+	t.writeColumns(b, "")
+	b.WriteString(", 0 FROM ").Ident(t.table).WriteString(" WHERE ").Ident(t.idColumn).WriteString(" = ").Arg(id)
+	t.writeNotDeleted(ctx, b, "")
+
+	nodes, err := t.query(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, ErrTreeNodeNotFound
+	}
+	return nodes[0], nil
+}
+
+// Children 查询直接子节点，按 ID 排序
+func (t *Tree[ID]) Children(ctx context.Context, id ID) ([]*TreeNode[ID], error) {
+	b, err := t.builder()
+	if err != nil {
+		return nil, err
+	}
+
+	b.WriteString("SELECT ")
+	t.writeColumns(b, "")
+	b.WriteString(", 1 FROM ").Ident(t.table).WriteString(" WHERE ").Ident(t.parentColumn).WriteString(" = ").Arg(id)
+	t.writeNotDeleted(ctx, b, "")
+	b.WriteString(" ORDER BY ").Ident(t.idColumn)
+
+	return t.query(ctx, b)
+}
+
+// Descendants 查询所有子孙节点，按层级和 ID 排序，节点不存在时返回 ErrTreeNodeNotFound
+func (t *Tree[ID]) Descendants(ctx context.Context, id ID) ([]*TreeNode[ID], error) {
+	nodes, err := t.descendants(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return nodes[1:], nil
+}
+
+// DescendantIDs 查询所有子孙节点的 ID，见 Descendants
+func (t *Tree[ID]) DescendantIDs(ctx context.Context, id ID) ([]ID, error) {
+	nodes, err := t.Descendants(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]ID, 0, len(nodes))
+	for _, node := range nodes {
+		ids = append(ids, node.ID)
+	}
+	return ids, nil
+}
+
+// Subtree 查询以 id 为根的子树，子节点按 ID 排序
+func (t *Tree[ID]) Subtree(ctx context.Context, id ID) (*TreeNode[ID], error) {
+	nodes, err := t.descendants(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	BuildTree(nodes)
+	return nodes[0], nil
+}
+
+// Ancestors 查询所有祖先节点，从父节点到根节点排列，节点不存在时返回 ErrTreeNodeNotFound
+func (t *Tree[ID]) Ancestors(ctx context.Context, id ID) ([]*TreeNode[ID], error) {
+	nodes, err := t.ancestors(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return nodes[1:], nil
+}
+
+// Path 查询从根节点到 id 的路径（面包屑），包含节点自身
+func (t *Tree[ID]) Path(ctx context.Context, id ID) ([]*TreeNode[ID], error) {
+	nodes, err := t.ancestors(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	path := make([]*TreeNode[ID], len(nodes))
+	for i, node := range nodes {
+		path[len(nodes)-1-i] = node
+	}
+	return path, nil
+}
+
+// Move 将节点移动到 parentID 下，parentID 为 nil 时移动为根节点
+//
+// 新的父节点是节点自身或其子孙节点时返回 ErrTreeCycle，超过最大层数时返回 ErrTreeMaxDepth。
+// 检查和更新不在同一语句中执行，需要强一致时在 EntClient.WithTx 中调用。
+func (t *Tree[ID]) Move(ctx context.Context, id ID, parentID *ID) error {
+	node, err := t.Node(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	depth := 1
+	if parentID != nil {
+		if *parentID == id {
+			return ErrTreeCycle
+		}
+		path, err := t.Path(ctx, *parentID)
+		if err != nil {
+			return err
+		}
+		for _, p := range path {
+			if p.ID == id {
+				return ErrTreeCycle
+			}
+		}
+		depth = len(path) + 1
+	}
+
+	if t.maxDepth > 0 {
+		descendants, err := t.Descendants(ctx, id)
+		if err != nil {
+			return err
+		}
+		if len(descendants) > 0 {
+			depth += descendants[len(descendants)-1].Depth
+		}
+		if depth > t.maxDepth {
+			return ErrTreeMaxDepth
+		}
+	}
+
+	if (node.ParentID == nil && parentID == nil) || (node.ParentID != nil && parentID != nil && *node.ParentID == *parentID) {
+		return nil
+	}
+
+	b, err := t.builder()
+	if err != nil {
+		return err
+	}
+	b.WriteString("UPDATE ").Ident(t.table).WriteString(" SET ").Ident(t.parentColumn).WriteString(" = ")
+	if parentID == nil {
+		b.WriteString("NULL")
+	} else {
+		b.Arg(*parentID)
+	}
+	b.WriteString(" WHERE ").Ident(t.idColumn).WriteString(" = ").Arg(id)
+
+	query, args := b.Query()
+	var res stdsql.Result
+	if err = t.drv.Exec(ctx, query, args, &res); err != nil {
+		return fmt.Errorf("move tree node failed: %w", err)
+	}
+	return nil
+}
+
+// BuildTree 按 ParentID 将节点组装为树，返回父节点不在 nodes 中的节点，保持 nodes 中的顺序
+func BuildTree[ID comparable](nodes []*TreeNode[ID]) []*TreeNode[ID] {
+	byID := make(map[ID]*TreeNode[ID], len(nodes))
+	for _, node := range nodes {
+		byID[node.ID] = node
+	}
+
+	var roots []*TreeNode[ID]
+	for _, node := range nodes {
+		if node.ParentID != nil {
+			if parent, ok := byID[*node.ParentID]; ok && parent != node {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
+
+// descendants 查询节点自身及所有子孙节点，第一个为节点自身
+func (t *Tree[ID]) descendants(ctx context.Context, id ID) ([]*TreeNode[ID], error) {
+	nodes, err := t.recursive(ctx, t.idColumn, id, 0, t.parentColumn, "id")
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 || nodes[0].Depth != 0 {
+		return nil, ErrTreeNodeNotFound
+	}
+	return nodes, nil
+}
+
+// descendantsOfParent 查询 parent_id 为 parentID 的节点及其所有子孙节点，不要求父节点存在，如根节点的父节点 ID 为 0
+func (t *Tree[ID]) descendantsOfParent(ctx context.Context, parentID ID) ([]*TreeNode[ID], error) {
+	return t.recursive(ctx, t.parentColumn, parentID, 1, t.parentColumn, "id")
+}
+
+// ancestors 查询节点自身及所有祖先节点，第一个为节点自身
+func (t *Tree[ID]) ancestors(ctx context.Context, id ID) ([]*TreeNode[ID], error) {
+	nodes, err := t.recursive(ctx, t.idColumn, id, 0, t.idColumn, "parent_id")
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 || nodes[0].Depth != 0 {
+		return nil, ErrTreeNodeNotFound
+	}
+	return nodes, nil
+}
+
+// recursive 从 seedColumn 等于 id 的节点开始递归查询，起始节点的层级为 depth，
+// 下一层节点的 joinColumn 等于上一层节点在公用表中的 cteColumn
+//
+//	WITH RECURSIVE tree_nodes (id, parent_id, name, depth) AS (
+//	    SELECT id, parent_id, name, 0 FROM t WHERE id = ?
+//	    UNION ALL
+//	    SELECT t.id, t.parent_id, t.name, tree_nodes.depth + 1 FROM t AS t
+//	        JOIN tree_nodes ON t.parent_id = tree_nodes.id AND t.deleted_at IS NULL AND tree_nodes.depth < ?
+//	)
+//	SELECT id, parent_id, name, depth FROM tree_nodes ORDER BY depth, id
+func (t *Tree[ID]) recursive(ctx context.Context, seedColumn string, id ID, depth int, joinColumn, cteColumn string) ([]*TreeNode[ID], error) {
+	b, err := t.builder()
+	if err != nil {
+		return nil, err
+	}
+
+	b.WriteString("WITH RECURSIVE ").Ident(treeCTE).WriteString(" (")
+	b.IdentComma(t.cteColumns()...)
+	b.WriteString(") AS (SELECT ")
+	t.writeColumns(b, "")
+	b.WriteString(fmt.Sprintf(", %d FROM ", depth)).Ident(t.table).WriteString(" WHERE ").Ident(seedColumn).WriteString(" = ").Arg(id)
+	t.writeNotDeleted(ctx, b, "")
+
+	b.WriteString(" UNION ALL SELECT ")
+	t.writeColumns(b, "t")
+	b.WriteString(", ").Ident(treeCTE).WriteString(".").Ident("depth").WriteString(" + 1 FROM ").Ident(t.table).WriteString(" AS ").Ident("t")
+	b.WriteString(" JOIN ").Ident(treeCTE).WriteString(" ON ").Ident("t").WriteString(".").Ident(joinColumn)
+	b.WriteString(" = ").Ident(treeCTE).WriteString(".").Ident(cteColumn)
+	t.writeNotDeleted(ctx, b, "t")
+	maxDepth := t.maxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultTreeMaxDepth
+	}
+	b.WriteString(" AND ").Ident(treeCTE).WriteString(".").Ident("depth").WriteString(" < ").Arg(maxDepth)
+
+	b.WriteString(") SELECT ")
+	b.IdentComma(t.cteColumns()...)
+	b.WriteString(" FROM ").Ident(treeCTE).WriteString(" ORDER BY ").Ident("depth").Comma().Ident("id")
+
+	return t.query(ctx, b)
+}
+
+func (t *Tree[ID]) builder() (*entSql.Builder, error) {
+	switch d := t.drv.Dialect(); d {
+	case dialect.MySQL, dialect.Postgres, dialect.SQLite:
+		b := &entSql.Builder{}
+		b.SetDialect(d)
+		return b, nil
+	default:
+		return nil, fmt.Errorf("tree query is not supported for dialect %q", d)
+	}
+}
+
+// cteColumns 公用表的字段名，与表的字段名无关
+func (t *Tree[ID]) cteColumns() []string {
+	if t.nameColumn == "" {
+		return []string{"id", "parent_id", "depth"}
+	}
+	return []string{"id", "parent_id", "name", "depth"}
+}
+
+// writeColumns 写入 ID、父节点 ID 和名称字段，alias 不为空时加表别名
+func (t *Tree[ID]) writeColumns(b *entSql.Builder, alias string) {
+	columns := []string{t.idColumn, t.parentColumn}
+	if t.nameColumn != "" {
+		columns = append(columns, t.nameColumn)
+	}
+	for i, column := range columns {
+		if i > 0 {
+			b.Comma()
+		}
+		if alias != "" {
+			b.Ident(alias).WriteString(".")
+		}
+		b.Ident(column)
+	}
+}
+
+// writeNotDeleted 使用 AND 追加软删除条件，alias 不为空时加表别名
+func (t *Tree[ID]) writeNotDeleted(ctx context.Context, b *entSql.Builder, alias string) {
+	if t.deletedAtColumn == "" || mixin.IsSkipSoftDelete(ctx) {
+		return
+	}
+	b.WriteString(" AND ")
+	if alias != "" {
+		b.Ident(alias).WriteString(".")
+	}
+	b.Ident(t.deletedAtColumn).WriteString(" IS NULL")
+}
+
+func (t *Tree[ID]) query(ctx context.Context, b *entSql.Builder) ([]*TreeNode[ID], error) {
+	query, args := b.Query()
+
+	rows := &entSql.Rows{}
+	if err := t.drv.Query(ctx, query, args, rows); err != nil {
+		return nil, fmt.Errorf("query tree nodes failed: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []*TreeNode[ID]
+	for rows.Next() {
+		node := &TreeNode[ID]{}
+		var name stdsql.NullString
+		dest := []any{&node.ID, &node.ParentID}
+		if t.nameColumn != "" {
+			dest = append(dest, &name)
+		}
+		dest = append(dest, &node.Depth)
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("scan tree node failed: %w", err)
+		}
+		node.Name = name.String
+		nodes = append(nodes, node)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query tree nodes failed: %w", err)
+	}
+	return nodes, nil
+}
@@ -0,0 +1,228 @@
+package entgo
+
+import (
+	"context"
+	"testing"
+
+	"entgo.io/ent/dialect"
+	entSql "entgo.io/ent/dialect/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/heyinLab/common/pkg/utils/entgo/mixin"
+)
+
+// openTreeDB 打开内存数据库并写入测试数据
+//
+//	1 总部
+//	├── 2 研发部
+//	│   ├── 4 后端组
+//	│   │   └── 6 存储小组
+//	│   └── 5 前端组（已删除）
+//	│       └── 7 组件小组
+//	└── 3 市场部
+//	8 分部
+func openTreeDB(t *testing.T) *entSql.Driver {
+	drv, err := entSql.Open(dialect.SQLite, "file::memory:")
+	require.NoError(t, err)
+	drv.DB().SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = drv.Close() })
+
+	_, err = drv.DB().Exec(`
+CREATE TABLE departments (
+	dept_id    INTEGER PRIMARY KEY,
+	pid        INTEGER,
+	name       TEXT,
+	deleted_at DATETIME
+);
+INSERT INTO departments VALUES
+	(1, NULL, '总部', NULL),
+	(2, 1, '研发部', NULL),
+	(3, 1, '市场部', NULL),
+	(4, 2, '后端组', NULL),
+	(5, 2, '前端组', '2024-01-01 00:00:00'),
+	(6, 4, '存储小组', NULL),
+	(7, 5, '组件小组', NULL),
+	(8, NULL, '分部', NULL);
+`)
+	require.NoError(t, err)
+	return drv
+}
+
+func nodeIDs(nodes []*TreeNode[uint32]) []uint32 {
+	ids := make([]uint32, 0, len(nodes))
+	for _, node := range nodes {
+		ids = append(ids, node.ID)
+	}
+	return ids
+}
+
+func TestTree(t *testing.T) {
+	ctx := context.Background()
+	drv := openTreeDB(t)
+	tree := NewTree[uint32](drv, "departments", WithTreeColumns("dept_id", "pid"), WithTreeSoftDelete(""))
+
+	node, err := tree.Node(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, "研发部", node.Name)
+	require.Equal(t, uint32(1), *node.ParentID)
+
+	_, err = tree.Node(ctx, 5)
+	require.ErrorIs(t, err, ErrTreeNodeNotFound)
+
+	children, err := tree.Children(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{4}, nodeIDs(children))
+
+	// 已删除节点的子树被跳过
+	descendants, err := tree.Descendants(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{2, 3, 4, 6}, nodeIDs(descendants))
+	require.Equal(t, []int{1, 1, 2, 3}, []int{descendants[0].Depth, descendants[1].Depth, descendants[2].Depth, descendants[3].Depth})
+
+	ids, err := tree.DescendantIDs(mixin.SkipSoftDelete(ctx), 1)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{2, 3, 4, 5, 6, 7}, ids)
+
+	_, err = tree.Descendants(ctx, 100)
+	require.ErrorIs(t, err, ErrTreeNodeNotFound)
+
+	ancestors, err := tree.Ancestors(ctx, 6)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{4, 2, 1}, nodeIDs(ancestors))
+
+	path, err := tree.Path(ctx, 6)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{1, 2, 4, 6}, nodeIDs(path))
+	require.Equal(t, "总部", path[0].Name)
+	require.Nil(t, path[0].ParentID)
+
+	subtree, err := tree.Subtree(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{2, 3}, nodeIDs(subtree.Children))
+	require.Equal(t, []uint32{4}, nodeIDs(subtree.Children[0].Children))
+	require.Equal(t, []uint32{6}, nodeIDs(subtree.Children[0].Children[0].Children))
+	require.Empty(t, subtree.Children[1].Children)
+
+	// 层级限制
+	limited := NewTree[uint32](drv, "departments", WithTreeColumns("dept_id", "pid"), WithTreeNameColumn(""), WithTreeMaxDepth(2))
+	descendants, err = limited.Descendants(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{2, 3, 4, 5}, nodeIDs(descendants))
+	require.Empty(t, descendants[0].Name)
+}
+
+func TestTreeMove(t *testing.T) {
+	ctx := context.Background()
+	drv := openTreeDB(t)
+	tree := NewTree[uint32](drv, "departments", WithTreeColumns("dept_id", "pid"), WithTreeSoftDelete(""), WithTreeMaxDepth(4))
+
+	parentID := func(id uint32) *uint32 { return &id }
+
+	require.ErrorIs(t, tree.Move(ctx, 2, parentID(2)), ErrTreeCycle)
+	require.ErrorIs(t, tree.Move(ctx, 2, parentID(6)), ErrTreeCycle)
+	require.ErrorIs(t, tree.Move(ctx, 2, parentID(5)), ErrTreeNodeNotFound)
+	require.ErrorIs(t, tree.Move(ctx, 5, parentID(3)), ErrTreeNodeNotFound)
+	// 研发部子树有 3 层，移动到第 2 层的市场部下共 5 层
+	require.ErrorIs(t, tree.Move(ctx, 2, parentID(3)), ErrTreeMaxDepth)
+
+	require.NoError(t, tree.Move(ctx, 4, parentID(3)))
+	path, err := tree.Path(ctx, 6)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{1, 3, 4, 6}, nodeIDs(path))
+
+	require.NoError(t, tree.Move(ctx, 2, parentID(8)))
+	require.NoError(t, tree.Move(ctx, 2, parentID(8)))
+	require.NoError(t, tree.Move(ctx, 3, nil))
+	path, err = tree.Path(ctx, 6)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{3, 4, 6}, nodeIDs(path))
+}
+
+// recordDriver 记录执行的 SQL，不连接数据库
+type recordDriver struct {
+	dialect string
+	query   string
+	args    []any
+}
+
+func (d *recordDriver) Exec(_ context.Context, query string, args, _ any) error {
+	d.query, d.args = query, args.([]any)
+	return nil
+}
+
+func (d *recordDriver) Query(_ context.Context, query string, args, _ any) error {
+	d.query, d.args = query, args.([]any)
+	return context.Canceled
+}
+
+func (d *recordDriver) Tx(context.Context) (dialect.Tx, error) { return nil, nil }
+func (d *recordDriver) Close() error                           { return nil }
+func (d *recordDriver) Dialect() string                        { return d.dialect }
+
+func TestTreeQuery(t *testing.T) {
+	testcases := []struct {
+		dialect string
+		query   string
+	}{
+		{
+			dialect.Postgres,
+			`WITH RECURSIVE "tree_nodes" ("id", "parent_id", "name", "depth") AS (SELECT "id", "parent_id", "name", 0 FROM "departments" WHERE "id" = $1 AND "deleted_at" IS NULL UNION ALL SELECT "t"."id", "t"."parent_id", "t"."name", "tree_nodes"."depth" + 1 FROM "departments" AS "t" JOIN "tree_nodes" ON "t"."parent_id" = "tree_nodes"."id" AND "t"."deleted_at" IS NULL AND "tree_nodes"."depth" < $2) SELECT "id", "parent_id", "name", "depth" FROM "tree_nodes" ORDER BY "depth", "id"`,
+		},
+		{
+			dialect.MySQL,
+			"WITH RECURSIVE `tree_nodes` (`id`, `parent_id`, `name`, `depth`) AS (SELECT `id`, `parent_id`, `name`, 0 FROM `departments` WHERE `id` = ? AND `deleted_at` IS NULL UNION ALL SELECT `t`.`id`, `t`.`parent_id`, `t`.`name`, `tree_nodes`.`depth` + 1 FROM `departments` AS `t` JOIN `tree_nodes` ON `t`.`parent_id` = `tree_nodes`.`id` AND `t`.`deleted_at` IS NULL AND `tree_nodes`.`depth` < ?) SELECT `id`, `parent_id`, `name`, `depth` FROM `tree_nodes` ORDER BY `depth`, `id`",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.dialect, func(t *testing.T) {
+			drv := &recordDriver{dialect: tc.dialect}
+			_, err := NewTree[int64](drv, "departments", WithTreeSoftDelete(""), WithTreeMaxDepth(10)).Descendants(context.Background(), 1)
+			require.ErrorIs(t, err, context.Canceled)
+			require.Equal(t, tc.query, drv.query)
+			require.Equal(t, []any{int64(1), 10}, drv.args)
+		})
+	}
+
+	_, err := NewTree[int64](&recordDriver{dialect: dialect.Gremlin}, "departments").Descendants(context.Background(), 1)
+	require.Error(t, err)
+}
+
+func TestQueryAllChildrenIds(t *testing.T) {
+	ctx := context.Background()
+	c := openTxClient(t)
+
+	// 根节点的父节点为 0，3 已删除，6 的父节点不存在，8、9 形成环
+	_, err := c.DB().Exec(`
+CREATE TABLE menus (id INTEGER PRIMARY KEY, parent_id INTEGER, deleted_at DATETIME);
+INSERT INTO menus VALUES
+	(1, 0, NULL),
+	(2, 1, NULL),
+	(3, 1, '2024-01-01 00:00:00'),
+	(4, 3, NULL),
+	(5, 0, NULL),
+	(6, 99, NULL),
+	(8, 9, NULL),
+	(9, 8, NULL);
+`)
+	require.NoError(t, err)
+
+	for parentID, ids := range map[uint32][]uint32{
+		0:   {1, 5, 2, 3, 4},
+		3:   {4},
+		99:  {6},
+		100: {},
+	} {
+		childIDs, err := QueryAllChildrenIds(ctx, c, "menus", parentID)
+		require.NoError(t, err)
+		require.Equal(t, ids, childIDs, "parent %d", parentID)
+	}
+
+	// 环按默认层数截断
+	childIDs, err := QueryAllChildrenIds(ctx, c, "menus", 8)
+	require.NoError(t, err)
+	require.Len(t, childIDs, defaultTreeMaxDepth)
+
+	_, err = QueryAllChildrenIds(ctx, c, "missing", 1)
+	require.ErrorContains(t, err, "query child nodes failed")
+}
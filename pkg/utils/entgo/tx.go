@@ -0,0 +1,283 @@
+package entgo
+
+import (
+	"context"
+	stdsql "database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"entgo.io/ent/dialect"
+	entSql "entgo.io/ent/dialect/sql"
+
+	entgoQuery "github.com/heyinLab/common/pkg/utils/entgo/query"
+)
+
+// TxOption 事务选项，只对最外层事务生效
+type TxOption func(*txOptions)
+
+type txOptions struct {
+	isolation  stdsql.IsolationLevel
+	readOnly   bool
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	retryable  func(error) bool
+}
+
+// WithTxIsolation 设置事务隔离级别
+func WithTxIsolation(level stdsql.IsolationLevel) TxOption {
+	return func(o *txOptions) {
+		o.isolation = level
+	}
+}
+
+// WithTxReadOnly 开启只读事务
+func WithTxReadOnly() TxOption {
+	return func(o *txOptions) {
+		o.readOnly = true
+	}
+}
+
+// WithTxRetry 开启失败重试，设置重试次数和退避时间，退避时间从 minBackoff 开始倍增，不超过 maxBackoff
+//
+// 默认不重试，开启后 fn 需要可以重复执行。
+func WithTxRetry(maxRetries int, minBackoff, maxBackoff time.Duration) TxOption {
+	return func(o *txOptions) {
+		o.maxRetries = maxRetries
+		o.minBackoff = minBackoff
+		o.maxBackoff = maxBackoff
+	}
+}
+
+// WithTxRetryable 设置可以重试的错误，默认为 IsRetryableTxError
+func WithTxRetryable(retryable func(error) bool) TxOption {
+	return func(o *txOptions) {
+		o.retryable = retryable
+	}
+}
+
+// backoff 第 attempt 次重试前的等待时间，在 [d/2, d) 之间随机
+func (o *txOptions) backoff(attempt int) time.Duration {
+	d := o.minBackoff << attempt
+	if d <= 0 || d > o.maxBackoff {
+		d = o.maxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// IsRetryableTxError 判断是否为可以重试的事务错误：序列化失败、死锁、锁等待超时和 SQLite 数据库锁定
+func IsRetryableTxError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// PostgreSQL（pgx、lib/pq）的错误实现了 SQLState
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		switch state.SQLState() {
+		case "40001", "40P01":
+			return true
+		}
+	}
+
+	msg := err.Error()
+	for _, s := range []string{
+		"Error 1213", // MySQL 死锁
+		"Error 1205", // MySQL 锁等待超时
+		"database is locked",
+		"database table is locked",
+	} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// txDriver 在事务中执行的 Driver，再次开启事务时不创建新的事务
+type txDriver struct {
+	tx      dialect.Tx
+	dialect string
+}
+
+func (d *txDriver) Exec(ctx context.Context, query string, args, v any) error {
+	return d.tx.Exec(ctx, query, args, v)
+}
+
+func (d *txDriver) Query(ctx context.Context, query string, args, v any) error {
+	return d.tx.Query(ctx, query, args, v)
+}
+
+func (d *txDriver) Tx(context.Context) (dialect.Tx, error) { return dialect.NopTx(d), nil }
+func (d *txDriver) Close() error                           { return nil }
+func (d *txDriver) Dialect() string                        { return d.dialect }
+
+// txState 一个数据库事务的状态，由最外层和嵌套的事务范围共享
+type txState struct {
+	drv        *txDriver
+	savepoints int
+	client     any
+}
+
+// txScope 事务范围，嵌套的范围对应一个保存点
+type txScope struct {
+	state     *txState
+	callbacks []func(context.Context)
+}
+
+// txKey 按数据库区分 context 中的事务
+type txKey struct {
+	drv *entSql.Driver
+}
+
+// context 返回保存事务范围的 context，嵌套的范围覆盖外层的范围；事务不能并发使用，分页查询不并发执行总数查询
+func (s *txScope) context(ctx context.Context, drv *entSql.Driver) context.Context {
+	return context.WithValue(entgoQuery.WithSequentialQuery(ctx), txKey{drv: drv}, s)
+}
+
+// WithTx 在事务中执行 fn，fn 返回错误或 panic 时回滚，否则提交
+//
+// 事务保存在 fn 的 ctx 中，通过 ClientContext、DriverContext、Query、Exec 加入事务。
+// ctx 中已有事务时，嵌套的 WithTx 使用保存点，fn 失败只回滚到保存点，由外层决定是否提交。
+// 设置 WithTxRetry 后，最外层事务遇到可重试的错误（见 IsRetryableTxError）时重新执行 fn，fn 需要可以重复执行。
+// 同一事务中不要并发调用。
+//
+// 使用示例:
+//
+//	err := entClient.WithTx(ctx, func(ctx context.Context) error {
+//	    if err := userRepo.Create(ctx, user); err != nil {
+//	        return err
+//	    }
+//	    entClient.AfterCommit(ctx, func(ctx context.Context) {
+//	        _ = mailer.SendWelcome(ctx, user.Email)
+//	    })
+//	    return nil
+//	})
+func (c *EntClient[T]) WithTx(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	if parent, ok := ctx.Value(txKey{drv: c.drv}).(*txScope); ok {
+		return c.withSavepoint(ctx, parent, fn)
+	}
+
+	o := &txOptions{retryable: IsRetryableTxError}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	for attempt := 0; ; attempt++ {
+		callbacks, err := c.runTx(ctx, fn, o)
+		if err == nil {
+			for _, callback := range callbacks {
+				callback(ctx)
+			}
+			return nil
+		}
+		if attempt >= o.maxRetries || !o.retryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(o.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// runTx 执行一次事务，返回提交后需要执行的回调
+func (c *EntClient[T]) runTx(ctx context.Context, fn func(ctx context.Context) error, o *txOptions) ([]func(context.Context), error) {
+	tx, err := c.drv.BeginTx(ctx, &stdsql.TxOptions{Isolation: o.isolation, ReadOnly: o.readOnly})
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction failed: %w", err)
+	}
+
+	scope := &txScope{state: &txState{drv: &txDriver{tx: tx, dialect: c.drv.Dialect()}}}
+	defer func() {
+		if v := recover(); v != nil {
+			_ = tx.Rollback()
+			panic(v)
+		}
+	}()
+
+	if err = fn(scope.context(ctx, c.drv)); err != nil {
+		return nil, Rollback(tx, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction failed: %w", err)
+	}
+	return scope.callbacks, nil
+}
+
+// withSavepoint 在保存点中执行 fn，成功时提交后的回调合并到外层
+func (c *EntClient[T]) withSavepoint(ctx context.Context, parent *txScope, fn func(ctx context.Context) error) error {
+	state := parent.state
+	state.savepoints++
+	name := fmt.Sprintf("sp_%d", state.savepoints)
+
+	if err := state.drv.Exec(ctx, "SAVEPOINT "+name, []any{}, nil); err != nil {
+		return fmt.Errorf("create savepoint failed: %w", err)
+	}
+
+	scope := &txScope{state: state}
+	if err := fn(scope.context(ctx, c.drv)); err != nil {
+		if rerr := state.drv.Exec(ctx, "ROLLBACK TO SAVEPOINT "+name, []any{}, nil); rerr != nil {
+			err = fmt.Errorf("%w: rollback to savepoint failed: %v", err, rerr)
+		}
+		return err
+	}
+
+	if err := state.drv.Exec(ctx, "RELEASE SAVEPOINT "+name, []any{}, nil); err != nil {
+		return fmt.Errorf("release savepoint failed: %w", err)
+	}
+	parent.callbacks = append(parent.callbacks, scope.callbacks...)
+	return nil
+}
+
+// InTx 判断 ctx 中是否有该数据库的事务
+func (c *EntClient[T]) InTx(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{drv: c.drv}).(*txScope)
+	return ok
+}
+
+// DriverContext 返回 ctx 中事务的 Driver，不在事务中时返回 Driver()
+func (c *EntClient[T]) DriverContext(ctx context.Context) dialect.Driver {
+	if scope, ok := ctx.Value(txKey{drv: c.drv}).(*txScope); ok {
+		return scope.state.drv
+	}
+	return c.drv
+}
+
+// ClientContext 返回加入 ctx 中事务的 Client，不在事务中或未设置 WithClientFactory 时返回 Client()
+func (c *EntClient[T]) ClientContext(ctx context.Context) T {
+	scope, ok := ctx.Value(txKey{drv: c.drv}).(*txScope)
+	if !ok || c.newClient == nil {
+		return c.db
+	}
+
+	if client, ok := scope.state.client.(T); ok {
+		return client
+	}
+	client := c.newClient(scope.state.drv)
+	scope.state.client = client
+	return client
+}
+
+// AfterCommit 注册该数据库的事务提交后执行的回调，事务回滚或回调所在的保存点回滚时不执行
+//
+// 回调使用 WithTx 传入的 ctx，不在事务中。ctx 中没有该数据库的事务时立即执行，
+// 其他数据库的事务不影响回调的执行时机。
+func (c *EntClient[T]) AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	scope, ok := ctx.Value(txKey{drv: c.drv}).(*txScope)
+	if !ok {
+		fn(ctx)
+		return
+	}
+	scope.callbacks = append(scope.callbacks, fn)
+}
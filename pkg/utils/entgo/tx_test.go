@@ -0,0 +1,221 @@
+package entgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"entgo.io/ent/dialect"
+	entSql "entgo.io/ent/dialect/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+
+	entgoQuery "github.com/heyinLab/common/pkg/utils/entgo/query"
+)
+
+// testClient 模拟 ent 生成的 Client
+type testClient struct {
+	drv dialect.Driver
+}
+
+func (c *testClient) Close() error { return nil }
+
+func openTxClient(t *testing.T) *EntClient[*testClient] {
+	drv, err := entSql.Open(dialect.SQLite, "file::memory:")
+	require.NoError(t, err)
+	drv.DB().SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = drv.Close() })
+
+	_, err = drv.DB().Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)`)
+	require.NoError(t, err)
+
+	return NewEntClient(&testClient{drv: drv}, drv, WithClientFactory(func(drv dialect.Driver) *testClient {
+		return &testClient{drv: drv}
+	}))
+}
+
+func insertUser(ctx context.Context, c *EntClient[*testClient], name string) error {
+	return c.Exec(ctx, "INSERT INTO users (name) VALUES (?)", []any{name}, nil)
+}
+
+func userNames(t *testing.T, c *EntClient[*testClient]) []string {
+	rows, err := c.DB().Query("SELECT name FROM users ORDER BY id")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+	c := openTxClient(t)
+	errFailed := errors.New("failed")
+
+	var committed []string
+	after := func(ctx context.Context, name string) {
+		c.AfterCommit(ctx, func(ctx context.Context) {
+			require.False(t, c.InTx(ctx))
+			committed = append(committed, name)
+		})
+	}
+
+	// 返回错误时回滚，不执行回调
+	err := c.WithTx(ctx, func(ctx context.Context) error {
+		require.True(t, c.InTx(ctx))
+		require.True(t, entgoQuery.IsSequentialQuery(ctx))
+		require.NoError(t, insertUser(ctx, c, "rollback"))
+		after(ctx, "rollback")
+		return errFailed
+	})
+	require.ErrorIs(t, err, errFailed)
+	require.Empty(t, userNames(t, c))
+	require.Empty(t, committed)
+
+	// 嵌套事务失败只回滚到保存点
+	err = c.WithTx(ctx, func(ctx context.Context) error {
+		require.NoError(t, insertUser(ctx, c, "outer"))
+		after(ctx, "outer")
+
+		require.NoError(t, c.WithTx(ctx, func(ctx context.Context) error {
+			after(ctx, "inner")
+			return insertUser(ctx, c, "inner")
+		}))
+
+		require.ErrorIs(t, c.WithTx(ctx, func(ctx context.Context) error {
+			require.NoError(t, insertUser(ctx, c, "savepoint"))
+			after(ctx, "savepoint")
+			return errFailed
+		}), errFailed)
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"outer", "inner"}, userNames(t, c))
+	require.Equal(t, []string{"outer", "inner"}, committed)
+
+	// panic 时回滚并继续 panic
+	require.PanicsWithValue(t, "boom", func() {
+		_ = c.WithTx(ctx, func(ctx context.Context) error {
+			require.NoError(t, insertUser(ctx, c, "panic"))
+			panic("boom")
+		})
+	})
+	require.Equal(t, []string{"outer", "inner"}, userNames(t, c))
+
+	// 不在事务中时立即执行
+	c.AfterCommit(ctx, func(context.Context) { committed = append(committed, "direct") })
+	require.Equal(t, []string{"outer", "inner", "direct"}, committed)
+
+	// 其他数据库的事务中立即执行
+	other := openTxClient(t)
+	require.NoError(t, c.WithTx(ctx, func(ctx context.Context) error {
+		other.AfterCommit(ctx, func(context.Context) { committed = append(committed, "other") })
+		require.Equal(t, []string{"outer", "inner", "direct", "other"}, committed)
+		return nil
+	}))
+}
+
+// sqlStateError 模拟 PostgreSQL 驱动的错误
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "pg error " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestWithTxRetry(t *testing.T) {
+	ctx := context.Background()
+	c := openTxClient(t)
+
+	attempts := 0
+	var committed []int
+	err := c.WithTx(ctx, func(ctx context.Context) error {
+		attempts++
+		attempt := attempts
+		require.NoError(t, insertUser(ctx, c, fmt.Sprintf("attempt%d", attempt)))
+		c.AfterCommit(ctx, func(context.Context) { committed = append(committed, attempt) })
+		if attempt < 3 {
+			return fmt.Errorf("insert failed: %w", sqlStateError("40001"))
+		}
+		return nil
+	}, WithTxRetry(3, time.Millisecond, 2*time.Millisecond))
+	require.NoError(t, err)
+	require.Equal(t, 3, attempts)
+	require.Equal(t, []int{3}, committed)
+	require.Equal(t, []string{"attempt3"}, userNames(t, c))
+
+	// 默认不重试，超过重试次数、不可重试的错误直接返回
+	for _, tc := range []struct {
+		err      error
+		opts     []TxOption
+		attempts int
+	}{
+		{sqlStateError("40P01"), []TxOption{WithTxRetry(1, 0, 0)}, 2},
+		{sqlStateError("40001"), nil, 1},
+		{sqlStateError("23505"), []TxOption{WithTxRetry(3, 0, 0)}, 1},
+		{sqlStateError("40001"), []TxOption{WithTxRetry(3, 0, 0), WithTxRetryable(func(error) bool { return false })}, 1},
+	} {
+		attempts = 0
+		err = c.WithTx(ctx, func(ctx context.Context) error {
+			attempts++
+			return tc.err
+		}, tc.opts...)
+		require.ErrorIs(t, err, tc.err)
+		require.Equal(t, tc.attempts, attempts, tc.err.Error())
+	}
+
+	// 等待重试时 ctx 取消
+	cancelCtx, cancel := context.WithCancel(ctx)
+	err = c.WithTx(cancelCtx, func(ctx context.Context) error {
+		cancel()
+		return sqlStateError("40001")
+	}, WithTxRetry(3, time.Hour, time.Hour))
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestClientContext(t *testing.T) {
+	ctx := context.Background()
+	c := openTxClient(t)
+
+	require.Same(t, c.Client(), c.ClientContext(ctx))
+	require.Equal(t, c.Driver(), c.DriverContext(ctx))
+
+	require.NoError(t, c.WithTx(ctx, func(ctx context.Context) error {
+		client := c.ClientContext(ctx)
+		require.NotSame(t, c.Client(), client)
+		require.Same(t, client, c.ClientContext(ctx))
+		require.Same(t, c.DriverContext(ctx), client.drv)
+
+		// 事务中再次开启事务不创建新的事务
+		tx, err := client.drv.Tx(ctx)
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+
+		return c.WithTx(ctx, func(ctx context.Context) error {
+			require.Same(t, client, c.ClientContext(ctx))
+			return nil
+		})
+	}))
+}
+
+func TestIsRetryableTxError(t *testing.T) {
+	for err, retryable := range map[error]bool{
+		sqlStateError("40001"): true,
+		sqlStateError("40P01"): true,
+		sqlStateError("23505"): false,
+		errors.New("Error 1213 (40001): Deadlock found when trying to get lock; try restarting transaction"): true,
+		errors.New("Error 1205 (HY000): Lock wait timeout exceeded; try restarting transaction"):             true,
+		errors.New("Error 1062 (23000): Duplicate entry"):                                                    false,
+		errors.New("database is locked"):                                                                     true,
+		context.Canceled:                                                                                     false,
+	} {
+		require.Equal(t, retryable, IsRetryableTxError(err), err.Error())
+	}
+	require.False(t, IsRetryableTxError(nil))
+}
@@ -0,0 +1,326 @@
+package entgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/heyinLab/common/pkg/utils/stringcase"
+)
+
+// JsonPathValue JSON 字段中的一个路径及其新值
+type JsonPathValue struct {
+	Path  []string // 键路径，如 []string{"profile", "nickname"}
+	Value any      // 新值，按 encoding/json 序列化
+}
+
+// BuildJsonFieldUpdater 构建 JSON 字段的局部更新，路径和值均使用绑定参数
+//
+// sets 中路径的中间对象不存在时自动创建，removes 中的路径被删除，字段为 NULL 时按空对象处理。
+// 支持 PostgreSQL（jsonb）、MySQL 和 SQLite，其他数据库的错误通过 UpdateBuilder 的 Err 返回。
+//
+// PostgreSQL: "col" = jsonb_set(COALESCE("col", '{}'::jsonb), ARRAY[$1]::text[], $2::jsonb) #- ARRAY[$3]::text[]
+// MySQL: `col` = JSON_REMOVE(JSON_SET(COALESCE(`col`, JSON_OBJECT()), ?, CAST(? AS JSON)), ?)
+// SQLite: `col` = json_remove(json_set(COALESCE(`col`, json_object()), ?, json(?)), ?)
+func BuildJsonFieldUpdater(column string, sets []JsonPathValue, removes [][]string) func(u *sql.UpdateBuilder) {
+	sets = validJsonPathValues(sets)
+	removes = validJsonPaths(removes)
+	if len(sets) == 0 && len(removes) == 0 {
+		return nil
+	}
+
+	return func(u *sql.UpdateBuilder) {
+		values := make([]string, 0, len(sets))
+		for _, set := range sets {
+			b, err := json.Marshal(set.Value)
+			if err != nil {
+				u.AddError(fmt.Errorf("invalid json value for %s.%s: %w", column, strings.Join(set.Path, "."), err))
+				return
+			}
+			values = append(values, string(b))
+		}
+
+		switch d := u.Dialect(); d {
+		case dialect.Postgres:
+			u.Set(column, sql.ExprFunc(func(b *sql.Builder) {
+				writePostgresJsonUpdate(b, column, sets, values, removes)
+			}))
+		case dialect.MySQL, dialect.SQLite:
+			u.Set(column, sql.ExprFunc(func(b *sql.Builder) {
+				writeJsonFuncUpdate(b, column, sets, values, removes, d == dialect.SQLite)
+			}))
+		default:
+			u.AddError(fmt.Errorf("json field update is not supported for dialect %q", d))
+		}
+	}
+}
+
+// BuildJsonFieldMaskUpdater 按字段掩码构建 JSON 字段的局部更新，msg 为 JSON 字段对应的消息
+//
+// 掩码路径支持嵌套，如 profile.nickname。msg 中已设置的路径写入新值，未设置的路径从 JSON 中删除。
+// needToSnakeCase 为 true 时 JSON 键转换为 snake_case。
+func BuildJsonFieldMaskUpdater(column string, msg proto.Message, paths []string, needToSnakeCase bool) func(u *sql.UpdateBuilder) {
+	sets, removes := ExtractJsonFieldUpdates(msg, paths, needToSnakeCase)
+	return BuildJsonFieldUpdater(column, sets, removes)
+}
+
+// ExtractJsonFieldUpdates 按字段掩码提取 JSON 字段的更新，返回需要写入和删除的路径
+//
+// 有显式存在性的字段（消息、optional 字段）未设置时删除对应路径，其他字段即使为零值也写入；
+// 路径上的中间消息未设置时只删除掩码指定的路径。消息按 protojson 序列化，枚举写入名称。
+// 掩码中不存在的字段、经过非消息字段的路径会被忽略。
+func ExtractJsonFieldUpdates(msg proto.Message, paths []string, needToSnakeCase bool) (sets []JsonPathValue, removes [][]string) {
+	if msg == nil {
+		return nil, nil
+	}
+
+	root := msg.ProtoReflect()
+	for _, path := range paths {
+		segments := strings.Split(path, ".")
+		keys := make([]string, 0, len(segments))
+
+		rft := root
+		unset := false
+		for i, segment := range segments {
+			fd := lookupFieldDescriptor(rft.Descriptor(), segment)
+			if fd == nil {
+				break
+			}
+
+			key := segment
+			if needToSnakeCase {
+				key = stringcase.ToSnakeCase(segment)
+			}
+			keys = append(keys, key)
+
+			if i == len(segments)-1 {
+				if unset || (fd.HasPresence() && !rft.Has(fd)) {
+					removes = append(removes, keys)
+				} else {
+					sets = append(sets, JsonPathValue{Path: keys, Value: protoJsonValue(fd, rft.Get(fd), needToSnakeCase)})
+				}
+				break
+			}
+
+			if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+				break
+			}
+			// 未设置的消息返回只读的空消息，继续按描述符解析后续路径
+			unset = unset || !rft.Has(fd)
+			rft = rft.Get(fd).Message()
+		}
+	}
+
+	return sets, removes
+}
+
+// lookupFieldDescriptor 按字段名查找字段，找不到时按 JSON 名称查找
+func lookupFieldDescriptor(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	if fd := md.Fields().ByName(protoreflect.Name(name)); fd != nil {
+		return fd
+	}
+	return md.Fields().ByJSONName(name)
+}
+
+// protoJsonValue 将字段值转换为可以用 encoding/json 序列化的值，消息按 protojson 序列化
+func protoJsonValue(fd protoreflect.FieldDescriptor, v protoreflect.Value, useProtoNames bool) any {
+	switch {
+	case fd.IsList():
+		l := v.List()
+		items := make([]any, l.Len())
+		for i := range items {
+			items[i] = protoScalarValue(fd, l.Get(i), useProtoNames)
+		}
+		return items
+
+	case fd.IsMap():
+		m := make(map[string]any, v.Map().Len())
+		v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+			m[k.String()] = protoScalarValue(fd.MapValue(), mv, useProtoNames)
+			return true
+		})
+		return m
+
+	default:
+		return protoScalarValue(fd, v, useProtoNames)
+	}
+}
+
+// protoScalarValue 转换单个值，消息和枚举与 protojson 的格式一致，useProtoNames 为 true 时消息的键使用字段名
+func protoScalarValue(fd protoreflect.FieldDescriptor, v protoreflect.Value, useProtoNames bool) any {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return protoMessageJson{msg: v.Message().Interface(), useProtoNames: useProtoNames}
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return int32(v.Enum())
+	default:
+		return v.Interface()
+	}
+}
+
+// protoMessageJson 使用 protojson 序列化的消息
+type protoMessageJson struct {
+	msg           proto.Message
+	useProtoNames bool
+}
+
+func (m protoMessageJson) MarshalJSON() ([]byte, error) {
+	return protojson.MarshalOptions{UseProtoNames: m.useProtoNames}.Marshal(m.msg)
+}
+
+// writePostgresJsonUpdate 使用 jsonb_set 和 #- 生成更新表达式
+func writePostgresJsonUpdate(b *sql.Builder, column string, sets []JsonPathValue, values []string, removes [][]string) {
+	prefixes := jsonPathPrefixes(sets)
+
+	for i := 0; i < len(prefixes)+len(sets); i++ {
+		b.WriteString("jsonb_set(")
+	}
+	b.WriteString("COALESCE(").Ident(column).WriteString(", '{}'::jsonb)")
+
+	// 先按原值或空对象补齐中间对象，再写入新值
+	for _, prefix := range prefixes {
+		b.Comma()
+		writePostgresPath(b, prefix)
+		b.Comma().WriteString("COALESCE(").Ident(column).WriteString(" #> ")
+		writePostgresPath(b, prefix)
+		b.WriteString(", '{}'::jsonb))")
+	}
+	for i, set := range sets {
+		b.Comma()
+		writePostgresPath(b, set.Path)
+		b.Comma().Arg(values[i]).WriteString("::jsonb)")
+	}
+
+	for _, path := range removes {
+		b.WriteString(" #- ")
+		writePostgresPath(b, path)
+	}
+}
+
+func writePostgresPath(b *sql.Builder, path []string) {
+	b.WriteString("ARRAY[")
+	for i, key := range path {
+		if i > 0 {
+			b.Comma()
+		}
+		b.Arg(key)
+	}
+	b.WriteString("]::text[]")
+}
+
+// writeJsonFuncUpdate 使用 MySQL、SQLite 的 JSON_INSERT、JSON_SET 和 JSON_REMOVE 生成更新表达式
+func writeJsonFuncUpdate(b *sql.Builder, column string, sets []JsonPathValue, values []string, removes [][]string, sqlite bool) {
+	insertFunc, setFunc, removeFunc, emptyObject := "JSON_INSERT", "JSON_SET", "JSON_REMOVE", "JSON_OBJECT()"
+	if sqlite {
+		insertFunc, setFunc, removeFunc, emptyObject = "json_insert", "json_set", "json_remove", "json_object()"
+	}
+
+	prefixes := jsonPathPrefixes(sets)
+	if len(removes) > 0 {
+		b.WriteString(removeFunc + "(")
+	}
+	if len(sets) > 0 {
+		b.WriteString(setFunc + "(")
+	}
+	if len(prefixes) > 0 {
+		b.WriteString(insertFunc + "(")
+	}
+	b.WriteString("COALESCE(").Ident(column).Comma().WriteString(emptyObject).WriteString(")")
+
+	// JSON_INSERT 只在路径不存在时写入，用于补齐中间对象
+	if len(prefixes) > 0 {
+		for _, prefix := range prefixes {
+			b.Comma().Arg(jsonPathString(prefix)).Comma().WriteString(emptyObject)
+		}
+		b.WriteString(")")
+	}
+	if len(sets) > 0 {
+		for i, set := range sets {
+			b.Comma().Arg(jsonPathString(set.Path)).Comma()
+			if sqlite {
+				b.WriteString("json(").Arg(values[i]).WriteString(")")
+			} else {
+				b.WriteString("CAST(").Arg(values[i]).WriteString(" AS JSON)")
+			}
+		}
+		b.WriteString(")")
+	}
+	if len(removes) > 0 {
+		for _, path := range removes {
+			b.Comma().Arg(jsonPathString(path))
+		}
+		b.WriteString(")")
+	}
+}
+
+// jsonPathString 生成 MySQL、SQLite 的 JSON 路径，键使用双引号转义，如 $."profile"."nick name"
+func jsonPathString(path []string) string {
+	var sb strings.Builder
+	sb.WriteString("$")
+	for _, key := range path {
+		sb.WriteString(".")
+		sb.WriteString(strconv.Quote(key))
+	}
+	return sb.String()
+}
+
+// jsonPathPrefixes 返回写入路径的中间对象路径，按深度从浅到深去重
+func jsonPathPrefixes(sets []JsonPathValue) [][]string {
+	var prefixes [][]string
+	seen := make(map[string]bool)
+	for _, set := range sets {
+		for i := 1; i < len(set.Path); i++ {
+			key := strings.Join(set.Path[:i], "\x00")
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			prefixes = append(prefixes, set.Path[:i])
+		}
+	}
+	return prefixes
+}
+
+// validJsonPathValues 过滤掉空路径
+func validJsonPathValues(sets []JsonPathValue) []JsonPathValue {
+	var out []JsonPathValue
+	for _, set := range sets {
+		if isValidJsonPath(set.Path) {
+			out = append(out, set)
+		}
+	}
+	return out
+}
+
+// validJsonPaths 过滤掉空路径
+func validJsonPaths(paths [][]string) [][]string {
+	var out [][]string
+	for _, path := range paths {
+		if isValidJsonPath(path) {
+			out = append(out, path)
+		}
+	}
+	return out
+}
+
+func isValidJsonPath(path []string) bool {
+	if len(path) == 0 {
+		return false
+	}
+	for _, key := range path {
+		if key == "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,177 @@
+package entgo
+
+import (
+	stdsql "database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	commonV1 "github.com/heyinLab/common/api/gen/go/common"
+	subscriptionV1 "github.com/heyinLab/common/api/gen/go/subscribe/v1"
+)
+
+func TestBuildJsonFieldUpdater(t *testing.T) {
+	sets := []JsonPathValue{
+		{Path: []string{"name"}, Value: "O'Brien"},
+		{Path: []string{"profile", "age"}, Value: 18},
+	}
+	removes := [][]string{{"profile", "nickname"}}
+
+	testcases := []struct {
+		name    string
+		dialect string
+		query   string
+		args    []any
+	}{
+		{
+			"PostgreSQL",
+			dialect.Postgres,
+			`UPDATE "users" SET "extra" = jsonb_set(jsonb_set(jsonb_set(COALESCE("extra", '{}'::jsonb), ARRAY[$1]::text[], COALESCE("extra" #> ARRAY[$2]::text[], '{}'::jsonb)), ARRAY[$3]::text[], $4::jsonb), ARRAY[$5, $6]::text[], $7::jsonb) #- ARRAY[$8, $9]::text[] WHERE "id" = $10`,
+			[]any{"profile", "profile", "name", `"O'Brien"`, "profile", "age", "18", "profile", "nickname", 1},
+		},
+		{
+			"MySQL",
+			dialect.MySQL,
+			"UPDATE `users` SET `extra` = JSON_REMOVE(JSON_SET(JSON_INSERT(COALESCE(`extra`, JSON_OBJECT()), ?, JSON_OBJECT()), ?, CAST(? AS JSON), ?, CAST(? AS JSON)), ?) WHERE `id` = ?",
+			[]any{`$."profile"`, `$."name"`, `"O'Brien"`, `$."profile"."age"`, "18", `$."profile"."nickname"`, 1},
+		},
+		{
+			"SQLite",
+			dialect.SQLite,
+			"UPDATE `users` SET `extra` = json_remove(json_set(json_insert(COALESCE(`extra`, json_object()), ?, json_object()), ?, json(?), ?, json(?)), ?) WHERE `id` = ?",
+			[]any{`$."profile"`, `$."name"`, `"O'Brien"`, `$."profile"."age"`, "18", `$."profile"."nickname"`, 1},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			u := sql.Dialect(tc.dialect).Update("users")
+			BuildJsonFieldUpdater("extra", sets, removes)(u)
+			u.Where(sql.EQ("id", 1))
+
+			query, args := u.Query()
+			require.NoError(t, u.Err())
+			require.Equal(t, tc.query, query)
+			require.Equal(t, tc.args, args)
+		})
+	}
+
+	t.Run("RemoveOnly", func(t *testing.T) {
+		u := sql.Dialect(dialect.MySQL).Update("users")
+		BuildJsonFieldUpdater("extra", nil, [][]string{{"a"}})(u)
+		query, args := u.Query()
+		require.Equal(t, "UPDATE `users` SET `extra` = JSON_REMOVE(COALESCE(`extra`, JSON_OBJECT()), ?)", query)
+		require.Equal(t, []any{`$."a"`}, args)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		require.Nil(t, BuildJsonFieldUpdater("extra", []JsonPathValue{{Value: 1}}, [][]string{{""}}))
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		u := sql.Dialect(dialect.Gremlin).Update("users")
+		BuildJsonFieldUpdater("extra", sets, nil)(u)
+		require.Error(t, u.Err())
+	})
+}
+
+func TestExtractJsonFieldUpdates(t *testing.T) {
+	msg := &commonV1.PagingRequest{
+		PageSize:  proto.Int32(20),
+		NoPaging:  proto.Bool(true),
+		FieldMask: &fieldmaskpb.FieldMask{Paths: []string{"id"}},
+	}
+
+	sets, removes := ExtractJsonFieldUpdates(msg, []string{"pageSize", "no_paging", "query", "fieldMask.paths", "unknown", "pageSize.x"}, true)
+	require.Equal(t, []JsonPathValue{
+		{Path: []string{"page_size"}, Value: int32(20)},
+		{Path: []string{"no_paging"}, Value: true},
+		{Path: []string{"field_mask", "paths"}, Value: []any{"id"}},
+	}, sets)
+	require.Equal(t, [][]string{{"query"}}, removes)
+
+	// 中间消息未设置时只删除掩码指定的路径
+	_, removes = ExtractJsonFieldUpdates(&commonV1.PagingRequest{}, []string{"fieldMask.paths"}, false)
+	require.Equal(t, [][]string{{"fieldMask", "paths"}}, removes)
+}
+
+func TestExtractJsonFieldUpdatesPresence(t *testing.T) {
+	msg := &subscriptionV1.SubscriptionInfo{
+		Status:    subscriptionV1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE,
+		StartDate: timestamppb.New(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)),
+		QuotaUsages: []*subscriptionV1.QuotaUsageInfo{
+			{DimensionKey: "seats", QuotaLimit: 10},
+		},
+	}
+
+	sets, removes := ExtractJsonFieldUpdates(msg, []string{
+		"status", "startDate", "trialDays", "isTrial", "tenantName", "quotaUsages", "endDate", "createdBy", "endDate.seconds",
+	}, true)
+	require.Equal(t, [][]string{{"end_date"}, {"created_by"}, {"end_date", "seconds"}}, removes)
+
+	values := make(map[string]string, len(sets))
+	for _, set := range sets {
+		b, err := json.Marshal(set.Value)
+		require.NoError(t, err)
+		values[strings.Join(set.Path, ".")] = string(b)
+	}
+	// 没有显式存在性的零值也写入，消息按 protojson 序列化，枚举写入名称
+	require.Equal(t, map[string]string{
+		"status":       `"SUBSCRIPTION_STATUS_ACTIVE"`,
+		"start_date":   `"2024-01-02T03:04:05Z"`,
+		"trial_days":   `0`,
+		"is_trial":     `false`,
+		"tenant_name":  `""`,
+		"quota_usages": `[{"dimension_key":"seats","quota_limit":10}]`,
+	}, values)
+
+	sets, _ = ExtractJsonFieldUpdates(msg, []string{"quotaUsages"}, false)
+	b, err := json.Marshal(sets[0].Value)
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"dimensionKey":"seats","quotaLimit":10}]`, string(b))
+}
+
+// TestSQLiteJsonFieldUpdate 在 SQLite 内存数据库中执行嵌套路径的写入和删除
+func TestSQLiteJsonFieldUpdate(t *testing.T) {
+	db, err := stdsql.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, extra TEXT);
+INSERT INTO users VALUES (1, '{"profile":{"nickname":"tom","age":1},"tags":["a"]}'), (2, NULL);`)
+	require.NoError(t, err)
+
+	updater := BuildJsonFieldUpdater("extra",
+		[]JsonPathValue{
+			{Path: []string{"profile", "age"}, Value: 18},
+			{Path: []string{"settings", "theme", "color"}, Value: "it's red"},
+		},
+		[][]string{{"profile", "nickname"}, {"tags"}},
+	)
+
+	for _, id := range []int{1, 2} {
+		u := sql.Dialect(dialect.SQLite).Update("users")
+		updater(u)
+		u.Where(sql.EQ("id", id))
+		query, args := u.Query()
+		_, err = db.Exec(query, args...)
+		require.NoError(t, err)
+	}
+
+	for id, expected := range map[int]string{
+		1: `{"profile":{"age":18},"settings":{"theme":{"color":"it's red"}}}`,
+		2: `{"profile":{"age":18},"settings":{"theme":{"color":"it's red"}}}`,
+	} {
+		var extra string
+		require.NoError(t, db.QueryRow("SELECT extra FROM users WHERE id = ?", id).Scan(&extra))
+		require.JSONEq(t, expected, extra)
+	}
+}
@@ -34,7 +34,9 @@ func BuildSetNullUpdater(fields []string) func(u *sql.UpdateBuilder) {
 	}
 }
 
-// ExtractJsonFieldKeyValues 提取json字段的键值对
+// ExtractJsonFieldKeyValues 提取json字段的键值对，键和值为 SQL 字面量
+//
+// Deprecated: 使用 ExtractJsonFieldUpdates 和 BuildJsonFieldUpdater，值作为绑定参数传入。
 func ExtractJsonFieldKeyValues(msg proto.Message, paths []string, needToSnakeCase bool) []string {
 	var keyValues []string
 	rft := msg.ProtoReflect()
@@ -54,50 +56,48 @@ func ExtractJsonFieldKeyValues(msg proto.Message, paths []string, needToSnakeCas
 			k = path
 		}
 
-		keyValues = append(keyValues, fmt.Sprintf("'%s'", k))
-
 		v := rft.Get(fd)
-		switch v.Interface().(type) {
+		switch val := v.Interface().(type) {
 		case int32, int64, uint32, uint64, float32, float64, bool:
-			keyValues = append(keyValues, fmt.Sprintf("%d", v.Interface()))
+			keyValues = append(keyValues, quoteSQLString(k), fmt.Sprintf("%v", val))
 		case string:
-			keyValues = append(keyValues, fmt.Sprintf("'%s'", v.Interface()))
+			keyValues = append(keyValues, quoteSQLString(k), quoteSQLString(val))
 		}
 	}
 
 	return keyValues
 }
 
-// SetJsonNullFieldUpdateBuilder 设置json字段的空值
+// quoteSQLString 生成 SQL 字符串字面量，单引号转义为两个单引号
+func quoteSQLString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// SetJsonNullFieldUpdateBuilder 删除json字段中未设置值的键，见 BuildJsonFieldUpdater
 func SetJsonNullFieldUpdateBuilder(fieldName string, msg proto.Message, paths []string) func(u *sql.UpdateBuilder) {
 	nilPaths := fieldmaskutil.NilValuePaths(msg, paths)
 	if len(nilPaths) == 0 {
 		return nil
 	}
 
-	return func(u *sql.UpdateBuilder) {
-		u.Set(fieldName,
-			sql.Expr(
-				fmt.Sprintf("\"%s\" - '{%s}'::text[]", fieldName, strings.Join(nilPaths, ",")),
-			),
-		)
+	removes := make([][]string, 0, len(nilPaths))
+	for _, path := range nilPaths {
+		removes = append(removes, []string{path})
 	}
+	return BuildJsonFieldUpdater(fieldName, nil, removes)
 }
 
-// SetJsonFieldValueUpdateBuilder 设置json字段的值
+// SetJsonFieldValueUpdateBuilder 设置json字段的值，见 BuildJsonFieldUpdater
 func SetJsonFieldValueUpdateBuilder(fieldName string, msg proto.Message, paths []string, needToSnakeCase bool) func(u *sql.UpdateBuilder) {
-	keyValues := ExtractJsonFieldKeyValues(msg, paths, needToSnakeCase)
-	if len(keyValues) == 0 {
-		return nil
+	var topLevelPaths []string
+	for _, path := range paths {
+		if !strings.Contains(path, ".") {
+			topLevelPaths = append(topLevelPaths, path)
+		}
 	}
 
-	return func(u *sql.UpdateBuilder) {
-		u.Set(fieldName,
-			sql.Expr(
-				fmt.Sprintf("\"%s\" || jsonb_build_object(%s)", fieldName, strings.Join(keyValues, ",")),
-			),
-		)
-	}
+	sets, _ := ExtractJsonFieldUpdates(msg, topLevelPaths, needToSnakeCase)
+	return BuildJsonFieldUpdater(fieldName, sets, nil)
 }
 
 // ApplyNilFieldMask 应用字段掩码以设置字段为NULL
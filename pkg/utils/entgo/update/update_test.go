@@ -5,8 +5,11 @@ import (
 
 	"entgo.io/ent/dialect"
 	"entgo.io/ent/dialect/sql"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/stretchr/testify/require"
+
+	commonV1 "github.com/heyinLab/common/api/gen/go/common"
 )
 
 func TestBuildSetNullUpdate(t *testing.T) {
@@ -28,3 +31,30 @@ func TestBuildSetNullUpdate(t *testing.T) {
 		require.Empty(t, args)
 	})
 }
+
+func TestExtractJsonFieldKeyValues(t *testing.T) {
+	msg := &commonV1.PagingRequest{
+		PageSize: proto.Int32(20),
+		NoPaging: proto.Bool(true),
+		Query:    proto.String(`it's`),
+	}
+
+	keyValues := ExtractJsonFieldKeyValues(msg, []string{"page_size", "no_paging", "query", "cursor"}, false)
+	require.Equal(t, []string{"'page_size'", "20", "'no_paging'", "true", "'query'", "'it''s'"}, keyValues)
+}
+
+func TestSetJsonFieldUpdateBuilder(t *testing.T) {
+	msg := &commonV1.PagingRequest{PageSize: proto.Int32(20)}
+
+	u := sql.Dialect(dialect.Postgres).Update("users")
+	SetJsonFieldValueUpdateBuilder("extra", msg, []string{"page_size", "query", "field_mask.paths"}, false)(u)
+	query, args := u.Query()
+	require.Equal(t, `UPDATE "users" SET "extra" = jsonb_set(COALESCE("extra", '{}'::jsonb), ARRAY[$1]::text[], $2::jsonb)`, query)
+	require.Equal(t, []any{"page_size", "20"}, args)
+
+	u = sql.Dialect(dialect.MySQL).Update("users")
+	SetJsonNullFieldUpdateBuilder("extra", msg, []string{"page_size", "query"})(u)
+	query, args = u.Query()
+	require.Equal(t, "UPDATE `users` SET `extra` = JSON_REMOVE(COALESCE(`extra`, JSON_OBJECT()), ?)", query)
+	require.Equal(t, []any{`$."query"`}, args)
+}
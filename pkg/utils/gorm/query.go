@@ -0,0 +1,304 @@
+package gorm
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	commonV1 "github.com/heyinLab/common/api/gen/go/common"
+	entgo "github.com/heyinLab/common/pkg/utils/entgo/query"
+	paging "github.com/heyinLab/common/pkg/utils/pagination"
+	"github.com/heyinLab/common/pkg/utils/query_parser"
+)
+
+// Scope GORM 查询作用域，可直接传给 gorm.DB.Scopes
+type Scope = func(db *gorm.DB) *gorm.DB
+
+// BuildQueryScopes 构建分页过滤查询的 Scope，过滤、排序、分页和字段选择的规则与 entgo.BuildQuerySelector 一致
+//
+// policy 为 nil 时不做限制。whereScopes 只包含过滤条件，可用于统计总行数。
+//
+// 使用示例:
+//
+//	err, whereScopes, queryScopes := gorm.BuildQueryScopes(userQueryPolicy,
+//	    req.GetQuery(), req.GetOrQuery(),
+//	    req.GetPage(), req.GetPageSize(), req.GetNoPaging(),
+//	    req.GetOrderBy(), "create_time",
+//	    req.GetFieldMask().GetPaths(),
+//	)
+//	db.Model(&User{}).Scopes(whereScopes...).Count(&total)
+//	db.Scopes(queryScopes...).Find(&users)
+func BuildQueryScopes(
+	policy *entgo.QueryPolicy,
+	andFilterJsonString, orFilterJsonString string,
+	page, pageSize int32, noPaging bool,
+	orderBys []string, defaultOrderField string,
+	selectFields []string,
+) (err error, whereScopes []Scope, queryScopes []Scope) {
+	if err = policy.CheckPaging(pageSize, noPaging); err != nil {
+		return err, nil, nil
+	}
+
+	var filterScope Scope
+	if err, filterScope = BuildFilterScope(policy, andFilterJsonString, orFilterJsonString); err != nil {
+		return err, nil, nil
+	}
+
+	var orderScope Scope
+	if err, orderScope = BuildOrderScope(policy, orderBys, defaultOrderField); err != nil {
+		return err, nil, nil
+	}
+
+	if err, selectFields = policy.SelectColumns(selectFields); err != nil {
+		return err, nil, nil
+	}
+
+	pageScope := BuildPaginationScope(page, pageSize, noPaging)
+	fieldScope := BuildFieldScope(selectFields)
+
+	if filterScope != nil {
+		whereScopes = append(whereScopes, filterScope)
+		queryScopes = append(queryScopes, filterScope)
+	}
+	if orderScope != nil {
+		queryScopes = append(queryScopes, orderScope)
+	}
+	if pageScope != nil {
+		queryScopes = append(queryScopes, pageScope)
+	}
+	if fieldScope != nil {
+		queryScopes = append(queryScopes, fieldScope)
+	}
+
+	return
+}
+
+// BuildPagingScopes 将通用列表查询请求转换为分页过滤查询的 Scope，见 BuildQueryScopes
+func BuildPagingScopes(policy *entgo.QueryPolicy, req *commonV1.PagingRequest, defaultOrderField string) (err error, whereScopes []Scope, queryScopes []Scope) {
+	return BuildQueryScopes(policy,
+		req.GetQuery(), req.GetOrQuery(),
+		req.GetPage(), req.GetPageSize(), req.GetNoPaging(),
+		req.GetOrderBy(), defaultOrderField,
+		req.GetFieldMask().GetPaths(),
+	)
+}
+
+// BuildFilterScope 按策略校验并构建过滤条件的 Scope，没有过滤条件时返回 nil
+//
+// 过滤条件由 entgo 的过滤选择器生成，在构建 SQL 时按当前表名和数据库方言渲染。
+func BuildFilterScope(policy *entgo.QueryPolicy, andFilterJsonString, orFilterJsonString string) (error, Scope) {
+	err, selectors := policy.BuildFilterSelector(andFilterJsonString, orFilterJsonString)
+	if err != nil {
+		return err, nil
+	}
+	return nil, whereScope(selectors...)
+}
+
+// BuildFilterExprScope 构建过滤表达式的 Scope，过滤值按登记的字段类型转换，r 可以为 nil
+//
+// 使用示例:
+//
+//	expr, err := query_parser.ParseFilterExprQueryString("status:ON,or(name__icontains:x,code__startswith:x)")
+//	db.Scopes(gorm.BuildFilterExprScope(nil, expr)).Find(&users)
+func BuildFilterExprScope(r *entgo.FieldRegistry, expr *query_parser.FilterExpr) Scope {
+	return whereScope(r.BuildFilterExprSelector(expr))
+}
+
+// BuildOrderScope 按策略校验并构建排序的 Scope，默认排序字段由服务端指定，不做校验
+func BuildOrderScope(policy *entgo.QueryPolicy, orderBys []string, defaultOrderField string) (error, Scope) {
+	if len(orderBys) == 0 {
+		if defaultOrderField == "" {
+			return nil, nil
+		}
+		orderBys = []string{"-" + defaultOrderField}
+	} else if policy != nil {
+		var err error
+		if err, orderBys = policy.OrderColumns(orderBys); err != nil {
+			return err, nil
+		}
+	}
+
+	var columns []clause.OrderByColumn
+	for _, v := range orderBys {
+		desc := strings.HasPrefix(v, "-")
+		field := strings.TrimPrefix(v, "-")
+		if len(field) == 0 {
+			continue
+		}
+		columns = append(columns, clause.OrderByColumn{
+			Column: clause.Column{Table: clause.CurrentTable, Name: field},
+			Desc:   desc,
+		})
+	}
+	if len(columns) == 0 {
+		return nil, nil
+	}
+
+	return nil, func(db *gorm.DB) *gorm.DB {
+		return db.Order(clause.OrderBy{Columns: columns})
+	}
+}
+
+// BuildPaginationScope 构建分页的 Scope，noPaging 为 true 时返回 nil
+func BuildPaginationScope(page, pageSize int32, noPaging bool) Scope {
+	if noPaging {
+		return nil
+	}
+
+	if page < 1 {
+		page = paging.DefaultPage
+	}
+	if pageSize < 1 {
+		pageSize = paging.DefaultPageSize
+	}
+	offset := paging.GetPageOffset(page, pageSize)
+
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Offset(offset).Limit(int(pageSize))
+	}
+}
+
+// BuildFieldScope 构建字段选择的 Scope，字段名转换为 snake_case，没有字段时返回 nil
+func BuildFieldScope(fields []string) Scope {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	fields = entgo.NormalizePaths(fields)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Select(fields)
+	}
+}
+
+// whereScope 将 ent 过滤选择器包装为 Scope，没有选择器时返回 nil
+func whereScope(selectors ...func(s *sql.Selector)) Scope {
+	var fncs []func(s *sql.Selector)
+	for _, fnc := range selectors {
+		if fnc != nil {
+			fncs = append(fncs, fnc)
+		}
+	}
+	if len(fncs) == 0 {
+		return nil
+	}
+
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(selectorExpr{selectors: fncs})
+	}
+}
+
+// selectorExpr 在构建 SQL 时执行 ent 选择器，并将生成的 WHERE 条件写入 GORM 语句
+type selectorExpr struct {
+	selectors []func(s *sql.Selector)
+}
+
+// Build 实现 clause.Expression
+func (e selectorExpr) Build(builder clause.Builder) {
+	stmt, ok := builder.(*gorm.Statement)
+	if !ok {
+		_ = builder.AddError(errors.New("gorm: filter scope must be built by gorm.Statement"))
+		return
+	}
+	if stmt.Table == "" && stmt.AddError(stmt.Parse(stmt.Model)) != nil {
+		return
+	}
+
+	d := entDialect(stmt.Dialector.Name())
+	s := sql.Dialect(d).Select().From(sql.Table(stmt.Table))
+	for _, fnc := range e.selectors {
+		fnc(s)
+	}
+
+	b := &sql.Builder{}
+	b.SetDialect(d)
+	if p := s.P(); p != nil {
+		b.Join(p)
+	}
+	if err := s.Err(); err != nil {
+		_ = stmt.AddError(err)
+		return
+	}
+
+	query, args := b.Query()
+	if strings.TrimSpace(query) == "" {
+		// 没有有效条件时保持 WHERE 子句合法
+		stmt.WriteString("1 = 1")
+		return
+	}
+	writeQuery(stmt, query, args, d == dialect.Postgres)
+}
+
+// entDialect 将 GORM 的方言名转换为 ent 的方言名
+//
+// ClickHouse 和 SQL Server 的方言名与 entgo.DialectClickHouse、entgo.DialectSQLServer 相同。
+func entDialect(name string) string {
+	if name == "sqlite" {
+		return dialect.SQLite
+	}
+	return name
+}
+
+// writeQuery 将 ent 生成的 SQL 写入 GORM 语句
+//
+// 标识符按 GORM 的方言重新引用，参数占位符交由 GORM 生成，字符串常量原样写入。
+func writeQuery(stmt *gorm.Statement, query string, args []any, postgres bool) {
+	var n int
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			j := i + 1
+			for ; j < len(query); j++ {
+				if query[j] != '\'' {
+					continue
+				}
+				if j+1 < len(query) && query[j+1] == '\'' {
+					j++
+					continue
+				}
+				break
+			}
+			if j >= len(query) {
+				j = len(query) - 1
+			}
+			stmt.WriteString(query[i : j+1])
+			i = j
+
+		case c == '`' || c == '"':
+			j := strings.IndexByte(query[i+1:], c)
+			if j < 0 {
+				stmt.WriteString(query[i:])
+				return
+			}
+			stmt.DB.Dialector.QuoteTo(stmt, query[i+1:i+1+j])
+			i += j + 1
+
+		case postgres && c == '$' && i+1 < len(query) && isDigit(query[i+1]):
+			j := i + 1
+			for j < len(query) && isDigit(query[j]) {
+				j++
+			}
+			idx, _ := strconv.Atoi(query[i+1 : j])
+			if idx >= 1 && idx <= len(args) {
+				stmt.AddVar(stmt, args[idx-1])
+			}
+			i = j - 1
+
+		case !postgres && c == '?' && n < len(args):
+			stmt.AddVar(stmt, args[n])
+			n++
+
+		default:
+			_ = stmt.WriteByte(c)
+		}
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,211 @@
+package gorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	entgo "github.com/heyinLab/common/pkg/utils/entgo/query"
+	"github.com/heyinLab/common/pkg/utils/query_parser"
+)
+
+type user struct {
+	ID   int
+	Name string
+	Age  int
+	Meta string
+	Tags string
+}
+
+// dryRun 返回只生成 SQL、不连接数据库的 GORM 实例
+func dryRun(t *testing.T, dialector gorm.Dialector) *gorm.DB {
+	db, err := gorm.Open(dialector, &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	require.NoError(t, err)
+	return db
+}
+
+func TestBuildQueryScopesDryRun(t *testing.T) {
+	testcases := []struct {
+		name string
+		db   *gorm.DB
+		sql  string
+		vars []any
+	}{
+		{
+			"MySQL",
+			dryRun(t, mysql.New(mysql.Config{DSN: "root@tcp(127.0.0.1:3306)/test", SkipInitializeWithVersion: true})),
+			"SELECT `name`,`age` FROM `users` WHERE (`users`.`name` LIKE ? AND YEAR(`users`.`created_at`) >= ? AND (JSON_EXTRACT(`users`.`meta`, '$.city') = ?)) AND (`users`.`age` = ? OR `users`.`age` = ?) ORDER BY `users`.`age` DESC,`users`.`name` LIMIT ? OFFSET ?",
+			[]any{"%a%", "2023", "Beijing", "1", "2", 10, 10},
+		},
+		{
+			"PostgreSQL",
+			dryRun(t, postgres.New(postgres.Config{DSN: "host=127.0.0.1 user=test dbname=test"})),
+			`SELECT "name","age" FROM "users" WHERE ("users"."name" LIKE $1 AND EXTRACT('YEAR' FROM "users"."created_at") >= $2 AND ("users"."meta" ->> 'city' = $3)) AND ("users"."age" = $4 OR "users"."age" = $5) ORDER BY "users"."age" DESC,"users"."name" LIMIT $6 OFFSET $7`,
+			[]any{"%a%", "2023", "Beijing", "1", "2", 10, 10},
+		},
+		{
+			"SQLServer",
+			dryRun(t, sqlserver.Open("sqlserver://test@127.0.0.1:1433?database=test")),
+			`SELECT "name","age" FROM "users" WHERE ("users"."name" LIKE @p1 AND DATEPART(year, "users"."created_at") >= @p2 AND (JSON_VALUE("users"."meta", '$.city') = @p3)) AND ("users"."age" = @p4 OR "users"."age" = @p5) ORDER BY "users"."age" DESC,"users"."name" OFFSET 10 ROWS FETCH NEXT 10 ROWS ONLY`,
+			[]any{"%a%", "2023", "Beijing", "1", "2"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err, _, queryScopes := BuildQueryScopes(nil,
+				`{"name__contains":"a","created_at__year__gte":"2023","meta.city":"Beijing"}`, `[{"age":"1"},{"age":"2"}]`,
+				2, 10, false,
+				[]string{"-age", "name"}, "id",
+				[]string{"name", "age"},
+			)
+			require.NoError(t, err)
+
+			var users []user
+			stmt := tc.db.Scopes(queryScopes...).Find(&users).Statement
+			require.NoError(t, stmt.Error)
+			require.Equal(t, tc.sql, stmt.SQL.String())
+			require.Equal(t, tc.vars, stmt.Vars)
+		})
+	}
+}
+
+func TestBuildQueryScopesPolicy(t *testing.T) {
+	policy := &entgo.QueryPolicy{
+		Fields: map[string]entgo.FieldPolicy{
+			"userName":  {Column: "name", Operators: []string{"icontains"}, Sortable: true},
+			"createdAt": {Column: "created_at"},
+		},
+		MaxPageSize: 100,
+	}
+	db := dryRun(t, postgres.New(postgres.Config{DSN: "host=127.0.0.1 user=test dbname=test"}))
+
+	err, whereScopes, queryScopes := BuildQueryScopes(policy, `{"userName__icontains":"a"}`, "", 1, 20, false, []string{"-userName"}, "created_at", nil)
+	require.NoError(t, err)
+
+	var total int64
+	stmt := db.Model(&user{}).Scopes(whereScopes...).Count(&total).Statement
+	require.NoError(t, stmt.Error)
+	require.Equal(t, `SELECT count(*) FROM "users" WHERE "users"."name" ILIKE $1`, stmt.SQL.String())
+
+	var users []user
+	stmt = db.Scopes(queryScopes...).Find(&users).Statement
+	require.NoError(t, stmt.Error)
+	require.Equal(t, `SELECT * FROM "users" WHERE "users"."name" ILIKE $1 ORDER BY "users"."name" DESC LIMIT $2`, stmt.SQL.String())
+
+	for _, tc := range []struct {
+		query    string
+		orderBys []string
+		pageSize int32
+		noPaging bool
+	}{
+		{query: `{"userName__startswith":"a"}`},
+		{query: `{"age":"1"}`},
+		{orderBys: []string{"createdAt"}},
+		{pageSize: 1000},
+		{noPaging: true},
+	} {
+		err, _, _ = BuildQueryScopes(policy, tc.query, "", 1, tc.pageSize, tc.noPaging, tc.orderBys, "created_at", nil)
+		require.Error(t, err, "%+v", tc)
+	}
+
+	err, _, _ = BuildQueryScopes(policy, "", "", 1, 20, false, nil, "created_at", []string{"userName", "age"})
+	require.Error(t, err)
+}
+
+// openSQLite 打开内存数据库并写入测试数据
+func openSQLite(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	require.NoError(t, db.Exec(`
+CREATE TABLE users (
+	id         INTEGER PRIMARY KEY,
+	name       TEXT,
+	age        INTEGER,
+	meta       TEXT,
+	created_at DATETIME,
+	deleted_at DATETIME,
+	tags       TEXT
+);
+INSERT INTO users VALUES
+	(1, 'Alice', 20, '{"city":"Beijing","level":"gold"}', '2023-01-15 08:30:45.123', NULL, '["go","db"]'),
+	(2, 'bob', 30, '{"city":"Shanghai","level":"silver"}', '2023-06-10 12:00:00', '2023-07-01 00:00:00', '["go"]'),
+	(3, 'Carol', 40, '{"city":"beijing"}', '2024-03-31 23:59:59', NULL, '[]'),
+	(4, 'Dave', 50, '{"city":"Shenzhen","zip":"518000"}', '2021-01-01 00:00:00', NULL, '["rust","db","wasm"]');
+`).Error)
+
+	return db
+}
+
+// TestSQLiteScopes 在 SQLite 内存数据库中执行 Scope 生成的查询，结果与 entgo 的一致
+func TestSQLiteScopes(t *testing.T) {
+	db := openSQLite(t)
+
+	testcases := []struct {
+		name  string
+		query string
+		or    string
+		ids   []int
+	}{
+		{"in", `{"age__in":"[20, 40]"}`, "", []int{1, 3}},
+		{"range", `{"age__range":"[30, 40]"}`, "", []int{2, 3}},
+		{"isnull", `{"deleted_at__isnull":"True"}`, "", []int{1, 3, 4}},
+		{"icontains", `{"name__icontains":"A"}`, "", []int{1, 3, 4}},
+		{"not", `{"not":{"name":"Alice"}}`, "", []int{2, 3, 4}},
+		{"or", "", `[{"age":"20"},{"name":"Dave"}]`, []int{1, 4}},
+		{"and_or", `{"age__gte":"30"}`, `[{"age":"20"},{"name":"Dave"}]`, []int{4}},
+		{"year", `{"created_at__year":"2023"}`, "", []int{1, 2}},
+		{"week_day", `{"created_at__week_day":"1"}`, "", []int{1, 3}},
+		{"date_range", `{"created_at__date__range":"[\"2023-01-01\", \"2023-12-31\"]"}`, "", []int{1, 2}},
+		{"json", `{"meta.city":"Beijing"}`, "", []int{1}},
+		{"json_isnull", `{"meta__level__isnull":"True"}`, "", []int{3, 4}},
+		{"has", `{"tags__has":"go"}`, "", []int{1, 2}},
+		{"has_all", `{"tags__has_all":["go","db"]}`, "", []int{1}},
+		{"len_gt", `{"tags__len_gt":"1"}`, "", []int{1, 4}},
+		{"has_any_keys", `{"meta__has_any_keys":["level","zip"]}`, "", []int{1, 2, 4}},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err, scope := BuildFilterScope(nil, tc.query, tc.or)
+			require.NoError(t, err)
+
+			var ids []int
+			require.NoError(t, db.Model(&user{}).Scopes(scope).Order("id").Pluck("id", &ids).Error)
+			require.Equal(t, tc.ids, ids)
+		})
+	}
+
+	err, whereScopes, queryScopes := BuildQueryScopes(nil, `{"age__gte":"20"}`, "", 2, 2, false, []string{"-age"}, "id", []string{"id", "name"})
+	require.NoError(t, err)
+
+	var total int64
+	require.NoError(t, db.Model(&user{}).Scopes(whereScopes...).Count(&total).Error)
+	require.Equal(t, int64(4), total)
+
+	var users []user
+	require.NoError(t, db.Scopes(queryScopes...).Find(&users).Error)
+	require.Equal(t, []user{{ID: 2, Name: "bob"}, {ID: 1, Name: "Alice"}}, users)
+
+	// 过滤表达式
+	expr, err := query_parser.ParseFilterExprQueryString("or(name:Alice,age__gt:40)")
+	require.NoError(t, err)
+	var ids []int
+	require.NoError(t, db.Model(&user{}).Scopes(BuildFilterExprScope(nil, expr)).Order("id").Pluck("id", &ids).Error)
+	require.Equal(t, []int{1, 4}, ids)
+
+	// 转换失败的错误通过 gorm.DB 的 Error 返回
+	err, scope := BuildFilterScope(nil, `{"tags__len":"x"}`, "")
+	require.NoError(t, err)
+	require.Error(t, db.Model(&user{}).Scopes(scope).Pluck("id", &ids).Error)
+}
@@ -3,6 +3,7 @@ package jwtutil
 import (
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-kratos/kratos/v2/encoding"
@@ -79,6 +80,28 @@ func VerifyJWT(tokenString string, secretKey []byte) (jwt.MapClaims, error) {
 	return nil, fmt.Errorf("invalid token")
 }
 
+// VerifyJWTWithOptions 验证 JWT 的签名以及标准声明（exp/nbf/iss/aud 等）
+//
+// keyFunc 用于根据 token 头部返回验签密钥，opts 为 jwt.Parser 的校验选项，
+// 例如 jwt.WithIssuer、jwt.WithAudience、jwt.WithLeeway、jwt.WithValidMethods。
+// 返回的错误可以使用 errors.Is 与 jwt.ErrTokenExpired 等进行比较。
+func VerifyJWTWithOptions(tokenString string, keyFunc jwt.Keyfunc, opts ...jwt.ParserOption) (jwt.MapClaims, error) {
+	if keyFunc == nil {
+		return nil, fmt.Errorf("key func cannot be nil")
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, jwt.MapClaims{}, keyFunc, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		return claims, nil
+	}
+
+	return nil, fmt.Errorf("invalid token")
+}
+
 func GetJWTClaims(tokenString string) (map[string]interface{}, error) {
 	claims, err := ParseJWTPayload(tokenString)
 	if err != nil {
@@ -213,6 +236,25 @@ func ExtractJWTFromRequest(r *http.Request) (string, error) {
 	return token, nil
 }
 
+// ExtractBearerToken 从 Authorization 头的值中提取 Bearer Token
+func ExtractBearerToken(authHeader string) (string, error) {
+	if authHeader == "" {
+		return "", fmt.Errorf("authorization header is missing")
+	}
+
+	const bearerPrefix = "Bearer "
+	if len(authHeader) <= len(bearerPrefix) || !strings.EqualFold(authHeader[:len(bearerPrefix)], bearerPrefix) {
+		return "", fmt.Errorf("invalid authorization header format")
+	}
+
+	token := strings.TrimSpace(authHeader[len(bearerPrefix):])
+	if token == "" {
+		return "", fmt.Errorf("invalid authorization header format")
+	}
+
+	return token, nil
+}
+
 // GenerateShortLivedJWT 生成短期有效的JWT
 func GenerateShortLivedJWT(payload jwt.MapClaims, secretKey []byte, signingMethod jwt.SigningMethod, duration time.Duration) (string, error) {
 	// 检查密钥是否为空
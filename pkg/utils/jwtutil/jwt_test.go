@@ -517,3 +517,69 @@ func TestGetJWTClaims(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, claims)
 }
+
+func TestVerifyJWTWithOptions(t *testing.T) {
+	secretKey := []byte("secret")
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		return secretKey, nil
+	}
+
+	token, err := GenerateJWT(jwt.MapClaims{
+		"sub": "userId",
+		"iss": "heyin",
+		"aud": "api",
+		"exp": time.Now().Add(1 * time.Hour).Unix(),
+	}, secretKey, jwt.SigningMethodHS256)
+	assert.NoError(t, err)
+
+	// 测试有效的 JWT
+	claims, err := VerifyJWTWithOptions(token, keyFunc, jwt.WithIssuer("heyin"), jwt.WithAudience("api"))
+	assert.NoError(t, err)
+	assert.Equal(t, "userId", claims["sub"])
+
+	// 测试发行者不匹配
+	_, err = VerifyJWTWithOptions(token, keyFunc, jwt.WithIssuer("other"))
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidIssuer)
+
+	// 测试受众不匹配
+	_, err = VerifyJWTWithOptions(token, keyFunc, jwt.WithAudience("other"))
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+
+	// 测试过期的 JWT
+	expired, err := GenerateJWT(jwt.MapClaims{
+		"sub": "userId",
+		"exp": time.Now().Add(-1 * time.Hour).Unix(),
+	}, secretKey, jwt.SigningMethodHS256)
+	assert.NoError(t, err)
+	_, err = VerifyJWTWithOptions(expired, keyFunc)
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+
+	// 测试签名错误
+	_, err = VerifyJWTWithOptions(token, func(token *jwt.Token) (interface{}, error) {
+		return []byte("wrong"), nil
+	})
+	assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+
+	// 测试空 keyFunc
+	_, err = VerifyJWTWithOptions(token, nil)
+	assert.Error(t, err)
+}
+
+func TestExtractBearerToken(t *testing.T) {
+	token, err := ExtractBearerToken("Bearer abc.def.ghi")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc.def.ghi", token)
+
+	token, err = ExtractBearerToken("bearer abc.def.ghi")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc.def.ghi", token)
+
+	_, err = ExtractBearerToken("")
+	assert.Error(t, err)
+
+	_, err = ExtractBearerToken("Basic abc")
+	assert.Error(t, err)
+
+	_, err = ExtractBearerToken("Bearer ")
+	assert.Error(t, err)
+}
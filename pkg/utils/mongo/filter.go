@@ -0,0 +1,527 @@
+package mongo
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	entgo "github.com/heyinLab/common/pkg/utils/entgo/query"
+	"github.com/heyinLab/common/pkg/utils/query_parser"
+	"github.com/heyinLab/common/pkg/utils/stringcase"
+)
+
+// IDField MongoDB 的主键字段，过滤、排序和字段选择中的 id 会被替换为该字段
+const IDField = "_id"
+
+// 数组和嵌套文档的集合操作符，与 entgo 一致
+const (
+	filterHas        = "has"
+	filterHasAny     = "has_any"
+	filterHasAll     = "has_all"
+	filterOverlap    = "overlap"
+	filterHasKey     = "has_key"
+	filterHasKeys    = "has_keys"
+	filterHasAnyKeys = "has_any_keys"
+)
+
+// 比较操作符对应的 MongoDB 操作符
+var compareOps = map[string]string{
+	"":                     "$eq",
+	query_parser.FilterNot: "$ne",
+	query_parser.FilterGTE: "$gte",
+	query_parser.FilterGT:  "$gt",
+	query_parser.FilterLTE: "$lte",
+	query_parser.FilterLT:  "$lt",
+}
+
+// 数组长度操作符对应的聚合比较操作符
+var lenOps = map[string]string{
+	"len":     "$eq",
+	"len_gt":  "$gt",
+	"len_gte": "$gte",
+	"len_lt":  "$lt",
+	"len_lte": "$lte",
+}
+
+// 日期部分对应的聚合表达式
+var datePartExprs = map[string]func(path string) any{
+	query_parser.DatePartDate: func(path string) any {
+		return bson.D{{Key: "$dateToString", Value: bson.D{{Key: "format", Value: "%Y-%m-%d"}, {Key: "date", Value: path}}}}
+	},
+	query_parser.DatePartYear:    dateOperator("$year"),
+	query_parser.DatePartISOYear: dateOperator("$isoWeekYear"),
+	query_parser.DatePartQuarter: func(path string) any {
+		return bson.D{{Key: "$ceil", Value: bson.D{{Key: "$divide", Value: bson.A{bson.D{{Key: "$month", Value: path}}, 3}}}}}
+	},
+	query_parser.DatePartMonth:      dateOperator("$month"),
+	query_parser.DatePartWeek:       dateOperator("$isoWeek"),
+	query_parser.DatePartWeekDay:    dateOperator("$dayOfWeek"),
+	query_parser.DatePartISOWeekDay: dateOperator("$isoDayOfWeek"),
+	query_parser.DatePartDay:        dateOperator("$dayOfMonth"),
+	query_parser.DatePartTime: func(path string) any {
+		return bson.D{{Key: "$dateToString", Value: bson.D{{Key: "format", Value: "%H:%M:%S"}, {Key: "date", Value: path}}}}
+	},
+	query_parser.DatePartHour:   dateOperator("$hour"),
+	query_parser.DatePartMinute: dateOperator("$minute"),
+	query_parser.DatePartSecond: dateOperator("$second"),
+	query_parser.DatePartMicrosecond: func(path string) any {
+		return bson.D{{Key: "$multiply", Value: bson.A{bson.D{{Key: "$millisecond", Value: path}}, 1000}}}
+	},
+}
+
+func dateOperator(op string) func(path string) any {
+	return func(path string) any {
+		return bson.D{{Key: op, Value: path}}
+	}
+}
+
+// BuildFilter 将 AND、OR 过滤条件转换为 MongoDB 过滤文档，语法与 entgo.BuildFilterSelector 一致
+//
+// r 为字段类型登记表，可以为 nil。MongoDB 按类型比较，数字、布尔和时间字段需要登记类型，否则按字符串查询。
+//
+// 使用示例:
+//
+//	types := entgo.NewFieldRegistry().Add("age", entgo.FieldTypeInt)
+//	err, filter := mongo.BuildFilter(types, `{"age__gte":"18","name__icontains":"tom"}`, "")
+//	cursor, err := collection.Find(ctx, filter)
+func BuildFilter(r *entgo.FieldRegistry, andFilterJsonString, orFilterJsonString string) (error, bson.D) {
+	err, andExpr, orExpr := (*entgo.QueryPolicy)(nil).ParseFilters(andFilterJsonString, orFilterJsonString)
+	if err != nil {
+		return err, nil
+	}
+	return buildFilter(r, andExpr, orExpr)
+}
+
+// buildFilter 将解析后的 AND、OR 过滤条件转换为过滤文档
+func buildFilter(r *entgo.FieldRegistry, andExpr, orExpr *query_parser.FilterExpr) (error, bson.D) {
+	var conds []bson.D
+	if !andExpr.IsEmpty() {
+		err, cond := buildExprs(r, "$and", topLevelFilterExprs(andExpr))
+		if err != nil {
+			return err, nil
+		}
+		if cond != nil {
+			conds = append(conds, cond)
+		}
+	}
+	if !orExpr.IsEmpty() {
+		err, cond := buildExprs(r, "$or", topLevelFilterExprs(orExpr))
+		if err != nil {
+			return err, nil
+		}
+		if cond != nil {
+			conds = append(conds, cond)
+		}
+	}
+
+	return nil, combine("$and", conds)
+}
+
+// BuildFilterExpr 将过滤表达式转换为 MongoDB 过滤文档，没有有效条件时返回空文档
+//
+// 使用示例:
+//
+//	expr, err := query_parser.ParseFilterExprQueryString("status:ON,or(name__icontains:x,code__startswith:x)")
+//	err, filter := mongo.BuildFilterExpr(nil, expr)
+func BuildFilterExpr(r *entgo.FieldRegistry, expr *query_parser.FilterExpr) (error, bson.D) {
+	err, cond := buildExpr(r, expr)
+	if err != nil {
+		return err, nil
+	}
+	if cond == nil {
+		return nil, bson.D{}
+	}
+	return nil, cond
+}
+
+// buildExpr 转换单个过滤表达式，没有有效条件时返回 nil
+func buildExpr(r *entgo.FieldRegistry, expr *query_parser.FilterExpr) (error, bson.D) {
+	if expr == nil {
+		return nil, nil
+	}
+
+	switch expr.Kind {
+	case query_parser.FilterExprLeaf:
+		return buildLeaf(r, expr.Key, expr.Value)
+
+	case query_parser.FilterExprNot:
+		if len(expr.Children) == 0 {
+			return nil, nil
+		}
+		err, cond := buildExpr(r, expr.Children[0])
+		if err != nil || cond == nil {
+			return err, nil
+		}
+		return nil, bson.D{{Key: "$nor", Value: bson.A{cond}}}
+
+	case query_parser.FilterExprOr:
+		return buildExprs(r, "$or", expr.Children)
+
+	case query_parser.FilterExprAnd:
+		return buildExprs(r, "$and", expr.Children)
+
+	default:
+		return nil, nil
+	}
+}
+
+// buildExprs 转换多个子表达式并按 $and 或 $or 组合，忽略无效条件
+func buildExprs(r *entgo.FieldRegistry, op string, children []*query_parser.FilterExpr) (error, bson.D) {
+	var conds []bson.D
+	for _, child := range children {
+		err, cond := buildExpr(r, child)
+		if err != nil {
+			return err, nil
+		}
+		if cond != nil {
+			conds = append(conds, cond)
+		}
+	}
+	if len(conds) == 0 {
+		return nil, nil
+	}
+	return nil, combine(op, conds)
+}
+
+// combine 组合多个条件，只有一个条件时直接返回
+func combine(op string, conds []bson.D) bson.D {
+	switch len(conds) {
+	case 0:
+		return bson.D{}
+	case 1:
+		return conds[0]
+	default:
+		items := make(bson.A, 0, len(conds))
+		for _, cond := range conds {
+			items = append(items, cond)
+		}
+		return bson.D{{Key: op, Value: items}}
+	}
+}
+
+// topLevelFilterExprs 展开顶层数组中的对象，兼容原有扁平格式的与/或语义
+func topLevelFilterExprs(expr *query_parser.FilterExpr) []*query_parser.FilterExpr {
+	if expr == nil || expr.Kind != query_parser.FilterExprAnd {
+		return []*query_parser.FilterExpr{expr}
+	}
+
+	var items []*query_parser.FilterExpr
+	for _, child := range expr.Children {
+		if child != nil && child.Kind == query_parser.FilterExprAnd {
+			items = append(items, child.Children...)
+		} else {
+			items = append(items, child)
+		}
+	}
+	return items
+}
+
+// buildLeaf 转换叶子节点，键的解析规则与 entgo 相同
+//
+//	{字段名}                       等值查询，字段名可以是 JSON 路径，如 meta.city
+//	{字段名}__{操作符或日期部分或JSON键}
+//	{字段名}__{日期部分}__{操作符}
+//	{字段名}__{JSON键}__{操作符或日期部分}
+func buildLeaf(r *entgo.FieldRegistry, key, value string) (error, bson.D) {
+	if len(key) == 0 || len(value) == 0 {
+		return nil, nil
+	}
+
+	keys := strings.Split(key, query_parser.JSONFilterFieldOperatorDelimiter)
+	path := fieldPath(keys[0])
+	if len(path) == 0 {
+		return nil, nil
+	}
+	for _, k := range keys[1:] {
+		if len(k) == 0 {
+			return nil, nil
+		}
+	}
+
+	switch len(keys) {
+	case 1:
+		return filterOp(r, path, "", value)
+
+	case 2:
+		op := strings.ToLower(keys[1])
+		if entgo.IsOperator(op) {
+			return filterOp(r, path, op, value)
+		} else if isDatePart(op) {
+			return filterDatePart(path, op, "", value)
+		}
+		return filterOp(r, path+query_parser.JsonFieldDelimiter+keys[1], "", value)
+
+	case 3:
+		op1 := strings.ToLower(keys[1])
+		op2 := strings.ToLower(keys[2])
+		if isDatePart(op1) {
+			if !entgo.IsOperator(op2) {
+				return nil, nil
+			}
+			return filterDatePart(path, op1, op2, value)
+		}
+
+		path += query_parser.JsonFieldDelimiter + keys[1]
+		if entgo.IsOperator(op2) {
+			return filterOp(r, path, op2, value)
+		} else if isDatePart(op2) {
+			return filterDatePart(path, op2, "", value)
+		}
+		return nil, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+// fieldPath 字段名转换为 snake_case 的文档路径，id 替换为 _id
+func fieldPath(field string) string {
+	parts := query_parser.SplitJSONField(strings.TrimSpace(field))
+	for i, part := range parts {
+		parts[i] = stringcase.ToSnakeCase(part)
+	}
+	if parts[0] == "id" {
+		parts[0] = IDField
+	}
+	return strings.Join(parts, query_parser.JsonFieldDelimiter)
+}
+
+// isDatePart 是否为支持的日期部分
+func isDatePart(op string) bool {
+	_, ok := datePartExprs[op]
+	return ok
+}
+
+// filterOp 按操作符构建字段条件
+func filterOp(r *entgo.FieldRegistry, path, op, value string) (error, bson.D) {
+	switch op {
+	case "", query_parser.FilterNot, query_parser.FilterExact,
+		query_parser.FilterGTE, query_parser.FilterGT, query_parser.FilterLTE, query_parser.FilterLT:
+		v, err := coerce(r, path, value)
+		if err != nil {
+			return err, nil
+		}
+		if op == "" || op == query_parser.FilterExact {
+			return nil, bson.D{{Key: path, Value: v}}
+		}
+		return nil, fieldCond(path, compareOps[op], v)
+
+	case query_parser.FilterIn, query_parser.FilterNotIn, query_parser.FilterRange:
+		err, values := coerceList(r, path, value)
+		if err != nil {
+			return err, nil
+		}
+		if op == query_parser.FilterIn {
+			return nil, fieldCond(path, "$in", values)
+		} else if op == query_parser.FilterNotIn {
+			return nil, fieldCond(path, "$nin", values)
+		}
+		if len(values) != 2 {
+			return fmt.Errorf("invalid value %q for %s: range requires 2 values", value, path), nil
+		}
+		return nil, bson.D{{Key: path, Value: bson.D{{Key: "$gte", Value: values[0]}, {Key: "$lte", Value: values[1]}}}}
+
+	case query_parser.FilterIsNull:
+		return nil, bson.D{{Key: path, Value: nil}}
+	case query_parser.FilterNotIsNull:
+		return nil, fieldCond(path, "$ne", nil)
+
+	case query_parser.FilterContains:
+		return nil, regexCond(path, regexp.QuoteMeta(value), "")
+	case query_parser.FilterInsensitiveContains:
+		return nil, regexCond(path, regexp.QuoteMeta(value), "i")
+	case query_parser.FilterStartsWith:
+		return nil, regexCond(path, "^"+regexp.QuoteMeta(value), "")
+	case query_parser.FilterInsensitiveStartsWith:
+		return nil, regexCond(path, "^"+regexp.QuoteMeta(value), "i")
+	case query_parser.FilterEndsWith:
+		return nil, regexCond(path, regexp.QuoteMeta(value)+"$", "")
+	case query_parser.FilterInsensitiveEndsWith:
+		return nil, regexCond(path, regexp.QuoteMeta(value)+"$", "i")
+	case query_parser.FilterInsensitiveExact:
+		return nil, regexCond(path, "^"+regexp.QuoteMeta(value)+"$", "i")
+	case query_parser.FilterRegex:
+		return nil, regexCond(path, value, "")
+	case query_parser.FilterInsensitiveRegex:
+		return nil, regexCond(path, strings.TrimPrefix(value, "(?i)"), "i")
+
+	case query_parser.FilterSearch:
+		// 文本搜索作用于集合的文本索引，忽略字段名
+		return nil, bson.D{{Key: "$text", Value: bson.D{{Key: "$search", Value: value}}}}
+
+	default:
+		return filterCollection(r, path, op, value)
+	}
+}
+
+// filterCollection 数组和嵌套文档的集合操作，语义与 entgo 的集合操作符一致
+func filterCollection(r *entgo.FieldRegistry, path, op, value string) (error, bson.D) {
+	switch op {
+	case filterHas:
+		v, err := coerce(r, path, value)
+		if err != nil {
+			return err, nil
+		}
+		return nil, bson.D{{Key: path, Value: v}}
+
+	case filterHasAny, filterOverlap, filterHasAll:
+		values, err := entgo.CollectionValues(path, value)
+		if err != nil {
+			return err, nil
+		}
+		if op == filterHasAll {
+			return nil, fieldCond(path, "$all", values)
+		}
+		return nil, fieldCond(path, "$in", values)
+
+	case filterHasKey:
+		return nil, fieldCond(path+query_parser.JsonFieldDelimiter+value, "$exists", true)
+
+	case filterHasKeys, filterHasAnyKeys:
+		values, err := entgo.CollectionValues(path, value)
+		if err != nil {
+			return err, nil
+		}
+		conds := make([]bson.D, 0, len(values))
+		for _, v := range values {
+			conds = append(conds, fieldCond(path+query_parser.JsonFieldDelimiter+entgo.JsonItemString(v), "$exists", true))
+		}
+		if op == filterHasKeys {
+			return nil, combine("$and", conds)
+		}
+		return nil, combine("$or", conds)
+
+	default:
+		cmp, ok := lenOps[op]
+		if !ok {
+			return nil, nil
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid value %q for %s: expected int", value, path), nil
+		}
+		if cmp == "$eq" {
+			return nil, fieldCond(path, "$size", n)
+		}
+		// 非数组字段不参与比较，与 SQL 中 NULL 的比较结果一致
+		field := "$" + path
+		return nil, bson.D{{Key: "$expr", Value: bson.D{{Key: "$and", Value: bson.A{
+			bson.D{{Key: "$isArray", Value: field}},
+			bson.D{{Key: cmp, Value: bson.A{bson.D{{Key: "$size", Value: field}}, n}}},
+		}}}}}
+	}
+}
+
+// filterDatePart 按日期部分比较，使用聚合表达式 $expr，日期按 UTC 计算
+//
+// 支持等值、not、in、not_in、gte、gt、lte、lt、range 操作符。
+func filterDatePart(path, datePart, op, value string) (error, bson.D) {
+	expr := datePartExprs[datePart]("$" + path)
+
+	parse := func(s string) (any, error) {
+		if datePart == query_parser.DatePartDate || datePart == query_parser.DatePartTime {
+			return s, nil
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s__%s: expected int", s, path, datePart)
+		}
+		return n, nil
+	}
+
+	switch op {
+	case "", query_parser.FilterNot, query_parser.FilterGTE, query_parser.FilterGT, query_parser.FilterLTE, query_parser.FilterLT:
+		v, err := parse(value)
+		if err != nil {
+			return err, nil
+		}
+		cmp := compareOps[op]
+		return nil, bson.D{{Key: "$expr", Value: bson.D{{Key: cmp, Value: bson.A{expr, v}}}}}
+
+	case query_parser.FilterIn, query_parser.FilterNotIn, query_parser.FilterRange:
+		var items []any
+		if err := json.Unmarshal([]byte(value), &items); err != nil {
+			return fmt.Errorf("invalid value %q for %s__%s: expected a json array", value, path, datePart), nil
+		}
+		values := make(bson.A, 0, len(items))
+		for _, item := range items {
+			v, err := parse(entgo.JsonItemString(item))
+			if err != nil {
+				return err, nil
+			}
+			values = append(values, v)
+		}
+
+		var cond bson.D
+		switch op {
+		case query_parser.FilterIn:
+			cond = bson.D{{Key: "$in", Value: bson.A{expr, values}}}
+		case query_parser.FilterNotIn:
+			cond = bson.D{{Key: "$not", Value: bson.A{bson.D{{Key: "$in", Value: bson.A{expr, values}}}}}}
+		default:
+			if len(values) != 2 {
+				return fmt.Errorf("invalid value %q for %s__%s: range requires 2 values", value, path, datePart), nil
+			}
+			cond = bson.D{{Key: "$and", Value: bson.A{
+				bson.D{{Key: "$gte", Value: bson.A{expr, values[0]}}},
+				bson.D{{Key: "$lte", Value: bson.A{expr, values[1]}}},
+			}}}
+		}
+		return nil, bson.D{{Key: "$expr", Value: cond}}
+
+	default:
+		return fmt.Errorf("operator %q is not supported for date part %s", op, datePart), nil
+	}
+}
+
+// fieldCond 构建 {path: {op: value}}
+func fieldCond(path, op string, value any) bson.D {
+	return bson.D{{Key: path, Value: bson.D{{Key: op, Value: value}}}}
+}
+
+// regexCond 构建 {path: {$regex: pattern, $options: options}}
+func regexCond(path, pattern, options string) bson.D {
+	cond := bson.D{{Key: "$regex", Value: pattern}}
+	if options != "" {
+		cond = append(cond, bson.E{Key: "$options", Value: options})
+	}
+	return bson.D{{Key: path, Value: cond}}
+}
+
+// coerce 按登记的字段类型转换过滤值，_id 的值为 ObjectID 格式时转换为 ObjectID
+func coerce(r *entgo.FieldRegistry, path, value string) (any, error) {
+	if path == IDField {
+		if oid, err := primitive.ObjectIDFromHex(value); err == nil {
+			return oid, nil
+		}
+	}
+	return r.Coerce(path, value)
+}
+
+// coerceList 解析 JSON 数组并转换每个元素，未登记类型的元素保留 JSON 类型
+func coerceList(r *entgo.FieldRegistry, path, value string) (error, bson.A) {
+	var items []any
+	if err := json.Unmarshal([]byte(value), &items); err != nil {
+		return fmt.Errorf("invalid value %q for %s: expected a json array", value, path), nil
+	}
+
+	_, typed := r.Lookup(path)
+	values := make(bson.A, 0, len(items))
+	for _, item := range items {
+		if !typed && path != IDField {
+			values = append(values, item)
+			continue
+		}
+		v, err := coerce(r, path, entgo.JsonItemString(item))
+		if err != nil {
+			return err, nil
+		}
+		values = append(values, v)
+	}
+	return nil, values
+}
@@ -0,0 +1,173 @@
+package mongo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	entgo "github.com/heyinLab/common/pkg/utils/entgo/query"
+	"github.com/heyinLab/common/pkg/utils/query_parser"
+)
+
+func toJSON(t *testing.T, doc bson.D) string {
+	b, err := bson.MarshalExtJSON(doc, false, false)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestBuildFilter(t *testing.T) {
+	types := entgo.NewFieldRegistry().
+		Add("age", entgo.FieldTypeInt).
+		Add("enabled", entgo.FieldTypeBool)
+
+	testcases := []struct {
+		name string
+		and  string
+		or   string
+		json string
+	}{
+		{"empty", "", "", `{}`},
+		{"equal", `{"name":"tom"}`, "", `{"name":"tom"}`},
+		{"typed", `{"age":"18","enabled":"true"}`, "", `{"$and":[{"age":18},{"enabled":true}]}`},
+		{"snake_case", `{"userName":"tom"}`, "", `{"user_name":"tom"}`},
+		{"id", `{"id":"65a1b2c3d4e5f60718293a4b"}`, "", `{"_id":{"$oid":"65a1b2c3d4e5f60718293a4b"}}`},
+		{"not", `{"name__not":"tom"}`, "", `{"name":{"$ne":"tom"}}`},
+		{"in", `{"age__in":"[1, 2]"}`, "", `{"age":{"$in":[1,2]}}`},
+		{"in_untyped", `{"status__in":["ON",1]}`, "", `{"status":{"$in":["ON",1.0]}}`},
+		{"not_in", `{"name__not_in":["a","b"]}`, "", `{"name":{"$nin":["a","b"]}}`},
+		{"gte", `{"age__gte":"18"}`, "", `{"age":{"$gte":18}}`},
+		{"range", `{"age__range":"[18, 30]"}`, "", `{"age":{"$gte":18,"$lte":30}}`},
+		{"isnull", `{"deleted_at__isnull":"True"}`, "", `{"deleted_at":null}`},
+		{"not_isnull", `{"deleted_at__not_isnull":"True"}`, "", `{"deleted_at":{"$ne":null}}`},
+		{"contains", `{"name__contains":"a.b"}`, "", `{"name":{"$regex":"a\\.b"}}`},
+		{"icontains", `{"name__icontains":"Tom"}`, "", `{"name":{"$regex":"Tom","$options":"i"}}`},
+		{"startswith", `{"name__startswith":"T"}`, "", `{"name":{"$regex":"^T"}}`},
+		{"iendswith", `{"name__iendswith":"m"}`, "", `{"name":{"$regex":"m$","$options":"i"}}`},
+		{"exact", `{"name__exact":"tom"}`, "", `{"name":"tom"}`},
+		{"iexact", `{"name__iexact":"tom"}`, "", `{"name":{"$regex":"^tom$","$options":"i"}}`},
+		{"regex", `{"name__regex":"^(An?|The) +"}`, "", `{"name":{"$regex":"^(An?|The) +"}}`},
+		{"iregex", `{"name__iregex":"^the"}`, "", `{"name":{"$regex":"^the","$options":"i"}}`},
+		{"search", `{"content__search":"mongo"}`, "", `{"$text":{"$search":"mongo"}}`},
+
+		{"json_path", `{"meta.city":"Beijing"}`, "", `{"meta.city":"Beijing"}`},
+		{"json_key", `{"meta__level":"gold"}`, "", `{"meta.level":"gold"}`},
+		{"json_key_op", `{"meta__city__icontains":"bei"}`, "", `{"meta.city":{"$regex":"bei","$options":"i"}}`},
+		{"json_path_op", `{"meta.city__startswith":"Bei"}`, "", `{"meta.city":{"$regex":"^Bei"}}`},
+
+		{"date_part", `{"created_at__year":"2023"}`, "", `{"$expr":{"$eq":[{"$year":"$created_at"},2023]}}`},
+		{"date_part_op", `{"created_at__month__gte":"6"}`, "", `{"$expr":{"$gte":[{"$month":"$created_at"},6]}}`},
+		{"date_part_in", `{"created_at__week_day__in":"[1, 7]"}`, "", `{"$expr":{"$in":[{"$dayOfWeek":"$created_at"},[1,7]]}}`},
+		{"date", `{"created_at__date":"2023-06-10"}`, "", `{"$expr":{"$eq":[{"$dateToString":{"format":"%Y-%m-%d","date":"$created_at"}},"2023-06-10"]}}`},
+		{"quarter", `{"created_at__quarter":"1"}`, "", `{"$expr":{"$eq":[{"$ceil":{"$divide":[{"$month":"$created_at"},3]}},1]}}`},
+
+		{"has", `{"tags__has":"go"}`, "", `{"tags":"go"}`},
+		{"has_any", `{"tags__has_any":["go","db"]}`, "", `{"tags":{"$in":["go","db"]}}`},
+		{"has_all", `{"tags__has_all":["go","db"]}`, "", `{"tags":{"$all":["go","db"]}}`},
+		{"len", `{"tags__len":"0"}`, "", `{"tags":{"$size":0}}`},
+		{"len_gt", `{"tags__len_gt":"1"}`, "", `{"$expr":{"$and":[{"$isArray":"$tags"},{"$gt":[{"$size":"$tags"},1]}]}}`},
+		{"has_key", `{"meta__has_key":"level"}`, "", `{"meta.level":{"$exists":true}}`},
+		{"has_any_keys", `{"meta__has_any_keys":["level","zip"]}`, "", `{"$or":[{"meta.level":{"$exists":true}},{"meta.zip":{"$exists":true}}]}`},
+
+		{"or", "", `[{"name":"tom"},{"name":"jerry"}]`, `{"$or":[{"name":"tom"},{"name":"jerry"}]}`},
+		{"and_or", `{"age__gte":"18"}`, `[{"name":"tom"},{"name":"jerry"}]`, `{"$and":[{"age":{"$gte":18}},{"$or":[{"name":"tom"},{"name":"jerry"}]}]}`},
+		{"nested", `{"status":"ON","or":[{"name":"tom"},{"not":{"age__lt":"18"}}]}`, "", `{"$and":[{"status":"ON"},{"$or":[{"name":"tom"},{"$nor":[{"age":{"$lt":18}}]}]}]}`},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err, filter := BuildFilter(types, tc.and, tc.or)
+			require.NoError(t, err)
+			require.Equal(t, tc.json, toJSON(t, filter))
+		})
+	}
+}
+
+func TestBuildFilterInvalid(t *testing.T) {
+	types := entgo.NewFieldRegistry().Add("age", entgo.FieldTypeInt)
+
+	for _, query := range []string{
+		`{"age":"x"}`,
+		`{"name__in":"x"}`,
+		`{"name__range":["a"]}`,
+		`{"created_at__year":"x"}`,
+		`{"created_at__year__contains":"1"}`,
+		`{"tags__has_any":"go"}`,
+		`{"tags__len":"x"}`,
+	} {
+		err, _ := BuildFilter(types, query, "")
+		require.Error(t, err, query)
+	}
+}
+
+func TestBuildFilterExpr(t *testing.T) {
+	expr, err := query_parser.ParseFilterExprQueryString("status:ON,or(name__icontains:x,code__startswith:x)")
+	require.NoError(t, err)
+
+	err, filter := BuildFilterExpr(nil, expr)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"$and":[{"status":"ON"},{"$or":[{"name":{"$regex":"x","$options":"i"}},{"code":{"$regex":"^x"}}]}]}`,
+		toJSON(t, filter),
+	)
+}
+
+func TestBuildQuery(t *testing.T) {
+	err, filter, opts := BuildQuery(nil,
+		`{"name":"tom"}`, "",
+		3, 20, false,
+		[]string{"-createTime", "id"}, "create_time",
+		[]string{"id", "userName"},
+	)
+	require.NoError(t, err)
+	require.Equal(t, `{"name":"tom"}`, toJSON(t, filter))
+	require.Equal(t, bson.D{{Key: "create_time", Value: -1}, {Key: "_id", Value: 1}}, opts.Sort)
+	require.Equal(t, int64(40), *opts.Skip)
+	require.Equal(t, int64(20), *opts.Limit)
+	require.Equal(t, bson.D{{Key: "_id", Value: 1}, {Key: "user_name", Value: 1}}, opts.Projection)
+
+	err, _, opts = BuildQuery(nil, "", "", 0, 0, true, nil, "create_time", nil)
+	require.NoError(t, err)
+	require.Equal(t, bson.D{{Key: "create_time", Value: -1}}, opts.Sort)
+	require.Nil(t, opts.Skip)
+	require.Nil(t, opts.Limit)
+	require.Nil(t, opts.Projection)
+}
+
+func TestBuildQueryPolicy(t *testing.T) {
+	policy := &entgo.QueryPolicy{
+		Fields: map[string]entgo.FieldPolicy{
+			"userName":  {Column: "name", Operators: []string{"icontains"}},
+			"age":       {Operators: []string{"gte"}},
+			"createdAt": {Column: "create_time", Sortable: true},
+		},
+		MaxPageSize: 100,
+		Types:       entgo.NewFieldRegistry().Add("age", entgo.FieldTypeInt),
+	}
+
+	err, filter, opts := BuildQuery(policy,
+		`{"userName__icontains":"a","age__gte":"18"}`, "",
+		1, 20, false,
+		[]string{"-createdAt"}, "create_time",
+		[]string{"userName", "age"},
+	)
+	require.NoError(t, err)
+	require.Equal(t, `{"$and":[{"name":{"$regex":"a","$options":"i"}},{"age":{"$gte":18}}]}`, toJSON(t, filter))
+	require.Equal(t, bson.D{{Key: "create_time", Value: -1}}, opts.Sort)
+	require.Equal(t, bson.D{{Key: "name", Value: 1}, {Key: "age", Value: 1}}, opts.Projection)
+
+	for _, tc := range []struct {
+		query        string
+		orderBys     []string
+		selectFields []string
+		pageSize     int32
+	}{
+		{query: `{"password":"x"}`},
+		{query: `{"userName__startswith":"a"}`},
+		{orderBys: []string{"age"}},
+		{selectFields: []string{"password"}},
+		{pageSize: 1000},
+	} {
+		err, _, _ = BuildQuery(policy, tc.query, "", 1, tc.pageSize, false, tc.orderBys, "create_time", tc.selectFields)
+		require.Error(t, err, "%+v", tc)
+	}
+}
@@ -0,0 +1,133 @@
+package mongo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	commonV1 "github.com/heyinLab/common/api/gen/go/common"
+	entgo "github.com/heyinLab/common/pkg/utils/entgo/query"
+	paging "github.com/heyinLab/common/pkg/utils/pagination"
+	"github.com/heyinLab/common/pkg/utils/query_parser"
+)
+
+// BuildQuery 按策略校验并构建分页过滤查询的过滤文档和查询选项，请求格式与 entgo.QueryPolicy.BuildQuerySelector 一致
+//
+// policy 的校验规则与 entgo 相同，字段别名会被替换为实际的列名，policy.Types 用于转换过滤值；policy 为 nil 时不做限制。
+//
+// 使用示例:
+//
+//	err, filter, opts := mongo.BuildQuery(userQueryPolicy,
+//	    req.GetQuery(), req.GetOrQuery(),
+//	    req.GetPage(), req.GetPageSize(), req.GetNoPaging(),
+//	    req.GetOrderBy(), "create_time",
+//	    req.GetFieldMask().GetPaths(),
+//	)
+//	total, err := collection.CountDocuments(ctx, filter)
+//	cursor, err := collection.Find(ctx, filter, opts)
+func BuildQuery(
+	policy *entgo.QueryPolicy,
+	andFilterJsonString, orFilterJsonString string,
+	page, pageSize int32, noPaging bool,
+	orderBys []string, defaultOrderField string,
+	selectFields []string,
+) (err error, filter bson.D, opts *options.FindOptions) {
+	if err = policy.CheckPaging(pageSize, noPaging); err != nil {
+		return err, nil, nil
+	}
+
+	err, andExpr, orExpr := policy.ParseFilters(andFilterJsonString, orFilterJsonString)
+	if err != nil {
+		return err, nil, nil
+	}
+	var r *entgo.FieldRegistry
+	if policy != nil {
+		r = policy.Types
+	}
+	if err, filter = buildFilter(r, andExpr, orExpr); err != nil {
+		return err, nil, nil
+	}
+
+	if len(orderBys) > 0 {
+		if err, orderBys = policy.OrderColumns(orderBys); err != nil {
+			return err, nil, nil
+		}
+	}
+	if err, selectFields = policy.SelectColumns(selectFields); err != nil {
+		return err, nil, nil
+	}
+
+	opts = options.Find()
+	if sort := BuildSort(orderBys, defaultOrderField); len(sort) > 0 {
+		opts.SetSort(sort)
+	}
+	if !noPaging {
+		skip, limit := BuildPagination(page, pageSize)
+		opts.SetSkip(skip).SetLimit(limit)
+	}
+	if projection := BuildProjection(selectFields); len(projection) > 0 {
+		opts.SetProjection(projection)
+	}
+
+	return nil, filter, opts
+}
+
+// BuildPagingQuery 将通用列表查询请求转换为过滤文档和查询选项，见 BuildQuery
+func BuildPagingQuery(policy *entgo.QueryPolicy, req *commonV1.PagingRequest, defaultOrderField string) (err error, filter bson.D, opts *options.FindOptions) {
+	return BuildQuery(policy,
+		req.GetQuery(), req.GetOrQuery(),
+		req.GetPage(), req.GetPageSize(), req.GetNoPaging(),
+		req.GetOrderBy(), defaultOrderField,
+		req.GetFieldMask().GetPaths(),
+	)
+}
+
+// BuildSort 构建排序文档，前缀 - 为降序，没有排序条件时按默认字段降序，字段名转换为 snake_case，id 替换为 _id
+func BuildSort(orderBys []string, defaultOrderField string) bson.D {
+	var sort bson.D
+	_ = query_parser.ParseOrderByStrings(orderBys, func(field string, desc bool) {
+		if len(field) == 0 {
+			return
+		}
+		sort = append(sort, sortField(field, desc))
+	})
+
+	if len(sort) == 0 && defaultOrderField != "" {
+		sort = append(sort, sortField(defaultOrderField, true))
+	}
+	return sort
+}
+
+func sortField(field string, desc bool) bson.E {
+	field = fieldPath(field)
+	if desc {
+		return bson.E{Key: field, Value: -1}
+	}
+	return bson.E{Key: field, Value: 1}
+}
+
+// BuildPagination 计算跳过的行数和每页行数，页码和每页行数小于 1 时使用默认值
+func BuildPagination(page, pageSize int32) (skip, limit int64) {
+	if page < 1 {
+		page = paging.DefaultPage
+	}
+	if pageSize < 1 {
+		pageSize = paging.DefaultPageSize
+	}
+	return int64(paging.GetPageOffset(page, pageSize)), int64(pageSize)
+}
+
+// BuildProjection 构建字段选择文档，字段名转换为 snake_case，id 替换为 _id
+func BuildProjection(fields []string) bson.D {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	var projection bson.D
+	for _, field := range entgo.NormalizePaths(fields) {
+		if field == "id" {
+			field = IDField
+		}
+		projection = append(projection, bson.E{Key: field, Value: 1})
+	}
+	return projection
+}
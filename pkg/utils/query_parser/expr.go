@@ -0,0 +1,320 @@
+package query_parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// FilterExprKind 过滤表达式节点类型
+type FilterExprKind int
+
+const (
+	FilterExprLeaf FilterExprKind = iota // 叶子节点：{字段名}__{操作符} : {查询值}
+	FilterExprAnd                        // 与
+	FilterExprOr                         // 或
+	FilterExprNot                        // 非
+)
+
+// 过滤表达式中的逻辑关键字
+const (
+	FilterExprKeywordAnd = "and"
+	FilterExprKeywordOr  = "or"
+	FilterExprKeywordNot = "not"
+)
+
+// FilterExpr 过滤表达式树
+type FilterExpr struct {
+	Kind     FilterExprKind
+	Children []*FilterExpr // And/Or 的子节点，Not 只有一个子节点
+
+	Key   string // 叶子节点的键，如 name__icontains、pub_date__year__gte
+	Value string // 叶子节点的值
+}
+
+// And 构建与节点
+func And(children ...*FilterExpr) *FilterExpr {
+	return &FilterExpr{Kind: FilterExprAnd, Children: children}
+}
+
+// Or 构建或节点
+func Or(children ...*FilterExpr) *FilterExpr {
+	return &FilterExpr{Kind: FilterExprOr, Children: children}
+}
+
+// Not 构建非节点
+func Not(child *FilterExpr) *FilterExpr {
+	return &FilterExpr{Kind: FilterExprNot, Children: []*FilterExpr{child}}
+}
+
+// Leaf 构建叶子节点
+func Leaf(key, value string) *FilterExpr {
+	return &FilterExpr{Kind: FilterExprLeaf, Key: key, Value: value}
+}
+
+// Walk 按顺序遍历所有叶子节点，解析字段名和操作符后调用处理函数
+func (e *FilterExpr) Walk(handler FilterHandler) {
+	if e == nil {
+		return
+	}
+	if e.Kind == FilterExprLeaf {
+		ParseFilterField(e.Key, e.Value, handler)
+		return
+	}
+	for _, child := range e.Children {
+		child.Walk(handler)
+	}
+}
+
+// IsEmpty 是否不包含任何叶子节点
+func (e *FilterExpr) IsEmpty() bool {
+	if e == nil {
+		return true
+	}
+	if e.Kind == FilterExprLeaf {
+		return false
+	}
+	for _, child := range e.Children {
+		if !child.IsEmpty() {
+			return false
+		}
+	}
+	return true
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// ParseFilterExprJSON 解析JSON格式的过滤表达式，返回顶层的与节点
+//
+// 兼容原有的扁平格式，并支持 and/or/not 嵌套：
+//
+//	{"status": "ON", "or": [{"name__icontains": "x"}, {"code__startswith": "x"}], "not": {"type": "3"}}
+//	[{"status": "ON"}, {"or": [...]}]
+//
+// 同一对象中的多个键为与关系，叶子节点的值可以是字符串、数字、布尔或数组（in、range 等操作符）。
+func ParseFilterExprJSON(query string) (*FilterExpr, error) {
+	if query == "" {
+		return nil, nil
+	}
+
+	var raw json.RawMessage
+	if err := json.Unmarshal([]byte(query), &raw); err != nil {
+		return nil, err
+	}
+
+	return parseJSONNode(raw)
+}
+
+// parseJSONNode 解析对象或对象数组
+func parseJSONNode(raw json.RawMessage) (*FilterExpr, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return And(), nil
+	}
+
+	switch raw[0] {
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		node := And()
+		for _, item := range items {
+			child, err := parseJSONNode(item)
+			if err != nil {
+				return nil, err
+			}
+			node.Children = append(node.Children, child)
+		}
+		return node, nil
+
+	case '{':
+		return parseJSONObject(raw)
+
+	default:
+		return nil, fmt.Errorf("filter expression must be an object or array, got: %s", raw)
+	}
+}
+
+// parseJSONObject 按键的原始顺序解析对象
+func parseJSONObject(raw json.RawMessage) (*FilterExpr, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	node := And()
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+
+		var value json.RawMessage
+		if err = dec.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		var child *FilterExpr
+		switch strings.ToLower(key) {
+		case FilterExprKeywordAnd:
+			child, err = parseJSONNode(value)
+		case FilterExprKeywordOr:
+			child, err = parseJSONNode(value)
+			if child != nil {
+				child = flattenTo(child, FilterExprOr)
+			}
+		case FilterExprKeywordNot:
+			child, err = parseJSONNode(value)
+			if child != nil {
+				child = Not(child)
+			}
+		default:
+			child = Leaf(key, jsonLeafValue(value))
+		}
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, child)
+	}
+
+	return node, nil
+}
+
+// flattenTo 将数组解析出的与节点转换为指定类型的节点
+func flattenTo(node *FilterExpr, kind FilterExprKind) *FilterExpr {
+	if node.Kind == FilterExprAnd {
+		node.Kind = kind
+		return node
+	}
+	return &FilterExpr{Kind: kind, Children: []*FilterExpr{node}}
+}
+
+// jsonLeafValue 字符串取其内容，其他类型保留JSON文本
+func jsonLeafValue(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	if string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// ParseFilterExprQueryString 解析自定义字符串格式的过滤表达式，返回顶层的与节点
+//
+// 兼容原有的 {字段名}__{操作符}:{查询值},... 格式，并支持 and(...)、or(...)、not(...) 嵌套：
+//
+//	status:ON,or(name__icontains:x,code__startswith:x),not(type:3)
+//
+// 值中的 , ( ) 等特殊字符需使用 EncodeSpecialCharacters 编码，无效的键值对会被跳过。
+func ParseFilterExprQueryString(query string) (*FilterExpr, error) {
+	if query == "" {
+		return nil, nil
+	}
+
+	p := &queryExprParser{input: query}
+	node, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.input) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos)
+	}
+	return node, nil
+}
+
+type queryExprParser struct {
+	input string
+	pos   int
+}
+
+// parseList item (',' item)*
+func (p *queryExprParser) parseList() (*FilterExpr, error) {
+	node := And()
+	for {
+		child, err := p.parseItem()
+		if err != nil {
+			return nil, err
+		}
+		if child != nil {
+			node.Children = append(node.Children, child)
+		}
+
+		if p.pos < len(p.input) && p.input[p.pos] == QueryFilterQueriesDelimiter[0] {
+			p.pos++
+			continue
+		}
+		return node, nil
+	}
+}
+
+// parseItem ('and'|'or'|'not') '(' list ')' | key ':' value
+func (p *queryExprParser) parseItem() (*FilterExpr, error) {
+	start := p.pos
+	for p.pos < len(p.input) && !strings.ContainsRune(",():", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	word := strings.TrimSpace(p.input[start:p.pos])
+
+	if p.pos < len(p.input) && p.input[p.pos] == '(' {
+		keyword := strings.ToLower(word)
+		if keyword != FilterExprKeywordAnd && keyword != FilterExprKeywordOr && keyword != FilterExprKeywordNot {
+			return nil, fmt.Errorf("unknown filter group %q at position %d", word, start)
+		}
+		p.pos++
+
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.input) || p.input[p.pos] != ')' {
+			return nil, fmt.Errorf("missing ')' for group %q at position %d", word, start)
+		}
+		p.pos++
+
+		switch keyword {
+		case FilterExprKeywordOr:
+			return flattenTo(list, FilterExprOr), nil
+		case FilterExprKeywordNot:
+			return Not(list), nil
+		default:
+			return list, nil
+		}
+	}
+
+	// 叶子节点
+	if p.pos >= len(p.input) || p.input[p.pos] != QueryFilterFieldOperatorDelimiter[0] {
+		return nil, nil // 跳过无效的键值对
+	}
+	p.pos++
+
+	valueStart := p.pos
+	for p.pos < len(p.input) && p.input[p.pos] != ',' && p.input[p.pos] != ')' {
+		p.pos++
+	}
+
+	rawValue := p.input[valueStart:p.pos]
+	if strings.Contains(rawValue, QueryFilterFieldOperatorDelimiter) {
+		return nil, nil // 跳过包含多个冒号的键值对
+	}
+
+	key, err := DecodeSpecialCharacters(word)
+	if err != nil {
+		return nil, nil
+	}
+	value, err := DecodeSpecialCharacters(strings.TrimSpace(rawValue))
+	if err != nil {
+		return nil, nil
+	}
+	if key == "" || value == "" {
+		return nil, nil
+	}
+
+	return Leaf(key, value), nil
+}
@@ -0,0 +1,64 @@
+package query_parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilterExprJSON(t *testing.T) {
+	expr, err := ParseFilterExprJSON(`{"status":"ON","or":[{"name__icontains":"x"},{"code__startswith":"x"}],"not":{"type__in":[1,2]}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, And(
+		Leaf("status", "ON"),
+		Or(And(Leaf("name__icontains", "x")), And(Leaf("code__startswith", "x"))),
+		Not(And(Leaf("type__in", "[1,2]"))),
+	), expr)
+
+	// 原有的扁平格式
+	expr, err = ParseFilterExprJSON(`[{"age__gte":30},{"status":"active"}]`)
+	assert.NoError(t, err)
+	assert.Equal(t, And(And(Leaf("age__gte", "30")), And(Leaf("status", "active"))), expr)
+
+	var fields []string
+	expr.Walk(func(field, operator, value string) {
+		fields = append(fields, field+"/"+operator+"/"+value)
+	})
+	assert.Equal(t, []string{"age/gte/30", "status//active"}, fields)
+
+	expr, err = ParseFilterExprJSON("")
+	assert.NoError(t, err)
+	assert.True(t, expr.IsEmpty())
+
+	_, err = ParseFilterExprJSON(`"status"`)
+	assert.Error(t, err)
+	_, err = ParseFilterExprJSON(`{"or": "x"}`)
+	assert.Error(t, err)
+}
+
+func TestParseFilterExprQueryString(t *testing.T) {
+	expr, err := ParseFilterExprQueryString("status:ON,or(name__icontains:x,code__startswith:x),not(type:3)")
+	assert.NoError(t, err)
+	assert.Equal(t, And(
+		Leaf("status", "ON"),
+		Or(Leaf("name__icontains", "x"), Leaf("code__startswith", "x")),
+		Not(And(Leaf("type", "3"))),
+	), expr)
+
+	// 嵌套分组和编码的值
+	expr, err = ParseFilterExprQueryString("and(a:1,or(b:2,c:" + EncodeSpecialCharacters("x,(y)") + "))")
+	assert.NoError(t, err)
+	assert.Equal(t, And(And(Leaf("a", "1"), Or(Leaf("b", "2"), Leaf("c", "x,(y)")))), expr)
+
+	// 跳过无效的键值对
+	expr, err = ParseFilterExprQueryString("invalid,na:me:John,name:Tom")
+	assert.NoError(t, err)
+	assert.Equal(t, And(Leaf("name", "Tom")), expr)
+
+	_, err = ParseFilterExprQueryString("or(a:1")
+	assert.Error(t, err)
+	_, err = ParseFilterExprQueryString("a:1)")
+	assert.Error(t, err)
+	_, err = ParseFilterExprQueryString("xor(a:1)")
+	assert.Error(t, err)
+}